@@ -0,0 +1,83 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenSource supplies bearer tokens for calls to other services.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// tokenRenewBefore renews a token this long before it expires, so it never
+// runs out while a request is in flight.
+const tokenRenewBefore = 30 * time.Second
+
+// ClientCredentials is a TokenSource that obtains service tokens from the
+// auth service with the OAuth 2.0 client credentials grant (RFC 6749,
+// section 4.4) and caches each one until shortly before it expires.
+type ClientCredentials struct {
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	http         *http.Client
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func NewClientCredentials(tokenURL, clientID, clientSecret string) *ClientCredentials {
+	return &ClientCredentials{
+		tokenURL:     tokenURL,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		http:         &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (c *ClientCredentials) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && time.Now().Add(tokenRenewBefore).Before(c.expiresAt) {
+		return c.token, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(c.clientID), url.QueryEscape(c.clientSecret))
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request service token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+		Error       string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decode service token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || body.AccessToken == "" {
+		return "", fmt.Errorf("request service token: status %d: %s", resp.StatusCode, body.Error)
+	}
+
+	c.token = body.AccessToken
+	c.expiresAt = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	return c.token, nil
+}
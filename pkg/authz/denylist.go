@@ -0,0 +1,43 @@
+package authz
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DenyList holds the ids (`jti`) of access tokens revoked before they
+// expire, such as those of a session the user signed out.
+type DenyList interface {
+	Deny(ctx context.Context, jti string, until time.Time) error
+	Denied(ctx context.Context, jti string) (bool, error)
+}
+
+const denyKeyPrefix = "authz:deny:"
+
+// RedisDenyList keeps each entry only until the token it denies would have
+// expired anyway, so the list stays as small as the access token TTL allows.
+type RedisDenyList struct {
+	rdb *redis.Client
+}
+
+func NewRedisDenyList(rdb *redis.Client) *RedisDenyList {
+	return &RedisDenyList{rdb: rdb}
+}
+
+func (d *RedisDenyList) Deny(ctx context.Context, jti string, until time.Time) error {
+	ttl := time.Until(until)
+	if jti == "" || ttl <= 0 {
+		return nil
+	}
+	return d.rdb.Set(ctx, denyKeyPrefix+jti, 1, ttl).Err()
+}
+
+func (d *RedisDenyList) Denied(ctx context.Context, jti string) (bool, error) {
+	n, err := d.rdb.Exists(ctx, denyKeyPrefix+jti).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,143 @@
+package authz
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Algorithms accepted for access tokens. HMAC is deliberately absent: with a
+// shared secret every service that verifies tokens could also mint them.
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// minRSABits rejects RSA keys too short to be trusted.
+const minRSABits = 2048
+
+// JWK is the public half of a signing key as published in a JWK Set
+// (RFC 7517). Only RSA and Ed25519 keys are supported.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicKey is a verification key together with the one algorithm it may
+// be used with.
+type PublicKey struct {
+	Alg string
+	Key crypto.PublicKey
+}
+
+func NewJWK(kid, alg string, pub crypto.PublicKey) (JWK, error) {
+	k := JWK{Kid: kid, Use: "sig", Alg: alg}
+
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		if alg != AlgRS256 {
+			return JWK{}, fmt.Errorf("rsa key cannot be used with %s", alg)
+		}
+		k.Kty = "RSA"
+		k.N = base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+		k.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	case ed25519.PublicKey:
+		if alg != AlgEdDSA {
+			return JWK{}, fmt.Errorf("ed25519 key cannot be used with %s", alg)
+		}
+		k.Kty = "OKP"
+		k.Crv = "Ed25519"
+		k.X = base64.RawURLEncoding.EncodeToString(key)
+	default:
+		return JWK{}, fmt.Errorf("unsupported key type %T", pub)
+	}
+
+	return k, nil
+}
+
+// PublicKey decodes k, checking that its key type matches its algorithm.
+func (k JWK) PublicKey() (PublicKey, error) {
+	if k.Use != "" && k.Use != "sig" {
+		return PublicKey{}, fmt.Errorf("key %q is not a signing key", k.Kid)
+	}
+
+	switch {
+	case k.Kty == "RSA" && k.Alg == AlgRS256:
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return PublicKey{}, fmt.Errorf("key %q: invalid modulus: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return PublicKey{}, fmt.Errorf("key %q: invalid exponent", k.Kid)
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if key.N.BitLen() < minRSABits {
+			return PublicKey{}, fmt.Errorf("key %q: rsa key shorter than %d bits", k.Kid, minRSABits)
+		}
+		return PublicKey{Alg: AlgRS256, Key: key}, nil
+	case k.Kty == "OKP" && k.Crv == "Ed25519" && k.Alg == AlgEdDSA:
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return PublicKey{}, fmt.Errorf("key %q: invalid ed25519 key", k.Kid)
+		}
+		return PublicKey{Alg: AlgEdDSA, Key: ed25519.PublicKey(x)}, nil
+	default:
+		return PublicKey{}, fmt.Errorf("key %q: unsupported kty %q with alg %q", k.Kid, k.Kty, k.Alg)
+	}
+}
+
+// Thumbprint returns the RFC 7638 thumbprint of pub. It is used as the key
+// id, so the same key always gets the same kid.
+func Thumbprint(pub crypto.PublicKey) (string, error) {
+	var members any
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		// Struct fields are marshalled in declaration order, which here is
+		// the lexicographic order the RFC requires.
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		}
+	case ed25519.PublicKey:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{
+			Crv: "Ed25519",
+			Kty: "OKP",
+			X:   base64.RawURLEncoding.EncodeToString(key),
+		}
+	default:
+		return "", errors.New("unsupported key type")
+	}
+
+	b, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,177 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrUnknownKey means the token names a key the issuer does not publish.
+	ErrUnknownKey = errors.New("unknown signing key")
+	// ErrKeysUnavailable means the keys could not be loaded at all, so no
+	// token can be verified right now.
+	ErrKeysUnavailable = errors.New("signing keys unavailable")
+)
+
+// KeySource resolves the `kid` from a token header to its verification key.
+type KeySource interface {
+	PublicKey(ctx context.Context, kid string) (PublicKey, error)
+}
+
+const (
+	// minJWKSRefresh bounds how often JWKS fetches the set again, for an
+	// unknown kid or an expired cache alike.
+	minJWKSRefresh = 10 * time.Second
+	jwksTimeout    = 5 * time.Second
+)
+
+// JWKS is a KeySource backed by the JWK Set the auth service publishes. The
+// set is cached for ttl and fetched again early when a token names a kid
+// not seen yet, which is how newly rotated keys are picked up. When the
+// issuer cannot be reached, keys already cached keep being served.
+//
+// Fetches happen outside the lock and at most one runs at a time. Requests
+// for cached keys are answered right away, from the old set while an
+// expired one is refreshed in the background; only requests for kids not
+// in the cache wait for a fetch, and they share the one in progress.
+type JWKS struct {
+	url  string
+	ttl  time.Duration
+	http *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]PublicKey
+	fetchedAt time.Time
+	triedAt   time.Time
+	inflight  *jwksFetch
+}
+
+// jwksFetch is a fetch in progress. done is closed once the keys it loaded,
+// if any, are in place.
+type jwksFetch struct {
+	done chan struct{}
+	err  error
+}
+
+func NewJWKS(url string, ttl time.Duration) *JWKS {
+	return &JWKS{
+		url:  url,
+		ttl:  ttl,
+		http: &http.Client{Timeout: jwksTimeout},
+	}
+}
+
+func (j *JWKS) PublicKey(ctx context.Context, kid string) (PublicKey, error) {
+	j.mu.Lock()
+	key, ok := j.keys[kid]
+	stale := time.Since(j.fetchedAt) >= j.ttl
+
+	f := j.inflight
+	if f == nil && (stale || !ok) && time.Since(j.triedAt) >= minJWKSRefresh {
+		j.triedAt = time.Now()
+		f = &jwksFetch{done: make(chan struct{})}
+		j.inflight = f
+		go j.refresh(ctx, f)
+	}
+	if ok {
+		j.mu.Unlock()
+		return key, nil
+	}
+	if f == nil {
+		defer j.mu.Unlock()
+		return j.lookup(kid)
+	}
+	j.mu.Unlock()
+
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		return PublicKey{}, fmt.Errorf("%w: %w", ErrKeysUnavailable, ctx.Err())
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if f.err != nil && j.keys == nil {
+		return PublicKey{}, fmt.Errorf("%w: %w", ErrKeysUnavailable, f.err)
+	}
+	return j.lookup(kid)
+}
+
+// lookup answers from the cached set. Callers hold j.mu.
+func (j *JWKS) lookup(kid string) (PublicKey, error) {
+	key, ok := j.keys[kid]
+	switch {
+	case ok:
+		return key, nil
+	case j.keys == nil:
+		return PublicKey{}, ErrKeysUnavailable
+	default:
+		return PublicKey{}, ErrUnknownKey
+	}
+}
+
+// refresh runs f and installs the keys it loaded.
+func (j *JWKS) refresh(ctx context.Context, f *jwksFetch) {
+	keys, err := j.fetch(ctx)
+	if err != nil {
+		slog.Warn("failed to fetch jwks", slog.String("url", j.url), slog.String("error", err.Error()))
+	}
+
+	j.mu.Lock()
+	if err == nil {
+		j.keys = keys
+		j.fetchedAt = time.Now()
+	}
+	f.err = err
+	j.inflight = nil
+	j.mu.Unlock()
+
+	close(f.done)
+}
+
+func (j *JWKS) fetch(ctx context.Context) (map[string]PublicKey, error) {
+	// Detached from the request that started it, whose waiters may outlive
+	// it.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jwksTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := j.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var set JWKSet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decode jwks: %w", err)
+	}
+
+	keys := make(map[string]PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		pub, err := k.PublicKey()
+		if err != nil {
+			// One bad entry must not take down verification with the others.
+			slog.Warn("skipping jwks key", slog.String("kid", k.Kid), slog.String("error", err.Error()))
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,242 @@
+package authz
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// jwksServer publishes whatever set holds and counts the requests.
+type jwksServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	set      JWKSet
+	status   int
+	requests atomic.Int32
+	// release, when set, holds every response until it is closed.
+	release chan struct{}
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	t.Helper()
+
+	s := &jwksServer{status: http.StatusOK}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		if s.release != nil {
+			<-s.release
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.status != http.StatusOK {
+			w.WriteHeader(s.status)
+			return
+		}
+		json.NewEncoder(w).Encode(s.set)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) publish(t *testing.T, kid string) ed25519.PrivateKey {
+	t.Helper()
+
+	pub, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwk, err := NewJWK(kid, AlgEdDSA, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.mu.Lock()
+	s.set.Keys = append(s.set.Keys, jwk)
+	s.mu.Unlock()
+	return private
+}
+
+func (s *jwksServer) fail(status int) {
+	s.mu.Lock()
+	s.status = status
+	s.mu.Unlock()
+}
+
+func TestJWKS(t *testing.T) {
+	ctx := context.Background()
+	srv := newJWKSServer(t)
+	srv.publish(t, "k1")
+
+	jwks := NewJWKS(srv.URL, time.Hour)
+
+	if _, err := jwks.PublicKey(ctx, "k1"); err != nil {
+		t.Fatalf("PublicKey(k1) error = %v", err)
+	}
+	if _, err := jwks.PublicKey(ctx, "k1"); err != nil {
+		t.Fatalf("PublicKey(k1) again error = %v", err)
+	}
+	if got := srv.requests.Load(); got != 1 {
+		t.Errorf("requests = %d, want cached keys served without fetching", got)
+	}
+
+	// A kid rotated in right after the last fetch is not fetched for until
+	// minJWKSRefresh has passed.
+	srv.publish(t, "k2")
+	if _, err := jwks.PublicKey(ctx, "k2"); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("PublicKey(k2) error = %v, want %v", err, ErrUnknownKey)
+	}
+
+	jwks.mu.Lock()
+	jwks.triedAt = time.Now().Add(-minJWKSRefresh)
+	jwks.mu.Unlock()
+	if _, err := jwks.PublicKey(ctx, "k2"); err != nil {
+		t.Fatalf("PublicKey(k2) after refresh error = %v", err)
+	}
+	if got := srv.requests.Load(); got != 2 {
+		t.Errorf("requests = %d, want 2", got)
+	}
+
+	// An unreachable issuer leaves the cached keys in service.
+	srv.fail(http.StatusInternalServerError)
+	jwks.mu.Lock()
+	jwks.triedAt = time.Now().Add(-minJWKSRefresh)
+	jwks.fetchedAt = time.Now().Add(-2 * time.Hour)
+	jwks.mu.Unlock()
+	if _, err := jwks.PublicKey(ctx, "k1"); err != nil {
+		t.Errorf("PublicKey(k1) with issuer down error = %v", err)
+	}
+	if _, err := jwks.PublicKey(ctx, "k3"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("PublicKey(k3) error = %v, want %v", err, ErrUnknownKey)
+	}
+}
+
+func TestJWKSUnavailable(t *testing.T) {
+	srv := newJWKSServer(t)
+	srv.fail(http.StatusServiceUnavailable)
+
+	jwks := NewJWKS(srv.URL, time.Hour)
+
+	for range 2 {
+		if _, err := jwks.PublicKey(context.Background(), "k1"); !errors.Is(err, ErrKeysUnavailable) {
+			t.Errorf("PublicKey() error = %v, want %v", err, ErrKeysUnavailable)
+		}
+	}
+	if got := srv.requests.Load(); got != 1 {
+		t.Errorf("requests = %d, want retries limited to one per minJWKSRefresh", got)
+	}
+}
+
+// Requests arriving while the set is fetched wait for that fetch instead of
+// starting their own, and cached keys are served meanwhile.
+func TestJWKSSharesFetch(t *testing.T) {
+	ctx := context.Background()
+	srv := newJWKSServer(t)
+	srv.publish(t, "k1")
+
+	jwks := NewJWKS(srv.URL, time.Hour)
+	if _, err := jwks.PublicKey(ctx, "k1"); err != nil {
+		t.Fatalf("PublicKey(k1) error = %v", err)
+	}
+
+	srv.publish(t, "k2")
+	srv.release = make(chan struct{})
+	jwks.mu.Lock()
+	jwks.triedAt = time.Time{}
+	jwks.mu.Unlock()
+
+	const waiters = 10
+	errs := make(chan error, waiters)
+	for range waiters {
+		go func() {
+			_, err := jwks.PublicKey(ctx, "k2")
+			errs <- err
+		}()
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for srv.requests.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the fetch")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := jwks.PublicKey(ctx, "k1"); err != nil {
+		t.Errorf("PublicKey(k1) during fetch error = %v", err)
+	}
+
+	close(srv.release)
+	for range waiters {
+		if err := <-errs; err != nil {
+			t.Errorf("PublicKey(k2) error = %v", err)
+		}
+	}
+	if got := srv.requests.Load(); got != 2 {
+		t.Errorf("requests = %d, want one shared fetch", got)
+	}
+}
+
+// Once the cache expires, cached keys are still answered at once while the
+// set is fetched again in the background.
+func TestJWKSServesStaleKeys(t *testing.T) {
+	srv := newJWKSServer(t)
+	srv.publish(t, "k1")
+
+	jwks := NewJWKS(srv.URL, time.Hour)
+	if _, err := jwks.PublicKey(context.Background(), "k1"); err != nil {
+		t.Fatalf("PublicKey(k1) error = %v", err)
+	}
+
+	srv.release = make(chan struct{})
+	jwks.mu.Lock()
+	jwks.fetchedAt = time.Now().Add(-2 * time.Hour)
+	jwks.triedAt = time.Time{}
+	jwks.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := jwks.PublicKey(ctx, "k1"); err != nil {
+		t.Fatalf("PublicKey(k1) with an expired cache error = %v, want the cached key", err)
+	}
+
+	close(srv.release)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		jwks.mu.Lock()
+		refreshed := time.Since(jwks.fetchedAt) < time.Hour
+		jwks.mu.Unlock()
+		if refreshed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the background refresh")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if got := srv.requests.Load(); got != 2 {
+		t.Errorf("requests = %d, want one background refresh", got)
+	}
+}
+
+func TestJWKSWaitHonoursContext(t *testing.T) {
+	srv := newJWKSServer(t)
+	srv.release = make(chan struct{})
+	defer close(srv.release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := NewJWKS(srv.URL, time.Hour).PublicKey(ctx, "k1")
+	if !errors.Is(err, ErrKeysUnavailable) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("PublicKey() error = %v, want %v after the deadline", err, ErrKeysUnavailable)
+	}
+}
@@ -0,0 +1,209 @@
+package authz
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-market/pkg/errs"
+	"github.com/go-market/pkg/problem"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Option configures Authenticate and OptionalAuthenticate.
+type Option func(*options)
+
+type options struct {
+	denied DenyList
+}
+
+// WithDenyList rejects tokens whose `jti` is on the deny list. When the list
+// cannot be consulted the request is refused rather than let through.
+func WithDenyList(d DenyList) Option {
+	return func(o *options) {
+		o.denied = d
+	}
+}
+
+// Authenticate validates the bearer token with v and stores the Principal in
+// the request context. Tokens may carry a `roles` array or the legacy single
+// `role` claim.
+func Authenticate(v *Verifier, opts ...Option) func(http.Handler) http.Handler {
+	return authenticate(v, true, opts)
+}
+
+// OptionalAuthenticate is Authenticate for endpoints that also serve
+// anonymous callers: requests without an Authorization header pass through
+// with no Principal, while a present but invalid token is still rejected.
+func OptionalAuthenticate(v *Verifier, opts ...Option) func(http.Handler) http.Handler {
+	return authenticate(v, false, opts)
+}
+
+func authenticate(v *Verifier, required bool, opts []Option) func(http.Handler) http.Handler {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				if !required {
+					next.ServeHTTP(w, r)
+					return
+				}
+				problem.Write(w, r, errs.ErrUnauthenticated)
+				return
+			}
+
+			parts := strings.Split(authHeader, " ")
+			if len(parts) != 2 || parts[0] != "Bearer" {
+				problem.Write(w, r, errs.ErrInvalidAuthHeader)
+				return
+			}
+
+			claims, err := v.Verify(r.Context(), parts[1])
+			if err != nil {
+				problem.Write(w, r, err)
+				return
+			}
+
+			userID, ok := claims["sub"].(string)
+			if !ok || userID == "" {
+				problem.Write(w, r, errs.ErrInvalidToken)
+				return
+			}
+
+			roles, ok := rolesFromClaims(claims)
+			if !ok {
+				problem.Write(w, r, errs.ErrInvalidToken)
+				return
+			}
+
+			jti, _ := claims["jti"].(string)
+			if o.denied != nil && jti != "" {
+				denied, err := o.denied.Denied(r.Context(), jti)
+				if err != nil {
+					problem.Write(w, r, errs.Wrap(errs.ErrAuthUnavailable, err))
+					return
+				}
+				if denied {
+					problem.Write(w, r, errs.ErrInvalidToken)
+					return
+				}
+			}
+
+			amr, ok := stringsFromClaim(claims, "amr")
+			if !ok {
+				problem.Write(w, r, errs.ErrInvalidToken)
+				return
+			}
+
+			sid, _ := claims["sid"].(string)
+			ctx := WithPrincipal(r.Context(), Principal{UserID: userID, Roles: roles, SessionID: sid, TokenID: jti, AMR: amr})
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func rolesFromClaims(claims jwt.MapClaims) ([]string, bool) {
+	if raw, ok := claims["roles"].([]interface{}); ok {
+		roles := make([]string, 0, len(raw))
+		for _, v := range raw {
+			role, ok := v.(string)
+			if !ok {
+				return nil, false
+			}
+			roles = append(roles, role)
+		}
+		return roles, len(roles) > 0
+	}
+
+	if role, ok := claims["role"].(string); ok && role != "" {
+		return []string{role}, true
+	}
+
+	return nil, false
+}
+
+// stringsFromClaim reads an optional array of strings. A missing claim is
+// not an error; a claim of any other shape is.
+func stringsFromClaim(claims jwt.MapClaims, name string) ([]string, bool) {
+	v, ok := claims[name]
+	if !ok {
+		return nil, true
+	}
+
+	raw, ok := v.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	values := make([]string, 0, len(raw))
+	for _, item := range raw {
+		s, ok := item.(string)
+		if !ok {
+			return nil, false
+		}
+		values = append(values, s)
+	}
+	return values, true
+}
+
+// RequirePermission rejects callers whose roles do not grant perm.
+func RequirePermission(perm Permission) func(http.Handler) http.Handler {
+	return require(errs.ErrForbidden, func(p Principal, _ *http.Request) bool {
+		return p.Can(perm)
+	})
+}
+
+// RequireOwnerOr lets the request through when the chi URL parameter param
+// equals the caller's id, or when the caller holds perm.
+func RequireOwnerOr(param string, perm Permission) func(http.Handler) http.Handler {
+	return require(errs.ErrForbidden, func(p Principal, r *http.Request) bool {
+		return OwnerOr(p, chi.URLParam(r, param), perm)
+	})
+}
+
+// RequireMFA rejects callers that signed in without a second factor. Put it
+// after a permission check on routes where a stolen password alone must not
+// be enough, such as deleting users. Services pass: their tokens come from
+// the client credentials grant, which has no second factor to offer.
+func RequireMFA() func(http.Handler) http.Handler {
+	return require(errs.ErrMFARequired, func(p Principal, _ *http.Request) bool {
+		return p.MFA() || p.HasRole(RoleService)
+	})
+}
+
+// RequireMFAUnlessOwner is RequireMFA for everyone but the owner named by
+// the chi URL parameter param. It goes after RequireOwnerOr, so users manage
+// their own data as before while staff acting on others need a second
+// factor.
+func RequireMFAUnlessOwner(param string) func(http.Handler) http.Handler {
+	return require(errs.ErrMFARequired, func(p Principal, r *http.Request) bool {
+		owner := chi.URLParam(r, param)
+		return (owner != "" && p.UserID == owner) || p.MFA() || p.HasRole(RoleService)
+	})
+}
+
+// require lets the request through when allow approves the caller and
+// answers with denied otherwise.
+func require(denied *errs.Error, allow func(Principal, *http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := FromContext(r.Context())
+			if !ok {
+				problem.Write(w, r, errs.ErrUnauthenticated)
+				return
+			}
+
+			if !allow(p, r) {
+				problem.Write(w, r, denied)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
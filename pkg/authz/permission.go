@@ -0,0 +1,75 @@
+package authz
+
+type Permission string
+
+const (
+	PermUsersRead    Permission = "users:read"
+	PermUsersWrite   Permission = "users:write"
+	PermUsersDelete  Permission = "users:delete"
+	PermOrdersManage Permission = "orders:manage"
+	PermCatalogWrite Permission = "catalog:write"
+
+	PermInventoryManage Permission = "inventory:manage"
+	PermPaymentsManage  Permission = "payments:manage"
+)
+
+const (
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+	// RoleService is held by tokens that services use to call each other.
+	RoleService = "service"
+)
+
+// rolePermissions is the single source of truth for what each role may do.
+// Plain users get nothing here: access to their own resources is granted by
+// ownership policies, not by permissions.
+var rolePermissions = map[string][]Permission{
+	RoleUser: {},
+	RoleSupport: {
+		PermUsersRead,
+		PermOrdersManage,
+	},
+	RoleAdmin: {
+		PermUsersRead,
+		PermUsersWrite,
+		PermUsersDelete,
+		PermOrdersManage,
+		PermCatalogWrite,
+		PermInventoryManage,
+		PermPaymentsManage,
+	},
+	RoleService: {
+		PermOrdersManage,
+		PermInventoryManage,
+		PermPaymentsManage,
+	},
+}
+
+// Can reports whether any of roles grants perm. Unknown roles grant nothing.
+func Can(roles []string, perm Permission) bool {
+	for _, role := range roles {
+		for _, p := range rolePermissions[role] {
+			if p == perm {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Permissions returns the union of permissions granted by roles.
+func Permissions(roles []string) []Permission {
+	seen := make(map[Permission]struct{})
+	var perms []Permission
+	for _, role := range roles {
+		for _, p := range rolePermissions[role] {
+			if _, ok := seen[p]; ok {
+				continue
+			}
+			seen[p] = struct{}{}
+			perms = append(perms, p)
+		}
+	}
+	return perms
+}
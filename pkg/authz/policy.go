@@ -0,0 +1,9 @@
+package authz
+
+// OwnerOr allows the owner of a resource, or anyone holding perm.
+func OwnerOr(p Principal, ownerID string, perm Permission) bool {
+	if ownerID != "" && p.UserID == ownerID {
+		return true
+	}
+	return p.Can(perm)
+}
@@ -0,0 +1,51 @@
+package authz
+
+import (
+	"context"
+	"slices"
+)
+
+type contextKey string
+
+const principalKey contextKey = "principal"
+
+// Authentication methods recorded in the `amr` claim (RFC 8176).
+const (
+	AMRPassword = "pwd"
+	AMROTP      = "otp"
+	AMRMFA      = "mfa"
+)
+
+// Principal is the authenticated caller extracted from the access token.
+type Principal struct {
+	UserID string
+	Roles  []string
+	// SessionID and TokenID come from the `sid` and `jti` claims. They are
+	// empty for tokens issued without them, such as service tokens.
+	SessionID string
+	TokenID   string
+	// AMR lists how the user authenticated when the session started.
+	AMR []string
+}
+
+func (p Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+// MFA reports whether the user passed a second factor to get the token.
+func (p Principal) MFA() bool {
+	return slices.Contains(p.AMR, AMRMFA)
+}
+
+func (p Principal) Can(perm Permission) bool {
+	return Can(p.Roles, perm)
+}
+
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey, p)
+}
+
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey).(Principal)
+	return p, ok && p.UserID != ""
+}
@@ -0,0 +1,86 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-market/pkg/errs"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Config tells a service where to find the auth service's public keys and
+// which tokens are meant for it.
+type Config struct {
+	JWKSURL  string        `yaml:"jwks_url" env-default:"http://localhost:8081/.well-known/jwks.json"`
+	Issuer   string        `yaml:"issuer" env-default:"http://localhost:8081"`
+	Audience string        `yaml:"audience" env-default:"go-market"`
+	CacheTTL time.Duration `yaml:"cache_ttl" env-default:"5m"`
+}
+
+// clockSkew is the leeway allowed on exp, nbf and iat between hosts.
+const clockSkew = 30 * time.Second
+
+// Verifier checks access tokens signed by the auth service.
+type Verifier struct {
+	keys     KeySource
+	issuer   string
+	audience string
+}
+
+func NewVerifier(keys KeySource, issuer, audience string) *Verifier {
+	return &Verifier{
+		keys:     keys,
+		issuer:   issuer,
+		audience: audience,
+	}
+}
+
+// NewRemoteVerifier verifies tokens against the JWK Set published at
+// cfg.JWKSURL.
+func NewRemoteVerifier(cfg Config) *Verifier {
+	return NewVerifier(NewJWKS(cfg.JWKSURL, cfg.CacheTTL), cfg.Issuer, cfg.Audience)
+}
+
+// Verify checks the signature with the key named by the `kid` header and
+// requires alg, iss, aud, exp and nbf to be valid. It returns
+// errs.ErrInvalidToken for bad tokens and errs.ErrAuthUnavailable when the
+// keys cannot be loaded.
+func (v *Verifier) Verify(ctx context.Context, raw string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("token has no kid")
+		}
+
+		key, err := v.keys.PublicKey(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+
+		// A key only verifies the algorithm it was published for, so a
+		// token cannot pick a different one to be checked with.
+		if t.Method.Alg() != key.Alg {
+			return nil, fmt.Errorf("token alg %s does not match key alg %s", t.Method.Alg(), key.Alg)
+		}
+		return key.Key, nil
+	},
+		jwt.WithValidMethods([]string{AlgRS256, AlgEdDSA}),
+		jwt.WithIssuer(v.issuer),
+		jwt.WithAudience(v.audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithNotBeforeRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	)
+	if err != nil {
+		if errors.Is(err, ErrKeysUnavailable) {
+			return nil, errs.Wrap(errs.ErrAuthUnavailable, err)
+		}
+		return nil, errs.Wrap(errs.ErrInvalidToken, err)
+	}
+
+	return claims, nil
+}
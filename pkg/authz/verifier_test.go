@@ -0,0 +1,176 @@
+package authz
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-market/pkg/errs"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// staticKeys is a KeySource over a fixed set of keys.
+type staticKeys map[string]PublicKey
+
+func (s staticKeys) PublicKey(_ context.Context, kid string) (PublicKey, error) {
+	if key, ok := s[kid]; ok {
+		return key, nil
+	}
+	return PublicKey{}, ErrUnknownKey
+}
+
+// downKeys is a KeySource that cannot reach the issuer.
+type downKeys struct{}
+
+func (downKeys) PublicKey(context.Context, string) (PublicKey, error) {
+	return PublicKey{}, ErrKeysUnavailable
+}
+
+const (
+	testIssuer   = "http://auth.test"
+	testAudience = "go-market"
+)
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key crypto.Signer, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	raw, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestVerify(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := staticKeys{
+		"ed":  {Alg: AlgEdDSA, Key: edKey.Public()},
+		"rsa": {Alg: AlgRS256, Key: rsaKey.Public()},
+	}
+
+	now := time.Now()
+	claims := func(modify func(jwt.MapClaims)) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"sub": "u1",
+			"iss": testIssuer,
+			"aud": testAudience,
+			"iat": now.Unix(),
+			"nbf": now.Unix(),
+			"exp": now.Add(time.Minute).Unix(),
+		}
+		if modify != nil {
+			modify(c)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		keys    KeySource
+		token   string
+		wantErr error
+	}{
+		{name: "valid EdDSA", keys: keys, token: sign(t, jwt.SigningMethodEdDSA, "ed", edKey, claims(nil))},
+		{name: "valid RS256", keys: keys, token: sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(nil))},
+		{
+			name:    "no kid",
+			keys:    keys,
+			token:   sign(t, jwt.SigningMethodEdDSA, "", edKey, claims(nil)),
+			wantErr: errs.ErrInvalidToken,
+		},
+		{
+			name:    "unknown kid",
+			keys:    keys,
+			token:   sign(t, jwt.SigningMethodEdDSA, "gone", edKey, claims(nil)),
+			wantErr: errs.ErrInvalidToken,
+		},
+		{
+			name:    "signed by another key",
+			keys:    keys,
+			token:   sign(t, jwt.SigningMethodEdDSA, "ed", otherKey, claims(nil)),
+			wantErr: errs.ErrInvalidToken,
+		},
+		{
+			name:    "alg other than the key's",
+			keys:    staticKeys{"ed": {Alg: AlgRS256, Key: edKey.Public()}},
+			token:   sign(t, jwt.SigningMethodEdDSA, "ed", edKey, claims(nil)),
+			wantErr: errs.ErrInvalidToken,
+		},
+		{
+			name:    "expired beyond the skew",
+			keys:    keys,
+			token:   sign(t, jwt.SigningMethodEdDSA, "ed", edKey, claims(func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Minute).Unix() })),
+			wantErr: errs.ErrInvalidToken,
+		},
+		{
+			name:  "expired within the skew",
+			keys:  keys,
+			token: sign(t, jwt.SigningMethodEdDSA, "ed", edKey, claims(func(c jwt.MapClaims) { c["exp"] = now.Add(-10 * time.Second).Unix() })),
+		},
+		{
+			name:    "no exp",
+			keys:    keys,
+			token:   sign(t, jwt.SigningMethodEdDSA, "ed", edKey, claims(func(c jwt.MapClaims) { delete(c, "exp") })),
+			wantErr: errs.ErrInvalidToken,
+		},
+		{
+			name:    "not valid yet",
+			keys:    keys,
+			token:   sign(t, jwt.SigningMethodEdDSA, "ed", edKey, claims(func(c jwt.MapClaims) { c["nbf"] = now.Add(time.Hour).Unix() })),
+			wantErr: errs.ErrInvalidToken,
+		},
+		{
+			name:    "other issuer",
+			keys:    keys,
+			token:   sign(t, jwt.SigningMethodEdDSA, "ed", edKey, claims(func(c jwt.MapClaims) { c["iss"] = "http://evil.test" })),
+			wantErr: errs.ErrInvalidToken,
+		},
+		{
+			name:    "other audience",
+			keys:    keys,
+			token:   sign(t, jwt.SigningMethodEdDSA, "ed", edKey, claims(func(c jwt.MapClaims) { c["aud"] = "elsewhere" })),
+			wantErr: errs.ErrInvalidToken,
+		},
+		{
+			name:    "keys unavailable",
+			keys:    downKeys{},
+			token:   sign(t, jwt.SigningMethodEdDSA, "ed", edKey, claims(nil)),
+			wantErr: errs.ErrAuthUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewVerifier(tt.keys, testIssuer, testAudience)
+
+			got, err := v.Verify(context.Background(), tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && got["sub"] != "u1" {
+				t.Errorf("Verify() sub = %v, want u1", got["sub"])
+			}
+		})
+	}
+}
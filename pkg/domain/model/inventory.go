@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+const (
+	TopicStockAdjusted  = "inventory.stock.adjusted"
+	TopicStockReserved  = "inventory.stock.reserved"
+	TopicStockCommitted = "inventory.stock.committed"
+	TopicStockReleased  = "inventory.stock.released"
+)
+
+// Reasons carried by StockReleasedEvent.
+const (
+	StockReleaseRequested = "released"
+	StockReleaseExpired   = "expired"
+)
+
+// StockLine is a quantity of one SKU held in one warehouse.
+type StockLine struct {
+	SKU         string `json:"sku"`
+	WarehouseID string `json:"warehouse_id"`
+	Quantity    int    `json:"quantity"`
+}
+
+// StockAdjustedEvent is emitted when on-hand stock is changed by hand, e.g.
+// on receiving goods or after a stock take.
+type StockAdjustedEvent struct {
+	SKU         string    `json:"sku"`
+	WarehouseID string    `json:"warehouse_id"`
+	Delta       int       `json:"delta"`
+	OnHand      int       `json:"on_hand"`
+	Reserved    int       `json:"reserved"`
+	Reason      string    `json:"reason,omitempty"`
+	OccurredAt  time.Time `json:"occurred_at"`
+}
+
+type StockReservedEvent struct {
+	ReservationID string      `json:"reservation_id"`
+	OrderID       string      `json:"order_id"`
+	Lines         []StockLine `json:"lines"`
+	ExpiresAt     time.Time   `json:"expires_at"`
+	OccurredAt    time.Time   `json:"occurred_at"`
+}
+
+// StockCommittedEvent means reserved stock has left the warehouse books.
+type StockCommittedEvent struct {
+	ReservationID string      `json:"reservation_id"`
+	OrderID       string      `json:"order_id"`
+	Lines         []StockLine `json:"lines"`
+	OccurredAt    time.Time   `json:"occurred_at"`
+}
+
+// StockReleasedEvent returns reserved stock to the available pool. Reason is
+// StockReleaseRequested or StockReleaseExpired.
+type StockReleasedEvent struct {
+	ReservationID string      `json:"reservation_id"`
+	OrderID       string      `json:"order_id"`
+	Lines         []StockLine `json:"lines"`
+	Reason        string      `json:"reason"`
+	OccurredAt    time.Time   `json:"occurred_at"`
+}
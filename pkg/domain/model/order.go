@@ -0,0 +1,71 @@
+package model
+
+import "time"
+
+const (
+	TopicOrderCreated         = "order.created"
+	TopicOrderAwaitingPayment = "order.awaiting_payment"
+	TopicOrderPaid            = "order.paid"
+	TopicOrderShipped         = "order.shipped"
+	TopicOrderDelivered       = "order.delivered"
+	TopicOrderCancelled       = "order.cancelled"
+	TopicOrderRefunded        = "order.refunded"
+
+	TopicCreateOrderCommand = "order.create.command"
+)
+
+type CreateOrderCommandItem struct {
+	ProductID      string `json:"product_id"`
+	SKU            string `json:"sku"`
+	Name           string `json:"name"`
+	Quantity       int    `json:"quantity"`
+	UnitPriceMinor int64  `json:"unit_price_minor"`
+	Currency       string `json:"currency"`
+}
+
+// CreateOrderCommand asks the order service to place an order. CommandID is
+// used as the order idempotency key, so redelivery never creates duplicates.
+type CreateOrderCommand struct {
+	CommandID   string                   `json:"command_id"`
+	UserID      string                   `json:"user_id"`
+	Items       []CreateOrderCommandItem `json:"items"`
+	RequestedAt time.Time                `json:"requested_at"`
+}
+
+type OrderItem struct {
+	ProductID      string `json:"product_id"`
+	SKU            string `json:"sku"`
+	Quantity       int    `json:"quantity"`
+	UnitPriceMinor int64  `json:"unit_price_minor"`
+}
+
+type OrderCreatedEvent struct {
+	OrderID    string      `json:"order_id"`
+	UserID     string      `json:"user_id"`
+	TotalMinor int64       `json:"total_minor"`
+	Currency   string      `json:"currency"`
+	Items      []OrderItem `json:"items"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+// OrderTransition is the payload shared by every order state change event.
+type OrderTransition struct {
+	OrderID    string    `json:"order_id"`
+	UserID     string    `json:"user_id"`
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	Reason     string    `json:"reason,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+type OrderAwaitingPaymentEvent struct{ OrderTransition }
+
+type OrderPaidEvent struct{ OrderTransition }
+
+type OrderShippedEvent struct{ OrderTransition }
+
+type OrderDeliveredEvent struct{ OrderTransition }
+
+type OrderCancelledEvent struct{ OrderTransition }
+
+type OrderRefundedEvent struct{ OrderTransition }
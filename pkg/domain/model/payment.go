@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+const (
+	TopicPaymentAuthorized = "payment.authorized"
+	TopicPaymentDeclined   = "payment.declined"
+	TopicPaymentCaptured   = "payment.captured"
+	TopicPaymentRefunded   = "payment.refunded"
+	TopicPaymentVoided     = "payment.voided"
+)
+
+// PaymentChange is the payload shared by every payment event. AmountMinor is
+// the amount moved by the operation, not the payment total.
+type PaymentChange struct {
+	PaymentID   string    `json:"payment_id"`
+	OrderID     string    `json:"order_id"`
+	Status      string    `json:"status"`
+	AmountMinor int64     `json:"amount_minor"`
+	Currency    string    `json:"currency"`
+	Reason      string    `json:"reason,omitempty"`
+	OccurredAt  time.Time `json:"occurred_at"`
+}
+
+type PaymentAuthorizedEvent struct{ PaymentChange }
+
+type PaymentDeclinedEvent struct{ PaymentChange }
+
+type PaymentCapturedEvent struct{ PaymentChange }
+
+type PaymentRefundedEvent struct{ PaymentChange }
+
+type PaymentVoidedEvent struct{ PaymentChange }
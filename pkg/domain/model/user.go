@@ -2,6 +2,11 @@ package model
 
 import "time"
 
+const (
+	TopicUserRegistered = "user.registered"
+	TopicUserErased     = "user.erased"
+)
+
 type User struct {
 	ID    string
 	Name  string
@@ -13,6 +18,13 @@ type UserRegisteredEvent struct {
 	UserID       string    `json:"user_id"`
 	Name         string    `json:"name"`
 	Email        string    `json:"email"`
-	Role         string    `json:"role"`
+	Roles        []string  `json:"roles"`
 	RegisteredAt time.Time `json:"registered_at"`
 }
+
+// UserErasedEvent tells other services to drop personal data they hold for
+// the user. It deliberately carries nothing but the id.
+type UserErasedEvent struct {
+	UserID   string    `json:"user_id"`
+	ErasedAt time.Time `json:"erased_at"`
+}
@@ -0,0 +1,78 @@
+package errs
+
+import (
+	"errors"
+	"time"
+)
+
+// Error is an error that is safe to show to clients. Code is a stable
+// machine-readable identifier, Status the HTTP status it maps to and
+// Message the text clients see.
+type Error struct {
+	Code    string
+	Status  int
+	Message string
+}
+
+func New(code string, status int, message string) *Error {
+	return &Error{Code: code, Status: status, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Wrap attaches cause to kind. The result matches kind with errors.Is and
+// errors.As, so clients only ever see kind's public message, while Error()
+// keeps the cause for logs.
+func Wrap(kind *Error, cause error) error {
+	if cause == nil {
+		return kind
+	}
+	return &wrapped{kind: kind, cause: cause}
+}
+
+type wrapped struct {
+	kind  *Error
+	cause error
+}
+
+func (w *wrapped) Error() string {
+	return w.kind.Message + ": " + w.cause.Error()
+}
+
+func (w *wrapped) Unwrap() []error {
+	return []error{w.kind, w.cause}
+}
+
+// Public returns the client-facing error carried by err, if any.
+func Public(err error) (*Error, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
+
+// Throttled is ErrTooManyRequests together with how long the caller should
+// wait before trying again.
+type Throttled struct {
+	RetryAfter time.Duration
+}
+
+func (t *Throttled) Error() string {
+	return ErrTooManyRequests.Message
+}
+
+func (t *Throttled) Unwrap() error {
+	return ErrTooManyRequests
+}
+
+// RetryAfter reports the wait carried by a *Throttled in err's chain.
+func RetryAfter(err error) (time.Duration, bool) {
+	var t *Throttled
+	if errors.As(err, &t) {
+		return t.RetryAfter, true
+	}
+	return 0, false
+}
@@ -1,12 +1,94 @@
 package errs
 
-import "errors"
+import "net/http"
 
 var (
+	// http
+	ErrInternal             = New("internal_error", http.StatusInternalServerError, "internal server error")
+	ErrInvalidRequestBody   = New("invalid_request_body", http.StatusBadRequest, "invalid request body")
+	ErrUnsupportedMediaType = New("unsupported_media_type", http.StatusUnsupportedMediaType, "unsupported content type")
+	ErrUnauthenticated      = New("unauthenticated", http.StatusUnauthorized, "authentication required")
+	ErrInvalidAuthHeader    = New("invalid_authorization_header", http.StatusUnauthorized, "invalid authorization header format")
+	ErrForbidden            = New("forbidden", http.StatusForbidden, "you are not allowed to perform this action")
+	ErrMFARequired          = New("mfa_required", http.StatusForbidden, "this action requires signing in with two-factor authentication")
+	ErrTooManyRequests      = New("too_many_requests", http.StatusTooManyRequests, "too many requests, try again later")
+	ErrAuthUnavailable      = New("auth_unavailable", http.StatusServiceUnavailable, "authentication is temporarily unavailable")
+
 	// user
-	ErrUserNotFound    = errors.New("user not found")
-	ErrUserExists      = errors.New("user already exists")
-	ErrInvalidID       = errors.New("invalid user id")
-	ErrInvalidEmail    = errors.New("invalid user email")
-	ErrFailedToGetUser = errors.New("failed to get user")
+	ErrUserNotFound    = New("user_not_found", http.StatusNotFound, "user not found")
+	ErrUserExists      = New("user_exists", http.StatusConflict, "user already exists")
+	ErrInvalidID       = New("invalid_id", http.StatusBadRequest, "invalid user id")
+	ErrInvalidEmail    = New("invalid_email", http.StatusBadRequest, "invalid user email")
+	ErrInvalidUsername = New("invalid_username", http.StatusBadRequest, "invalid username")
+	ErrInvalidAvatar   = New("invalid_avatar", http.StatusBadRequest, "invalid avatar url")
+	ErrFailedToGetUser = New("failed_to_get_user", http.StatusInternalServerError, "failed to get user")
+	ErrInvalidCursor   = New("invalid_cursor", http.StatusBadRequest, "invalid pagination cursor")
+	ErrInvalidFilter   = New("invalid_filter", http.StatusBadRequest, "invalid list filter")
+	ErrInvalidPatch    = New("invalid_patch", http.StatusBadRequest, "invalid merge patch: expected an object with username, email or avatar")
+	ErrVersionConflict = New("version_conflict", http.StatusPreconditionFailed, "user was modified by another request")
+	ErrIfMatchRequired = New("if_match_required", http.StatusPreconditionRequired, "If-Match header is required")
+
+	ErrAvatarTooLarge         = New("avatar_too_large", http.StatusRequestEntityTooLarge, "avatar image is too large")
+	ErrUnsupportedImage       = New("unsupported_image_type", http.StatusUnsupportedMediaType, "avatar must be a JPEG, PNG or WebP image")
+	ErrInvalidImage           = New("invalid_image", http.StatusBadRequest, "avatar image could not be decoded")
+	ErrInvalidImageDimensions = New("invalid_image_dimensions", http.StatusUnprocessableEntity, "avatar image dimensions are out of range")
+	ErrAvatarRequired         = New("avatar_required", http.StatusBadRequest, "multipart body must contain an avatar part")
+
+	ErrInvalidVerificationToken = New("invalid_verification_token", http.StatusBadRequest, "verification link is invalid or has expired")
+	ErrEmailAlreadyVerified     = New("email_already_verified", http.StatusConflict, "email is already verified")
+
+	// auth
+	ErrInvalidCredentials = New("invalid_credentials", http.StatusUnauthorized, "invalid email or password")
+	ErrInvalidPassword    = New("invalid_password", http.StatusBadRequest, "password must be at least 8 characters")
+	ErrInvalidToken       = New("invalid_token", http.StatusUnauthorized, "invalid or expired token")
+	ErrInvalidClient      = New("invalid_client", http.StatusUnauthorized, "invalid client credentials")
+	ErrSessionNotFound    = New("session_not_found", http.StatusNotFound, "session not found")
+	ErrInvalidResetToken  = New("invalid_reset_token", http.StatusBadRequest, "password reset link is invalid or has expired")
+	ErrInvalidMFAToken    = New("invalid_mfa_token", http.StatusUnauthorized, "sign-in attempt is invalid or has expired, sign in again")
+	ErrInvalidMFACode     = New("invalid_mfa_code", http.StatusUnauthorized, "invalid authentication code")
+	ErrMFAAlreadyEnabled  = New("mfa_already_enabled", http.StatusConflict, "two-factor authentication is already enabled")
+	ErrMFANotEnrolled     = New("mfa_not_enrolled", http.StatusConflict, "two-factor authentication is not set up")
+
+	// catalog
+	ErrProductNotFound  = New("product_not_found", http.StatusNotFound, "product not found")
+	ErrProductExists    = New("product_exists", http.StatusConflict, "product with this sku already exists")
+	ErrInvalidProduct   = New("invalid_product", http.StatusBadRequest, "invalid product")
+	ErrCategoryNotFound = New("category_not_found", http.StatusNotFound, "category not found")
+	ErrCategoryExists   = New("category_exists", http.StatusConflict, "category with this slug already exists")
+	ErrCategoryInUse    = New("category_in_use", http.StatusConflict, "category has subcategories")
+	ErrInvalidCategory  = New("invalid_category", http.StatusBadRequest, "invalid category")
+
+	// order
+	ErrOrderNotFound          = New("order_not_found", http.StatusNotFound, "order not found")
+	ErrInvalidOrder           = New("invalid_order", http.StatusBadRequest, "invalid order")
+	ErrInvalidTransition      = New("invalid_transition", http.StatusConflict, "illegal order status transition")
+	ErrIdempotencyKeyRequired = New("idempotency_key_required", http.StatusBadRequest, "Idempotency-Key header is required")
+	ErrIdempotencyKeyReused   = New("idempotency_key_reused", http.StatusUnprocessableEntity, "idempotency key was already used with a different request")
+	ErrCheckoutNotFound       = New("checkout_not_found", http.StatusNotFound, "checkout not found")
+
+	// cart
+	ErrInvalidCartItem   = New("invalid_cart_item", http.StatusBadRequest, "invalid cart item")
+	ErrCartItemNotFound  = New("cart_item_not_found", http.StatusNotFound, "item is not in the cart")
+	ErrCartEmpty         = New("cart_empty", http.StatusBadRequest, "cart is empty")
+	ErrCartChanged       = New("cart_changed", http.StatusConflict, "cart items changed, review the cart before checkout")
+	ErrCartOwnerRequired = New("cart_owner_required", http.StatusBadRequest, "X-Cart-ID header or authorization is required")
+
+	// inventory
+	ErrStockNotFound          = New("stock_not_found", http.StatusNotFound, "no stock recorded for this sku")
+	ErrInsufficientStock      = New("insufficient_stock", http.StatusConflict, "insufficient stock")
+	ErrInvalidStockAdjustment = New("invalid_stock_adjustment", http.StatusBadRequest, "invalid stock adjustment")
+	ErrReservationNotFound    = New("reservation_not_found", http.StatusNotFound, "reservation not found")
+	ErrInvalidReservation     = New("invalid_reservation", http.StatusBadRequest, "invalid reservation")
+	ErrReservationNotHeld     = New("reservation_not_held", http.StatusConflict, "reservation is no longer held")
+	ErrReservationExpired     = New("reservation_expired", http.StatusConflict, "reservation has expired")
+
+	// payment
+	ErrPaymentNotFound     = New("payment_not_found", http.StatusNotFound, "payment not found")
+	ErrInvalidPayment      = New("invalid_payment", http.StatusBadRequest, "invalid payment")
+	ErrPaymentDeclined     = New("payment_declined", http.StatusPaymentRequired, "payment declined")
+	ErrInvalidPaymentState = New("invalid_payment_state", http.StatusConflict, "operation is not allowed in the current payment state")
+	ErrPaymentBusy         = New("payment_busy", http.StatusConflict, "another operation on this payment is in progress")
+	ErrPaymentExists       = New("payment_exists", http.StatusConflict, "order already has a payment")
+	ErrProviderUnavailable = New("provider_unavailable", http.StatusBadGateway, "payment provider unavailable, retry with the same Idempotency-Key")
+	ErrInvalidSignature    = New("invalid_signature", http.StatusUnauthorized, "invalid webhook signature")
 )
@@ -0,0 +1,241 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-market/pkg/logger/sl"
+)
+
+// Handler processes one decoded message. Returning an error retries the
+// message with backoff; wrap it with Permanent to send it straight to the
+// dead-letter topic.
+type Handler[T any] func(ctx context.Context, msg Message, event T) error
+
+type ConsumerConfig struct {
+	Topic string
+	Group string
+	// DeadLetterTopic receives messages that could not be handled. Defaults
+	// to Topic + ".dlq".
+	DeadLetterTopic string
+	MaxAttempts     int
+	BaseBackoff     time.Duration
+	MaxBackoff      time.Duration
+	// HandlerTimeout bounds a single handler call. On shutdown the message
+	// in flight gets this long to finish.
+	HandlerTimeout time.Duration
+}
+
+// DeadLetter is the value published to the dead-letter topic. It carries the
+// original message untouched plus why it was given up on.
+type DeadLetter struct {
+	Topic     string    `json:"topic"`
+	Partition int       `json:"partition"`
+	Offset    int64     `json:"offset"`
+	Key       []byte    `json:"key"`
+	Value     []byte    `json:"value"`
+	Group     string    `json:"group"`
+	Error     string    `json:"error"`
+	Attempts  int       `json:"attempts"`
+	FailedAt  time.Time `json:"failed_at"`
+}
+
+// Consumer reads JSON-encoded T from a consumer group and hands each message
+// to a Handler. Delivery is at-least-once: the offset is committed only
+// after the handler succeeded or the message was dead-lettered.
+type Consumer[T any] struct {
+	reader  Reader
+	dlq     Publisher
+	handler Handler[T]
+	log     *slog.Logger
+	cfg     ConsumerConfig
+}
+
+func NewConsumer[T any](reader Reader, dlq Publisher, handler Handler[T], log *slog.Logger, cfg ConsumerConfig) *Consumer[T] {
+	if cfg.DeadLetterTopic == "" {
+		cfg.DeadLetterTopic = cfg.Topic + ".dlq"
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = 500 * time.Millisecond
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 30 * time.Second
+	}
+	if cfg.HandlerTimeout <= 0 {
+		cfg.HandlerTimeout = 30 * time.Second
+	}
+
+	return &Consumer[T]{
+		reader:  reader,
+		dlq:     dlq,
+		handler: handler,
+		log: log.With(
+			slog.String("component", "kafka.Consumer"),
+			slog.String("topic", cfg.Topic),
+			slog.String("group", cfg.Group),
+		),
+		cfg: cfg,
+	}
+}
+
+// Run consumes until ctx is cancelled. The message being handled when that
+// happens is finished (within HandlerTimeout) and committed; messages that
+// were waiting for a retry are left uncommitted and redelivered later.
+func (c *Consumer[T]) Run(ctx context.Context) {
+	for {
+		msg, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			c.log.Error("failed to fetch message", sl.Err(err))
+			if !sleep(ctx, c.cfg.BaseBackoff) {
+				return
+			}
+			continue
+		}
+
+		if !c.process(ctx, msg) {
+			return
+		}
+	}
+}
+
+// process handles msg and commits it. It returns false when shutdown
+// interrupted it before the message could be committed.
+func (c *Consumer[T]) process(ctx context.Context, msg Message) bool {
+	log := c.log.With(slog.Int("partition", msg.Partition), slog.Int64("offset", msg.Offset))
+
+	var (
+		event    T
+		attempts int
+	)
+	err := json.Unmarshal(msg.Value, &event)
+	if err != nil {
+		err = Permanent(fmt.Errorf("decode message: %w", err))
+	}
+
+	for err == nil {
+		attempts++
+		if err = c.handle(ctx, msg, event); err == nil || IsPermanent(err) || attempts >= c.cfg.MaxAttempts {
+			break
+		}
+
+		delay := backoff(c.cfg.BaseBackoff, c.cfg.MaxBackoff, attempts)
+		log.Warn("failed to handle message, retrying",
+			slog.Int("attempts", attempts), slog.Duration("retry_in", delay), sl.Err(err))
+		if !sleep(ctx, delay) {
+			return false
+		}
+		err = nil
+	}
+
+	if err != nil {
+		log.Error("giving up on message, sending to dead-letter topic",
+			slog.String("dead_letter_topic", c.cfg.DeadLetterTopic), slog.Int("attempts", attempts), sl.Err(err))
+		if !c.deadLetter(ctx, msg, err, attempts) {
+			return false
+		}
+	}
+
+	// Commit even while shutting down: the handler already did its work.
+	commitCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := c.reader.CommitMessages(commitCtx, msg); err != nil {
+		log.Error("failed to commit message", sl.Err(err))
+	}
+
+	return true
+}
+
+// handle runs the handler detached from ctx cancellation so a shutdown does
+// not abort it halfway; HandlerTimeout still bounds it.
+func (c *Consumer[T]) handle(ctx context.Context, msg Message, event T) (err error) {
+	hctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.cfg.HandlerTimeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = Permanent(fmt.Errorf("handler panic: %v", r))
+		}
+	}()
+
+	return c.handler(hctx, msg, event)
+}
+
+// deadLetter keeps trying to publish to the dead-letter topic, because
+// committing without it would lose the message.
+func (c *Consumer[T]) deadLetter(ctx context.Context, msg Message, cause error, attempts int) bool {
+	value, err := json.Marshal(DeadLetter{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Key:       msg.Key,
+		Value:     msg.Value,
+		Group:     c.cfg.Group,
+		Error:     cause.Error(),
+		Attempts:  attempts,
+		FailedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		c.log.Error("failed to encode dead letter", sl.Err(err))
+		return false
+	}
+
+	for i := 1; ; i++ {
+		if err := c.dlq.Publish(ctx, c.cfg.DeadLetterTopic, msg.Key, value); err == nil {
+			return true
+		} else if ctx.Err() == nil {
+			c.log.Error("failed to publish dead letter", sl.Err(err))
+		}
+		if !sleep(ctx, backoff(c.cfg.BaseBackoff, c.cfg.MaxBackoff, i)) {
+			return false
+		}
+	}
+}
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks a handler error as not worth retrying.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+func backoff(base, max time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	return min(delay, max)
+}
+
+// sleep waits for d and reports false if ctx was cancelled first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -0,0 +1,278 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	testTopic = "orders"
+	testGroup = "test-group"
+)
+
+type testEvent struct {
+	ID string `json:"id"`
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// committed reports the offset group has committed on topic.
+func committed(b *MemoryBroker, topic, group string) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.offsets[groupTopic{group: group, topic: topic}]
+}
+
+// waitFor polls cond until it holds or the test times out.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the consumer")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConsumer(t *testing.T) {
+	const maxAttempts = 3
+
+	tests := []struct {
+		name         string
+		value        []byte
+		handler      func(calls int) error
+		wantCalls    int
+		wantDLQ      bool
+		wantAttempts int
+	}{
+		{
+			name:      "handled first time",
+			value:     []byte(`{"id":"1"}`),
+			handler:   func(int) error { return nil },
+			wantCalls: 1,
+		},
+		{
+			name:  "retried until it succeeds",
+			value: []byte(`{"id":"1"}`),
+			handler: func(calls int) error {
+				if calls < 2 {
+					return errors.New("database is down")
+				}
+				return nil
+			},
+			wantCalls: 2,
+		},
+		{
+			name:         "dead-lettered after max attempts",
+			value:        []byte(`{"id":"1"}`),
+			handler:      func(int) error { return errors.New("database is down") },
+			wantCalls:    maxAttempts,
+			wantDLQ:      true,
+			wantAttempts: maxAttempts,
+		},
+		{
+			name:         "permanent error is dead-lettered at once",
+			value:        []byte(`{"id":"1"}`),
+			handler:      func(int) error { return Permanent(errors.New("unknown product")) },
+			wantCalls:    1,
+			wantDLQ:      true,
+			wantAttempts: 1,
+		},
+		{
+			name:      "undecodable message is dead-lettered without calling the handler",
+			value:     []byte(`{"id":`),
+			handler:   func(int) error { return nil },
+			wantCalls: 0,
+			wantDLQ:   true,
+		},
+		{
+			name:         "handler panic is dead-lettered",
+			value:        []byte(`{"id":"1"}`),
+			handler:      func(int) error { panic("nil map") },
+			wantCalls:    1,
+			wantDLQ:      true,
+			wantAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker := NewMemoryBroker()
+			if err := broker.Publish(context.Background(), testTopic, []byte("key"), tt.value); err != nil {
+				t.Fatalf("Publish() error = %v", err)
+			}
+
+			var calls atomic.Int32
+			handler := func(_ context.Context, _ Message, event testEvent) error {
+				n := int(calls.Add(1))
+				if event.ID != "1" {
+					t.Errorf("handler got event %+v", event)
+				}
+				return tt.handler(n)
+			}
+
+			consumer := NewConsumer(broker.Subscribe(testTopic, testGroup), broker, handler, discardLogger(), ConsumerConfig{
+				Topic:       testTopic,
+				Group:       testGroup,
+				MaxAttempts: maxAttempts,
+				BaseBackoff: time.Millisecond,
+				MaxBackoff:  time.Millisecond,
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				consumer.Run(ctx)
+			}()
+
+			waitFor(t, func() bool { return committed(broker, testTopic, testGroup) == 1 })
+			cancel()
+			<-done
+
+			if got := int(calls.Load()); got != tt.wantCalls {
+				t.Errorf("handler calls = %d, want %d", got, tt.wantCalls)
+			}
+
+			dead := broker.Messages(testTopic + ".dlq")
+			if !tt.wantDLQ {
+				if len(dead) != 0 {
+					t.Errorf("dead letters = %d, want none", len(dead))
+				}
+				return
+			}
+			if len(dead) != 1 {
+				t.Fatalf("dead letters = %d, want 1", len(dead))
+			}
+
+			var letter DeadLetter
+			if err := json.Unmarshal(dead[0].Value, &letter); err != nil {
+				t.Fatalf("decode dead letter: %v", err)
+			}
+			if string(letter.Value) != string(tt.value) || letter.Topic != testTopic || letter.Group != testGroup {
+				t.Errorf("dead letter = %+v, want the original message from %s", letter, testTopic)
+			}
+			if letter.Attempts != tt.wantAttempts {
+				t.Errorf("dead letter attempts = %d, want %d", letter.Attempts, tt.wantAttempts)
+			}
+			if letter.Error == "" {
+				t.Error("dead letter has no error")
+			}
+		})
+	}
+}
+
+// A message still waiting for a retry at shutdown is not committed, so the
+// next reader of the group gets it again.
+func TestConsumerRedeliversUncommittedOnShutdown(t *testing.T) {
+	broker := NewMemoryBroker()
+	if err := broker.Publish(context.Background(), testTopic, nil, []byte(`{"id":"1"}`)); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	var calls atomic.Int32
+	consumer := NewConsumer(broker.Subscribe(testTopic, testGroup), broker,
+		func(context.Context, Message, testEvent) error {
+			calls.Add(1)
+			return errors.New("database is down")
+		},
+		discardLogger(), ConsumerConfig{Topic: testTopic, Group: testGroup, BaseBackoff: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		consumer.Run(ctx)
+	}()
+
+	waitFor(t, func() bool { return calls.Load() == 1 })
+	cancel()
+	<-done
+
+	if got := committed(broker, testTopic, testGroup); got != 0 {
+		t.Fatalf("committed offset = %d, want 0", got)
+	}
+
+	fetchCtx, cancelFetch := context.WithTimeout(context.Background(), time.Second)
+	defer cancelFetch()
+	msg, err := broker.Subscribe(testTopic, testGroup).FetchMessage(fetchCtx)
+	if err != nil {
+		t.Fatalf("FetchMessage() error = %v", err)
+	}
+	if msg.Offset != 0 {
+		t.Errorf("redelivered offset = %d, want 0", msg.Offset)
+	}
+}
+
+func TestMemoryReaderResumesFromCommittedOffset(t *testing.T) {
+	broker := NewMemoryBroker()
+	ctx := context.Background()
+	for _, v := range []string{"a", "b", "c"} {
+		if err := broker.Publish(ctx, testTopic, nil, []byte(v)); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+
+	first := broker.Subscribe(testTopic, testGroup)
+	msg, err := first.FetchMessage(ctx)
+	if err != nil {
+		t.Fatalf("FetchMessage() error = %v", err)
+	}
+	if err := first.CommitMessages(ctx, msg); err != nil {
+		t.Fatalf("CommitMessages() error = %v", err)
+	}
+	// Fetched but not committed.
+	if _, err := first.FetchMessage(ctx); err != nil {
+		t.Fatalf("FetchMessage() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		group string
+		want  string
+	}{
+		{name: "same group continues after the commit", group: testGroup, want: "b"},
+		{name: "other group starts at the beginning", group: "other", want: "a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := broker.Subscribe(testTopic, tt.group).FetchMessage(ctx)
+			if err != nil {
+				t.Fatalf("FetchMessage() error = %v", err)
+			}
+			if string(msg.Value) != tt.want {
+				t.Errorf("FetchMessage() = %q, want %q", msg.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: 100 * time.Millisecond},
+		{attempts: 3, want: 400 * time.Millisecond},
+		{attempts: 5, want: time.Second},
+		{attempts: 100, want: time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := backoff(100*time.Millisecond, time.Second, tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Time      time.Time
+}
+
+// MemoryBroker is an in-process stand-in for Kafka intended for tests and
+// local runs without a cluster. Each topic is a single partition, and
+// consumer groups track their committed offset like Kafka does.
+type MemoryBroker struct {
+	mu      sync.Mutex
+	topics  map[string][]Message
+	offsets map[groupTopic]int64
+	// notify is closed and replaced on every publish to wake blocked readers.
+	notify chan struct{}
+}
+
+type groupTopic struct {
+	group string
+	topic string
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		topics:  make(map[string][]Message),
+		offsets: make(map[groupTopic]int64),
+		notify:  make(chan struct{}),
+	}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, topic string, key, value []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.topics[topic] = append(b.topics[topic], Message{
+		Topic:  topic,
+		Offset: int64(len(b.topics[topic])),
+		Key:    append([]byte(nil), key...),
+		Value:  append([]byte(nil), value...),
+		Time:   time.Now(),
+	})
+
+	close(b.notify)
+	b.notify = make(chan struct{})
+
+	return nil
+}
+
+// Messages returns a copy of everything published to topic so far.
+func (b *MemoryBroker) Messages(topic string) []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]Message(nil), b.topics[topic]...)
+}
+
+// Subscribe returns a Reader for topic in consumer group. It starts at the
+// group's committed offset, so a new reader picks up where a previous one
+// of the same group stopped.
+func (b *MemoryBroker) Subscribe(topic, group string) *MemoryReader {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	gt := groupTopic{group: group, topic: topic}
+	return &MemoryReader{broker: b, gt: gt, next: b.offsets[gt]}
+}
+
+// MemoryReader is the Reader side of MemoryBroker.
+type MemoryReader struct {
+	broker *MemoryBroker
+	gt     groupTopic
+	next   int64
+}
+
+func (r *MemoryReader) FetchMessage(ctx context.Context) (Message, error) {
+	for {
+		r.broker.mu.Lock()
+		msgs := r.broker.topics[r.gt.topic]
+		if r.next < int64(len(msgs)) {
+			msg := msgs[r.next]
+			r.next++
+			r.broker.mu.Unlock()
+			return msg, nil
+		}
+		notify := r.broker.notify
+		r.broker.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		case <-notify:
+		}
+	}
+}
+
+func (r *MemoryReader) CommitMessages(_ context.Context, msgs ...Message) error {
+	r.broker.mu.Lock()
+	defer r.broker.mu.Unlock()
+
+	for _, msg := range msgs {
+		if msg.Offset+1 > r.broker.offsets[r.gt] {
+			r.broker.offsets[r.gt] = msg.Offset + 1
+		}
+	}
+	return nil
+}
+
+func (r *MemoryReader) Close() error {
+	return nil
+}
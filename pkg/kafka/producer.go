@@ -0,0 +1,50 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Publisher is implemented by both the Kafka-backed Producer and the
+// in-memory MemoryBroker, so callers never depend on a live cluster.
+type Publisher interface {
+	Publish(ctx context.Context, topic string, key, value []byte) error
+}
+
+type Producer struct {
+	w *kafka.Writer
+}
+
+func NewProducer(brokers []string) *Producer {
+	return &Producer{
+		w: &kafka.Writer{
+			Addr:                   kafka.TCP(brokers...),
+			Balancer:               &kafka.Hash{},
+			RequiredAcks:           kafka.RequireAll,
+			AllowAutoTopicCreation: true,
+			BatchTimeout:           10 * time.Millisecond,
+		},
+	}
+}
+
+func (p *Producer) Publish(ctx context.Context, topic string, key, value []byte) error {
+	const op = "kafka.Producer.Publish"
+
+	err := p.w.WriteMessages(ctx, kafka.Message{
+		Topic: topic,
+		Key:   key,
+		Value: value,
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (p *Producer) Close() error {
+	return p.w.Close()
+}
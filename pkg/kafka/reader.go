@@ -0,0 +1,72 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Reader is a consumer-group subscription to one topic. FetchMessage does
+// not advance the committed offset; CommitMessages does, so a message that
+// was fetched but never committed is delivered again after a restart.
+type Reader interface {
+	FetchMessage(ctx context.Context) (Message, error)
+	CommitMessages(ctx context.Context, msgs ...Message) error
+	Close() error
+}
+
+// GroupReader is the Kafka-backed Reader.
+type GroupReader struct {
+	r *kafka.Reader
+}
+
+func NewGroupReader(brokers []string, topic, group string) *GroupReader {
+	return &GroupReader{
+		r: kafka.NewReader(kafka.ReaderConfig{
+			Brokers:  brokers,
+			Topic:    topic,
+			GroupID:  group,
+			MinBytes: 1,
+			MaxBytes: 10e6,
+			// Commits are synchronous and explicit, after the handler is done.
+			CommitInterval: 0,
+		}),
+	}
+}
+
+func (g *GroupReader) FetchMessage(ctx context.Context) (Message, error) {
+	const op = "kafka.GroupReader.FetchMessage"
+
+	m, err := g.r.FetchMessage(ctx)
+	if err != nil {
+		return Message{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return Message{
+		Topic:     m.Topic,
+		Partition: m.Partition,
+		Offset:    m.Offset,
+		Key:       m.Key,
+		Value:     m.Value,
+		Time:      m.Time,
+	}, nil
+}
+
+func (g *GroupReader) CommitMessages(ctx context.Context, msgs ...Message) error {
+	const op = "kafka.GroupReader.CommitMessages"
+
+	km := make([]kafka.Message, 0, len(msgs))
+	for _, m := range msgs {
+		km = append(km, kafka.Message{Topic: m.Topic, Partition: m.Partition, Offset: m.Offset})
+	}
+	if err := g.r.CommitMessages(ctx, km...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (g *GroupReader) Close() error {
+	return g.r.Close()
+}
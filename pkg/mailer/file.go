@@ -0,0 +1,48 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FileMailer writes each message to Dir as an .eml file and logs where it
+// went, so links in local emails can be followed without an SMTP server.
+type FileMailer struct {
+	dir  string
+	from string
+	log  *slog.Logger
+}
+
+func NewFile(dir, from string, log *slog.Logger) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("mailer.NewFile: %w", err)
+	}
+	return &FileMailer{
+		dir:  dir,
+		from: from,
+		log:  log.With(slog.String("component", "mailer.FileMailer")),
+	}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	const op = "mailer.FileMailer.Send"
+
+	body, err := render(m.from, msg)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	name := filepath.Join(m.dir, time.Now().UTC().Format("20060102T150405")+"-"+uuid.NewString()+".eml")
+	if err := os.WriteFile(name, body, 0o644); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	m.log.Info("email written", slog.String("to", msg.To), slog.String("subject", msg.Subject), slog.String("file", name))
+	return nil
+}
@@ -0,0 +1,110 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/go-market/pkg/logger/sl"
+)
+
+// ErrQueueFull is returned by Queue.Send when no more messages fit.
+var ErrQueueFull = errors.New("mail queue is full")
+
+type QueueConfig struct {
+	Size        int
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	SendTimeout time.Duration
+}
+
+// Queue is a Mailer that delivers in the background, so callers neither
+// wait for the mail server nor learn whether delivery worked. Messages are
+// kept in memory only: those still queued when the process stops are lost,
+// which suits mail the recipient can simply ask for again.
+type Queue struct {
+	next     Mailer
+	log      *slog.Logger
+	cfg      QueueConfig
+	messages chan Message
+}
+
+func NewQueue(next Mailer, log *slog.Logger, cfg QueueConfig) *Queue {
+	if cfg.Size <= 0 {
+		cfg.Size = 1000
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = time.Minute
+	}
+	if cfg.SendTimeout <= 0 {
+		cfg.SendTimeout = 30 * time.Second
+	}
+
+	return &Queue{
+		next:     next,
+		log:      log.With(slog.String("component", "mailer.Queue")),
+		cfg:      cfg,
+		messages: make(chan Message, cfg.Size),
+	}
+}
+
+// Send queues msg. It fails with ErrQueueFull instead of blocking.
+func (q *Queue) Send(ctx context.Context, msg Message) error {
+	select {
+	case q.messages <- msg:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Run delivers queued messages one at a time until ctx is cancelled.
+func (q *Queue) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-q.messages:
+			q.deliver(ctx, msg)
+		}
+	}
+}
+
+// deliver retries a failed send with exponential backoff and drops the
+// message after MaxAttempts.
+func (q *Queue) deliver(ctx context.Context, msg Message) {
+	for attempt := 1; ; attempt++ {
+		sendCtx, cancel := context.WithTimeout(ctx, q.cfg.SendTimeout)
+		err := q.next.Send(sendCtx, msg)
+		cancel()
+		if err == nil {
+			return
+		}
+		if attempt >= q.cfg.MaxAttempts || ctx.Err() != nil {
+			q.log.Error("failed to send mail", slog.String("subject", msg.Subject),
+				slog.Int("attempts", attempt), sl.Err(err))
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(q.backoff(attempt)):
+		}
+	}
+}
+
+func (q *Queue) backoff(attempts int) time.Duration {
+	d := q.cfg.BaseBackoff << (attempts - 1)
+	if d <= 0 || d > q.cfg.MaxBackoff {
+		return q.cfg.MaxBackoff
+	}
+	return d
+}
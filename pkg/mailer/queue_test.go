@@ -0,0 +1,104 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyMailer fails its first failures sends and records the rest.
+type flakyMailer struct {
+	mu       sync.Mutex
+	failures int
+	calls    int
+	sent     []Message
+}
+
+func (m *flakyMailer) Send(_ context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls++
+	if m.calls <= m.failures {
+		return errors.New("connection refused")
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func (m *flakyMailer) counts() (int, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.calls, len(m.sent)
+}
+
+func TestQueue(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		wantCalls int
+		wantSent  int
+	}{
+		{name: "delivered first time", failures: 0, wantCalls: 1, wantSent: 1},
+		{name: "retried until delivered", failures: 2, wantCalls: 3, wantSent: 1},
+		{name: "dropped after max attempts", failures: 10, wantCalls: 3, wantSent: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &flakyMailer{failures: tt.failures}
+			q := NewQueue(next, slog.New(slog.NewTextHandler(io.Discard, nil)), QueueConfig{
+				MaxAttempts: 3,
+				BaseBackoff: time.Millisecond,
+				MaxBackoff:  time.Millisecond,
+			})
+
+			if err := q.Send(context.Background(), Message{To: "alice@example.com"}); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				q.Run(ctx)
+			}()
+
+			deadline := time.Now().Add(2 * time.Second)
+			for {
+				if calls, _ := next.counts(); calls >= tt.wantCalls {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatal("timed out waiting for delivery")
+				}
+				time.Sleep(time.Millisecond)
+			}
+			// Let a wrong extra attempt show up before counting.
+			time.Sleep(10 * time.Millisecond)
+			cancel()
+			<-done
+
+			calls, sent := next.counts()
+			if calls != tt.wantCalls || sent != tt.wantSent {
+				t.Errorf("calls, sent = %d, %d, want %d, %d", calls, sent, tt.wantCalls, tt.wantSent)
+			}
+		})
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	q := NewQueue(&flakyMailer{}, slog.New(slog.NewTextHandler(io.Discard, nil)), QueueConfig{Size: 1})
+
+	if err := q.Send(context.Background(), Message{}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if err := q.Send(context.Background(), Message{}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Send() error = %v, want %v", err, ErrQueueFull)
+	}
+}
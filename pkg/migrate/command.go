@@ -0,0 +1,45 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const usage = "usage: migrate up|down|status"
+
+// Command implements the `migrate up|down|status` subcommand shared by the
+// service binaries.
+func Command(ctx context.Context, m *Migrator, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return errors.New(usage)
+	}
+
+	switch args[0] {
+	case "up":
+		n, err := m.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "applied %d migration(s)\n", n)
+	case "down":
+		return m.Down(ctx)
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(out, "%06d  %-40s  %s\n", s.Version, s.Name, applied)
+		}
+	default:
+		return errors.New(usage)
+	}
+
+	return nil
+}
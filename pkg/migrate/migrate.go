@@ -0,0 +1,327 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const schemaTable = "schema_migrations"
+
+// lockID is the pg_advisory_lock key shared by every migrator, so replicas
+// starting at the same time apply migrations one after another.
+const lockID int64 = 0x6d69677261746521
+
+var (
+	ErrNoDownMigration = errors.New("migration has no down file")
+	ErrUnknownVersion  = errors.New("database has a version with no migration file")
+	ErrNothingToRevert = errors.New("no applied migrations to revert")
+)
+
+var fileRe = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+}
+
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []Migration
+	log        *slog.Logger
+}
+
+// New loads versioned NNNNNN_name.up.sql / NNNNNN_name.down.sql files from
+// path. A "file://" prefix is accepted for compatibility with
+// golang-migrate style configs.
+func New(db *pgxpool.Pool, path string, log *slog.Logger) (*Migrator, error) {
+	const op = "migrate.New"
+
+	migrations, err := load(strings.TrimPrefix(path, "file://"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		log:        log.With(slog.String("component", "migrate")),
+	}, nil
+}
+
+func load(dir string) ([]Migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		match := fileRe.FindStringSubmatch(e.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %q: %w", e.Name(), err)
+		}
+
+		body, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Up applies every pending migration, each in its own transaction, and
+// returns how many were applied.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	const op = "migrate.Up"
+
+	applied := 0
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range pending(m.migrations, done) {
+			err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, mig.Up); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx,
+					`INSERT INTO `+schemaTable+` (version, name) VALUES ($1, $2)`, mig.Version, mig.Name)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("apply %d_%s: %w", mig.Version, mig.Name, err)
+			}
+
+			m.log.Info("applied migration", slog.Int64("version", mig.Version), slog.String("name", mig.Name))
+			applied++
+		}
+		return nil
+	})
+	if err != nil {
+		return applied, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return applied, nil
+}
+
+// Down reverts the most recently applied migration.
+func (m *Migrator) Down(ctx context.Context) error {
+	const op = "migrate.Down"
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		latest, err := latestApplied(m.migrations, done)
+		if err != nil {
+			return err
+		}
+
+		err = pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, latest.Down); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, `DELETE FROM `+schemaTable+` WHERE version = $1`, latest.Version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("revert %d_%s: %w", latest.Version, latest.Name, err)
+		}
+
+		m.log.Info("reverted migration", slog.Int64("version", latest.Version), slog.String("name", latest.Name))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Status lists every known migration with the time it was applied, if any.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	const op = "migrate.Status"
+
+	var statuses []Status
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range m.migrations {
+			s := Status{Version: mig.Version, Name: mig.Name}
+			if at, ok := done[mig.Version]; ok {
+				s.AppliedAt = &at
+			}
+			statuses = append(statuses, s)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return statuses, nil
+}
+
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return fmt.Errorf("acquire advisory lock: %w", err)
+	}
+	defer func() {
+		// Use a fresh context so the lock is released even if ctx was cancelled.
+		_, _ = conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID)
+	}()
+
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS `+schemaTable+` (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", schemaTable, err)
+	}
+
+	return fn(conn)
+}
+
+// applied returns the applied versions and fails if the database knows a
+// version that is missing on disk, which means the binary is older than the
+// schema it is pointed at.
+func (m *Migrator) applied(ctx context.Context, conn *pgxpool.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.Query(ctx, `SELECT version, applied_at FROM `+schemaTable)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := make(map[int64]time.Time)
+	for rows.Next() {
+		var (
+			version   int64
+			appliedAt time.Time
+		)
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		done[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := checkKnown(m.migrations, done); err != nil {
+		return nil, err
+	}
+	return done, nil
+}
+
+// checkKnown fails if done has a version that none of migrations has.
+func checkKnown(migrations []Migration, done map[int64]time.Time) error {
+	known := make(map[int64]struct{}, len(migrations))
+	for _, mig := range migrations {
+		known[mig.Version] = struct{}{}
+	}
+
+	var unknown []int64
+	for version := range done {
+		if _, ok := known[version]; !ok {
+			unknown = append(unknown, version)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Slice(unknown, func(i, j int) bool { return unknown[i] < unknown[j] })
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, unknown[0])
+	}
+	return nil
+}
+
+// pending returns the migrations not in done, in version order.
+func pending(migrations []Migration, done map[int64]time.Time) []Migration {
+	var todo []Migration
+	for _, mig := range migrations {
+		if _, ok := done[mig.Version]; !ok {
+			todo = append(todo, mig)
+		}
+	}
+	return todo
+}
+
+// latestApplied returns the applied migration with the highest version,
+// which is the one Down reverts.
+func latestApplied(migrations []Migration, done map[int64]time.Time) (*Migration, error) {
+	for i := len(migrations) - 1; i >= 0; i-- {
+		if _, ok := done[migrations[i].Version]; !ok {
+			continue
+		}
+		latest := &migrations[i]
+		if latest.Down == "" {
+			return nil, fmt.Errorf("%d_%s: %w", latest.Version, latest.Name, ErrNoDownMigration)
+		}
+		return latest, nil
+	}
+	return nil, ErrNothingToRevert
+}
@@ -0,0 +1,272 @@
+package migrate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, body := range files {
+		if strings.HasSuffix(name, "/") {
+			if err := os.Mkdir(filepath.Join(dir, name), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name         string
+		files        map[string]string
+		wantVersions []int64
+		wantErr      string
+	}{
+		{
+			name: "ordered by version, not by name",
+			files: map[string]string{
+				"000010_add_index.up.sql":      "CREATE INDEX",
+				"000010_add_index.down.sql":    "DROP INDEX",
+				"000002_add_column.up.sql":     "ALTER TABLE",
+				"000001_create_table.up.sql":   "CREATE TABLE",
+				"000001_create_table.down.sql": "DROP TABLE",
+			},
+			wantVersions: []int64{1, 2, 10},
+		},
+		{
+			name: "other files and directories are ignored",
+			files: map[string]string{
+				"000001_create_table.up.sql": "CREATE TABLE",
+				"README.md":                  "notes",
+				"000002_draft.sql":           "SELECT 1",
+				"000003_nested.up.sql/":      "",
+			},
+			wantVersions: []int64{1},
+		},
+		{
+			name:         "empty directory",
+			files:        map[string]string{},
+			wantVersions: []int64{},
+		},
+		{
+			name: "down without up",
+			files: map[string]string{
+				"000001_create_table.down.sql": "DROP TABLE",
+			},
+			wantErr: "has no up file",
+		},
+		{
+			name: "one version, two names",
+			files: map[string]string{
+				"000001_create_table.up.sql": "CREATE TABLE",
+				"000001_create_users.up.sql": "CREATE TABLE",
+			},
+			wantErr: "conflicting names",
+		},
+		{
+			name: "version out of range",
+			files: map[string]string{
+				"99999999999999999999_huge.up.sql": "SELECT 1",
+			},
+			wantErr: "invalid migration version",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := load(writeFiles(t, tt.files))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load() error = %v", err)
+			}
+
+			versions := []int64{}
+			for _, m := range migrations {
+				versions = append(versions, m.Version)
+			}
+			if !slices.Equal(versions, tt.wantVersions) {
+				t.Errorf("versions = %v, want %v", versions, tt.wantVersions)
+			}
+		})
+	}
+}
+
+func TestLoadReadsBodies(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"000001_create_table.up.sql":   "CREATE TABLE t (id INT);",
+		"000001_create_table.down.sql": "DROP TABLE t;",
+	})
+
+	migrations, err := load(dir)
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+
+	want := Migration{Version: 1, Name: "create_table", Up: "CREATE TABLE t (id INT);", Down: "DROP TABLE t;"}
+	if len(migrations) != 1 || migrations[0] != want {
+		t.Errorf("load() = %+v, want %+v", migrations, want)
+	}
+}
+
+func TestBookkeeping(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Name: "create_table", Up: "up", Down: "down"},
+		{Version: 2, Name: "add_column", Up: "up"},
+		{Version: 3, Name: "add_index", Up: "up", Down: "down"},
+	}
+	applied := func(versions ...int64) map[int64]time.Time {
+		done := make(map[int64]time.Time)
+		for _, v := range versions {
+			done[v] = time.Now()
+		}
+		return done
+	}
+
+	tests := []struct {
+		name        string
+		done        map[int64]time.Time
+		wantPending []int64
+		wantLatest  int64
+		wantDownErr error
+		wantKnown   error
+	}{
+		{
+			name:        "fresh database",
+			done:        applied(),
+			wantPending: []int64{1, 2, 3},
+			wantDownErr: ErrNothingToRevert,
+		},
+		{
+			name:        "partly applied",
+			done:        applied(1),
+			wantPending: []int64{2, 3},
+			wantLatest:  1,
+		},
+		{
+			name:        "gap is filled in",
+			done:        applied(1, 3),
+			wantPending: []int64{2},
+			wantLatest:  3,
+		},
+		{
+			name:        "latest has no down file",
+			done:        applied(1, 2),
+			wantPending: []int64{3},
+			wantDownErr: ErrNoDownMigration,
+		},
+		{
+			name:        "up to date",
+			done:        applied(1, 2, 3),
+			wantLatest:  3,
+			wantPending: nil,
+		},
+		{
+			name:        "database is ahead of the binary",
+			done:        applied(1, 2, 3, 4),
+			wantLatest:  3,
+			wantPending: nil,
+			wantKnown:   ErrUnknownVersion,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkKnown(migrations, tt.done); !errors.Is(err, tt.wantKnown) {
+				t.Errorf("checkKnown() error = %v, want %v", err, tt.wantKnown)
+			}
+
+			var versions []int64
+			for _, m := range pending(migrations, tt.done) {
+				versions = append(versions, m.Version)
+			}
+			if !slices.Equal(versions, tt.wantPending) {
+				t.Errorf("pending() = %v, want %v", versions, tt.wantPending)
+			}
+
+			latest, err := latestApplied(migrations, tt.done)
+			if !errors.Is(err, tt.wantDownErr) {
+				t.Fatalf("latestApplied() error = %v, want %v", err, tt.wantDownErr)
+			}
+			if err == nil && latest.Version != tt.wantLatest {
+				t.Errorf("latestApplied() = %d, want %d", latest.Version, tt.wantLatest)
+			}
+		})
+	}
+}
+
+func TestMissingColumns(t *testing.T) {
+	columns := func(names ...string) map[string]struct{} {
+		set := make(map[string]struct{})
+		for _, n := range names {
+			set[n] = struct{}{}
+		}
+		return set
+	}
+	expected := map[string][]string{
+		"users":  {"id", "email", "version"},
+		"outbox": {"id", "locked_until"},
+	}
+
+	tests := []struct {
+		name   string
+		actual map[string]map[string]struct{}
+		want   []string
+	}{
+		{
+			name: "schema matches",
+			actual: map[string]map[string]struct{}{
+				"users":  columns("id", "email", "version", "created_at"),
+				"outbox": columns("id", "locked_until"),
+			},
+		},
+		{
+			name: "column missing",
+			actual: map[string]map[string]struct{}{
+				"users":  columns("id", "email"),
+				"outbox": columns("id"),
+			},
+			want: []string{"outbox.locked_until", "users.version"},
+		},
+		{
+			name: "column renamed",
+			actual: map[string]map[string]struct{}{
+				"users":  columns("id", "mail", "version"),
+				"outbox": columns("id", "locked_until"),
+			},
+			want: []string{"users.email"},
+		},
+		{
+			name: "table missing",
+			actual: map[string]map[string]struct{}{
+				"users": columns("id", "email", "version"),
+			},
+			want: []string{"outbox.id", "outbox.locked_until"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := missingColumns(expected, tt.actual); !slices.Equal(got, tt.want) {
+				t.Errorf("missingColumns() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
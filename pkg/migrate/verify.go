@@ -0,0 +1,70 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrSchemaDrift = errors.New("schema drift")
+
+// VerifyColumns checks that every table/column pair a repository relies on
+// exists in the current schema. Services call it after Up so that a mismatch
+// between migrations and queries fails at startup instead of on first request.
+func VerifyColumns(ctx context.Context, db *pgxpool.Pool, expected map[string][]string) error {
+	const op = "migrate.VerifyColumns"
+
+	tables := make([]string, 0, len(expected))
+	for table := range expected {
+		tables = append(tables, table)
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT table_name, column_name
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = ANY($1)`, tables)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	actual := make(map[string]map[string]struct{})
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if actual[table] == nil {
+			actual[table] = make(map[string]struct{})
+		}
+		actual[table][column] = struct{}{}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if missing := missingColumns(expected, actual); len(missing) > 0 {
+		return fmt.Errorf("%s: %w, missing columns: %s", op, ErrSchemaDrift, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// missingColumns returns the expected table.column pairs absent from
+// actual, sorted.
+func missingColumns(expected map[string][]string, actual map[string]map[string]struct{}) []string {
+	var missing []string
+	for table, columns := range expected {
+		for _, column := range columns {
+			if _, ok := actual[table][column]; !ok {
+				missing = append(missing, table+"."+column)
+			}
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
@@ -0,0 +1,28 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Enqueue stores an event in the outbox table using the caller's transaction,
+// so the event is persisted if and only if the business change commits.
+// The Relay later delivers it to the broker.
+func Enqueue(ctx context.Context, tx pgx.Tx, topic, key string, event any) error {
+	const op = "outbox.Enqueue"
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("%s: marshal event: %w", op, err)
+	}
+
+	query := `INSERT INTO outbox (topic, message_key, payload) VALUES ($1, $2, $3)`
+	if _, err := tx.Exec(ctx, query, topic, key, payload); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-market/pkg/kafka"
+	"github.com/go-market/pkg/logger/sl"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Config struct {
+	PollInterval time.Duration
+	BatchSize    int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	// Lease is how long a claimed batch is reserved for the relay that
+	// claimed it. A relay that dies mid-batch releases it when it runs out.
+	Lease time.Duration
+}
+
+// Relay drains the outbox table into a Publisher. Delivery is at-least-once:
+// a message is marked published only after the publisher acknowledged it, so
+// a crash in between results in a redelivery rather than a lost event.
+//
+// Messages with the same topic and key are published in id order: a message
+// is not claimed while an earlier one for its key is waiting for a retry,
+// and a failure stops the rest of its key in the batch.
+type Relay struct {
+	store store
+	pub   kafka.Publisher
+	log   *slog.Logger
+	cfg   Config
+}
+
+func NewRelay(db *pgxpool.Pool, pub kafka.Publisher, log *slog.Logger, cfg Config) *Relay {
+	return newRelay(&pgStore{db: db}, pub, log, cfg)
+}
+
+func newRelay(store store, pub kafka.Publisher, log *slog.Logger, cfg Config) *Relay {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 5 * time.Minute
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = time.Minute
+	}
+
+	return &Relay{
+		store: store,
+		pub:   pub,
+		log:   log.With(slog.String("component", "outbox.Relay")),
+		cfg:   cfg,
+	}
+}
+
+// Run polls the outbox until ctx is cancelled.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := r.drain(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					r.log.Error("failed to drain outbox", sl.Err(err))
+				}
+				break
+			}
+			// A full batch means there is probably more waiting.
+			if n < r.cfg.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Relay) drain(ctx context.Context) (int, error) {
+	const op = "outbox.Relay.drain"
+
+	batch, err := r.store.claim(ctx, r.cfg.BatchSize, r.cfg.Lease)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	type stream struct{ topic, key string }
+	failed := make(map[stream]bool)
+
+	for _, m := range batch {
+		s := stream{m.topic, m.key}
+		if failed[s] {
+			// Publishing it now would overtake the failed message.
+			if err := r.store.release(ctx, m.id); err != nil {
+				return 0, fmt.Errorf("%s: %w", op, err)
+			}
+			continue
+		}
+
+		if err := r.pub.Publish(ctx, m.topic, []byte(m.key), m.payload); err != nil {
+			failed[s] = true
+
+			delay := r.backoff(m.attempts)
+			r.log.Warn("failed to publish outbox message",
+				slog.Int64("id", m.id),
+				slog.String("topic", m.topic),
+				slog.Int("attempts", m.attempts+1),
+				slog.Duration("retry_in", delay),
+				sl.Err(err),
+			)
+
+			if err := r.store.retry(ctx, m.id, err.Error(), delay); err != nil {
+				return 0, fmt.Errorf("%s: %w", op, err)
+			}
+			continue
+		}
+
+		if err := r.store.markPublished(ctx, m.id); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return len(batch), nil
+}
+
+func (r *Relay) backoff(attempts int) time.Duration {
+	delay := r.cfg.BaseBackoff
+	for i := 0; i < attempts && delay < r.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, r.cfg.MaxBackoff)
+}
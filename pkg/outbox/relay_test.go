@@ -0,0 +1,288 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-market/pkg/kafka"
+)
+
+type row struct {
+	message
+	nextAttemptAt time.Time
+	lockedUntil   time.Time
+	published     bool
+	delays        []time.Duration
+}
+
+// memStore is an outbox table with the claim rules of pgStore and a clock
+// the tests move by hand.
+type memStore struct {
+	mu   sync.Mutex
+	now  time.Time
+	rows []*row
+	// failMark makes markPublished fail, as if the relay died right after
+	// the broker acknowledged.
+	failMark bool
+}
+
+func newMemStore() *memStore {
+	return &memStore{now: time.Unix(1_700_000_000, 0)}
+}
+
+func (s *memStore) enqueue(topic, key, payload string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rows = append(s.rows, &row{
+		message:       message{id: int64(len(s.rows) + 1), topic: topic, key: key, payload: []byte(payload)},
+		nextAttemptAt: s.now,
+	})
+}
+
+func (s *memStore) advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = s.now.Add(d)
+}
+
+func (s *memStore) get(id int64) *row {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rows[id-1]
+}
+
+func (s *memStore) claim(_ context.Context, limit int, lease time.Duration) ([]message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	blocked := func(o *row) bool {
+		for _, e := range s.rows {
+			if e.id < o.id && e.topic == o.topic && e.key == o.key && !e.published &&
+				(e.nextAttemptAt.After(s.now) || !e.lockedUntil.Before(s.now)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var claimable []*row
+	for _, o := range s.rows {
+		if len(claimable) < limit && !o.published && !o.nextAttemptAt.After(s.now) &&
+			o.lockedUntil.Before(s.now) && !blocked(o) {
+			claimable = append(claimable, o)
+		}
+	}
+
+	var batch []message
+	for _, o := range claimable {
+		o.lockedUntil = s.now.Add(lease)
+		batch = append(batch, o.message)
+	}
+	return batch, nil
+}
+
+func (s *memStore) release(_ context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rows[id-1].lockedUntil = time.Time{}
+	return nil
+}
+
+func (s *memStore) retry(_ context.Context, id int64, _ string, delay time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.rows[id-1]
+	r.attempts++
+	r.nextAttemptAt = s.now.Add(delay)
+	r.lockedUntil = time.Time{}
+	r.delays = append(r.delays, delay)
+	return nil
+}
+
+func (s *memStore) markPublished(_ context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failMark {
+		return errors.New("connection reset")
+	}
+	s.rows[id-1].published = true
+	s.rows[id-1].lockedUntil = time.Time{}
+	return nil
+}
+
+// flakyBroker is a MemoryBroker that refuses the payloads in failing.
+type flakyBroker struct {
+	*kafka.MemoryBroker
+	failing map[string]bool
+}
+
+func (b *flakyBroker) Publish(ctx context.Context, topic string, key, value []byte) error {
+	if b.failing[string(value)] {
+		return errors.New("broker unavailable")
+	}
+	return b.MemoryBroker.Publish(ctx, topic, key, value)
+}
+
+func newTestRelay(s store, pub kafka.Publisher) *Relay {
+	return newRelay(s, pub, slog.New(slog.NewTextHandler(io.Discard, nil)), Config{
+		BatchSize:   10,
+		BaseBackoff: time.Second,
+		MaxBackoff:  10 * time.Second,
+		Lease:       time.Minute,
+	})
+}
+
+func payloads(msgs []kafka.Message) []string {
+	var got []string
+	for _, m := range msgs {
+		got = append(got, string(m.Value))
+	}
+	return got
+}
+
+func drain(t *testing.T, r *Relay) {
+	t.Helper()
+	if _, err := r.drain(context.Background()); err != nil {
+		t.Fatalf("drain() error = %v", err)
+	}
+}
+
+func TestRelayRedelivers(t *testing.T) {
+	s := newMemStore()
+	broker := &flakyBroker{MemoryBroker: kafka.NewMemoryBroker(), failing: map[string]bool{"m1": true}}
+	r := newTestRelay(s, broker)
+	s.enqueue("users", "u1", "m1")
+
+	drain(t, r)
+	if got := broker.Messages("users"); len(got) != 0 {
+		t.Fatalf("published %v while the broker was failing", payloads(got))
+	}
+
+	// Not due before its backoff has passed.
+	drain(t, r)
+	if got := s.get(1).attempts; got != 1 {
+		t.Fatalf("attempts = %d, want 1", got)
+	}
+
+	broker.failing = nil
+	s.advance(time.Second)
+	drain(t, r)
+	if got := payloads(broker.Messages("users")); !slices.Equal(got, []string{"m1"}) {
+		t.Fatalf("published %v, want m1 once", got)
+	}
+	if !s.get(1).published {
+		t.Error("message not marked published")
+	}
+}
+
+// A relay that loses track of a message after the broker took it publishes
+// it again once the lease runs out: duplicates are possible, losses are not.
+func TestRelayAtLeastOnce(t *testing.T) {
+	s := newMemStore()
+	broker := kafka.NewMemoryBroker()
+	r := newTestRelay(s, broker)
+	s.enqueue("users", "u1", "m1")
+
+	s.failMark = true
+	if _, err := r.drain(context.Background()); err == nil {
+		t.Fatal("drain() error = nil, want the failed update reported")
+	}
+	s.failMark = false
+
+	drain(t, r)
+	if got := broker.Messages("users"); len(got) != 1 {
+		t.Fatalf("published %d times, want the lease to hold the message", len(got))
+	}
+
+	s.advance(time.Minute + time.Second)
+	drain(t, r)
+	if got := payloads(broker.Messages("users")); !slices.Equal(got, []string{"m1", "m1"}) {
+		t.Errorf("published %v, want m1 redelivered", got)
+	}
+	if !s.get(1).published {
+		t.Error("message not marked published")
+	}
+}
+
+func TestRelayBackoff(t *testing.T) {
+	s := newMemStore()
+	broker := &flakyBroker{MemoryBroker: kafka.NewMemoryBroker(), failing: map[string]bool{"m1": true}}
+	r := newTestRelay(s, broker)
+	s.enqueue("users", "u1", "m1")
+
+	for range 6 {
+		drain(t, r)
+		s.advance(10 * time.Second)
+	}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	if got := s.get(1).delays; !slices.Equal(got, want) {
+		t.Errorf("retry delays = %v, want %v", got, want)
+	}
+}
+
+func TestRelayKeepsKeyOrder(t *testing.T) {
+	s := newMemStore()
+	broker := &flakyBroker{MemoryBroker: kafka.NewMemoryBroker(), failing: map[string]bool{"a1": true}}
+	r := newTestRelay(s, broker)
+	s.enqueue("users", "a", "a1")
+	s.enqueue("users", "a", "a2")
+	s.enqueue("users", "b", "b1")
+
+	// a1 fails: a2 waits behind it while other keys carry on.
+	drain(t, r)
+	if got := payloads(broker.Messages("users")); !slices.Equal(got, []string{"b1"}) {
+		t.Fatalf("published %v, want only b1", got)
+	}
+	if got := s.get(2).attempts; got != 0 {
+		t.Errorf("a2 attempts = %d, want it held back without an attempt", got)
+	}
+
+	// While a1 is waiting for its retry, a2 is not even claimed.
+	drain(t, r)
+	if got := payloads(broker.Messages("users")); !slices.Equal(got, []string{"b1"}) {
+		t.Fatalf("published %v before a1 was due", got)
+	}
+
+	broker.failing = nil
+	s.advance(time.Second)
+	drain(t, r)
+	if got := payloads(broker.Messages("users")); !slices.Equal(got, []string{"b1", "a1", "a2"}) {
+		t.Errorf("published %v, want a1 before a2", got)
+	}
+}
+
+// A message leased by another relay holds back later messages of its key.
+func TestRelaySkipsKeyLeasedElsewhere(t *testing.T) {
+	s := newMemStore()
+	broker := kafka.NewMemoryBroker()
+	r := newTestRelay(s, broker)
+	s.enqueue("users", "a", "a1")
+	s.enqueue("users", "a", "a2")
+	s.enqueue("users", "b", "b1")
+
+	if batch, _ := s.claim(context.Background(), 1, time.Minute); len(batch) != 1 || batch[0].id != 1 {
+		t.Fatalf("other relay claimed %v, want a1", batch)
+	}
+
+	drain(t, r)
+	if got := payloads(broker.Messages("users")); !slices.Equal(got, []string{"b1"}) {
+		t.Fatalf("published %v, want a2 held back while a1 is leased", got)
+	}
+
+	// The other relay died; a1 is claimed again when its lease runs out.
+	s.advance(time.Minute + time.Second)
+	drain(t, r)
+	if got := payloads(broker.Messages("users")); !slices.Equal(got, []string{"b1", "a1", "a2"}) {
+		t.Errorf("published %v, want a1 before a2", got)
+	}
+}
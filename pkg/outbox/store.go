@@ -0,0 +1,113 @@
+package outbox
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type message struct {
+	id       int64
+	topic    string
+	key      string
+	payload  []byte
+	attempts int
+}
+
+// store is the outbox table as the Relay uses it.
+type store interface {
+	// claim leases up to limit due messages in id order. A message is
+	// skipped while an earlier message with the same topic and key is
+	// unpublished and not due yet or leased by someone else, which keeps
+	// each key in order across polls and replicas.
+	claim(ctx context.Context, limit int, lease time.Duration) ([]message, error)
+	// release gives up the lease without counting an attempt.
+	release(ctx context.Context, id int64) error
+	// retry records a failed attempt and makes the message due after delay.
+	retry(ctx context.Context, id int64, reason string, delay time.Duration) error
+	markPublished(ctx context.Context, id int64) error
+}
+
+type pgStore struct {
+	db *pgxpool.Pool
+}
+
+// claimLock serialises claims between replicas. Claims are a single short
+// transaction; publishing happens after it commits.
+const claimLock = "outbox.claim"
+
+func (s *pgStore) claim(ctx context.Context, limit int, lease time.Duration) ([]message, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`, claimLock); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `
+		UPDATE outbox SET locked_until = NOW() + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT o.id FROM outbox o
+			WHERE o.published_at IS NULL AND o.next_attempt_at <= NOW()
+				AND (o.locked_until IS NULL OR o.locked_until < NOW())
+				AND NOT EXISTS (
+					SELECT 1 FROM outbox e
+					WHERE e.topic = o.topic AND e.message_key = o.message_key
+						AND e.published_at IS NULL AND e.id < o.id
+						AND (e.next_attempt_at > NOW() OR e.locked_until >= NOW())
+				)
+			ORDER BY o.id
+			LIMIT $1
+		)
+		RETURNING id, topic, message_key, payload, attempts`, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+
+	var batch []message
+	for rows.Next() {
+		var m message
+		if err := rows.Scan(&m.id, &m.topic, &m.key, &m.payload, &m.attempts); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		batch = append(batch, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	// RETURNING does not keep the order of the subquery.
+	slices.SortFunc(batch, func(a, b message) int { return cmp.Compare(a.id, b.id) })
+	return batch, nil
+}
+
+func (s *pgStore) release(ctx context.Context, id int64) error {
+	_, err := s.db.Exec(ctx, `UPDATE outbox SET locked_until = NULL WHERE id = $1`, id)
+	return err
+}
+
+func (s *pgStore) retry(ctx context.Context, id int64, reason string, delay time.Duration) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE outbox
+		SET attempts = attempts + 1, last_error = $2, next_attempt_at = NOW() + make_interval(secs => $3),
+			locked_until = NULL
+		WHERE id = $1`, id, reason, delay.Seconds())
+	return err
+}
+
+func (s *pgStore) markPublished(ctx context.Context, id int64) error {
+	_, err := s.db.Exec(ctx, `UPDATE outbox SET published_at = NOW(), locked_until = NULL WHERE id = $1`, id)
+	return err
+}
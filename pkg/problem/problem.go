@@ -0,0 +1,79 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-market/pkg/errs"
+	"github.com/go-market/pkg/validation"
+)
+
+const ContentType = "application/problem+json"
+
+// Details is an RFC 7807 problem document. Code, TraceID and Errors are
+// extension members.
+type Details struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	Code     string            `json:"code"`
+	TraceID  string            `json:"trace_id,omitempty"`
+	Errors   validation.Errors `json:"errors,omitempty"`
+}
+
+// Write renders err as problem details. Only errors that carry an
+// *errs.Error, or validation.Errors, reach the client as they are; anything
+// else becomes a generic 500 so internals never leak.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	d := New(r, err)
+
+	if after, ok := errs.RetryAfter(err); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(after.Seconds()))))
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(d.Status)
+	_ = json.NewEncoder(w).Encode(d)
+}
+
+// New builds the problem document Write would send for err.
+func New(r *http.Request, err error) Details {
+	d := Details{
+		Type:     "about:blank",
+		Instance: r.URL.Path,
+		TraceID:  TraceID(r),
+	}
+
+	var fields validation.Errors
+	if errors.As(err, &fields) {
+		d.Status = http.StatusUnprocessableEntity
+		d.Code = "validation_failed"
+		d.Detail = "request validation failed"
+		d.Errors = fields
+	} else {
+		e, ok := errs.Public(err)
+		if !ok {
+			e = errs.ErrInternal
+		}
+		d.Status, d.Code, d.Detail = e.Status, e.Code, e.Message
+	}
+	d.Title = http.StatusText(d.Status)
+
+	return d
+}
+
+// TraceID returns the W3C trace id from a traceparent header when the
+// caller sent one, otherwise the request id assigned by chi's RequestID
+// middleware.
+func TraceID(r *http.Request) string {
+	if parts := strings.Split(r.Header.Get("traceparent"), "-"); len(parts) == 4 && len(parts[1]) == 32 {
+		return parts[1]
+	}
+	return middleware.GetReqID(r.Context())
+}
@@ -0,0 +1,377 @@
+package saga
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-market/pkg/logger/sl"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Config struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// Lease is how long a claimed saga stays reserved for one process. It is
+	// renewed after every step, so it only has to outlast a single step.
+	Lease       time.Duration
+	StepTimeout time.Duration
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// MaxAttempts bounds retries of one step before the saga gives up on it:
+	// a failing action starts compensation, a failing compensation marks
+	// the saga failed.
+	MaxAttempts int
+}
+
+// querier is the part of *pgxpool.Pool the orchestrator uses.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Orchestrator drives sagas of one Definition. Several replicas may run the
+// same Orchestrator; a lease makes sure each saga is advanced by one process
+// at a time.
+type Orchestrator[T any] struct {
+	db    querier
+	def   Definition[T]
+	log   *slog.Logger
+	cfg   Config
+	owner string
+	wake  chan struct{}
+}
+
+func New[T any](db *pgxpool.Pool, def Definition[T], log *slog.Logger, cfg Config) *Orchestrator[T] {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 10
+	}
+	if cfg.StepTimeout <= 0 {
+		cfg.StepTimeout = 30 * time.Second
+	}
+	if cfg.Lease <= cfg.StepTimeout {
+		cfg.Lease = 2 * cfg.StepTimeout
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 5 * time.Minute
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 10
+	}
+
+	owner := make([]byte, 8)
+	_, _ = rand.Read(owner)
+
+	return &Orchestrator[T]{
+		db:    db,
+		def:   def,
+		log:   log.With(slog.String("component", "saga."+def.Name)),
+		cfg:   cfg,
+		owner: hex.EncodeToString(owner),
+		wake:  make(chan struct{}, 1),
+	}
+}
+
+// Start records a new saga with the given id. Starting an id that already
+// exists does nothing and returns the existing saga with created=false, so
+// callers can use a business key (e.g. the order id) to start at most once.
+// The saga itself is executed by Run.
+func (o *Orchestrator[T]) Start(ctx context.Context, id string, data T) (*Instance[T], bool, error) {
+	const op = "saga.Start"
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: marshal data: %w", op, err)
+	}
+
+	tag, err := o.db.Exec(ctx, `INSERT INTO sagas (id, name, status, step, data)
+		VALUES ($1, $2, $3, 0, $4) ON CONFLICT (id) DO NOTHING`,
+		id, o.def.Name, StatusRunning, payload)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	inst, err := o.Get(ctx, id)
+	if err != nil {
+		return nil, false, err
+	}
+
+	created := tag.RowsAffected() == 1
+	if created {
+		select {
+		case o.wake <- struct{}{}:
+		default:
+		}
+	}
+
+	return inst, created, nil
+}
+
+func (o *Orchestrator[T]) Get(ctx context.Context, id string) (*Instance[T], error) {
+	const op = "saga.Get"
+
+	var (
+		inst    Instance[T]
+		step    int
+		payload []byte
+	)
+	err := o.db.QueryRow(ctx, `SELECT id, name, status, step, data, attempts, COALESCE(last_error, ''), created_at, updated_at
+		FROM sagas WHERE id = $1 AND name = $2`, id, o.def.Name).
+		Scan(&inst.ID, &inst.Name, &inst.Status, &step, &payload, &inst.Attempts, &inst.LastError, &inst.CreatedAt, &inst.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := json.Unmarshal(payload, &inst.Data); err != nil {
+		return nil, fmt.Errorf("%s: unmarshal data: %w", op, err)
+	}
+	if step >= 0 && step < len(o.def.Steps) {
+		inst.Step = o.def.Steps[step].Name
+	}
+
+	return &inst, nil
+}
+
+// Run advances due sagas until ctx is cancelled, including those left
+// unfinished by a previous process.
+func (o *Orchestrator[T]) Run(ctx context.Context) {
+	ticker := time.NewTicker(o.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := o.poll(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					o.log.Error("failed to advance sagas", sl.Err(err))
+				}
+				break
+			}
+			if n < o.cfg.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-o.wake:
+		}
+	}
+}
+
+// state is the persisted progress of a saga being advanced.
+type state[T any] struct {
+	id       string
+	status   Status
+	step     int
+	data     T
+	attempts int
+}
+
+func (o *Orchestrator[T]) poll(ctx context.Context) (int, error) {
+	const op = "saga.poll"
+
+	rows, err := o.db.Query(ctx, `UPDATE sagas SET lease_owner = $1, locked_until = NOW() + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id FROM sagas
+			WHERE name = $3 AND status IN ($4, $5) AND next_attempt_at <= NOW()
+				AND (locked_until IS NULL OR locked_until < NOW())
+			ORDER BY next_attempt_at
+			LIMIT $6
+			FOR UPDATE SKIP LOCKED)
+		RETURNING id, status, step, data, attempts`,
+		o.owner, o.cfg.Lease.Seconds(), o.def.Name, StatusRunning, StatusCompensating, o.cfg.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var claimed []state[T]
+	for rows.Next() {
+		var (
+			s       state[T]
+			payload []byte
+		)
+		if err := rows.Scan(&s.id, &s.status, &s.step, &payload, &s.attempts); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+		if err := json.Unmarshal(payload, &s.data); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("%s: unmarshal data of %s: %w", op, s.id, err)
+		}
+		claimed = append(claimed, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i := range claimed {
+		if err := o.advance(ctx, &claimed[i]); err != nil {
+			if ctx.Err() != nil {
+				return 0, ctx.Err()
+			}
+			o.log.Error("failed to advance saga", slog.String("saga_id", claimed[i].id), sl.Err(err))
+		}
+	}
+
+	return len(claimed), nil
+}
+
+// advance runs the saga forward (or backward while compensating) until it
+// finishes or a step has to be retried later.
+func (o *Orchestrator[T]) advance(ctx context.Context, s *state[T]) error {
+	log := o.log.With(slog.String("saga_id", s.id))
+
+	for s.status == StatusRunning {
+		if s.step >= len(o.def.Steps) {
+			s.status = StatusCompleted
+			log.Info("saga completed")
+			return o.save(ctx, s, "", 0)
+		}
+
+		step := o.def.Steps[s.step]
+		err := o.call(ctx, step.Action, &s.data)
+		if err == nil {
+			s.step++
+			s.attempts = 0
+			if err := o.save(ctx, s, "", 0); err != nil {
+				return err
+			}
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		s.attempts++
+		if !IsPermanent(err) && s.attempts < o.cfg.MaxAttempts {
+			delay := o.backoff(s.attempts)
+			log.Warn("saga step failed, retrying",
+				slog.String("step", step.Name), slog.Int("attempts", s.attempts), slog.Duration("retry_in", delay), sl.Err(err))
+			return o.save(ctx, s, err.Error(), delay)
+		}
+
+		// The failed step may have taken effect before the error surfaced,
+		// so compensation starts with the step itself.
+		log.Warn("saga step failed, compensating", slog.String("step", step.Name), sl.Err(err))
+		s.status = StatusCompensating
+		s.attempts = 0
+		if err := o.save(ctx, s, err.Error(), 0); err != nil {
+			return err
+		}
+	}
+
+	for s.status == StatusCompensating {
+		if s.step < 0 {
+			s.status = StatusCompensated
+			log.Info("saga compensated")
+			return o.save(ctx, s, "", 0)
+		}
+		if s.step >= len(o.def.Steps) {
+			s.step = len(o.def.Steps) - 1
+		}
+
+		step := o.def.Steps[s.step]
+		if step.Compensate != nil {
+			if err := o.call(ctx, step.Compensate, &s.data); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+
+				s.attempts++
+				if s.attempts >= o.cfg.MaxAttempts {
+					s.status = StatusFailed
+					log.Error("saga compensation gave up", slog.String("step", step.Name), sl.Err(err))
+					return o.save(ctx, s, err.Error(), 0)
+				}
+
+				delay := o.backoff(s.attempts)
+				log.Warn("saga compensation failed, retrying",
+					slog.String("step", step.Name), slog.Int("attempts", s.attempts), slog.Duration("retry_in", delay), sl.Err(err))
+				return o.save(ctx, s, err.Error(), delay)
+			}
+		}
+
+		s.step--
+		s.attempts = 0
+		if err := o.save(ctx, s, "", 0); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (o *Orchestrator[T]) call(ctx context.Context, fn func(context.Context, *T) error, data *T) error {
+	ctx, cancel := context.WithTimeout(ctx, o.cfg.StepTimeout)
+	defer cancel()
+
+	return fn(ctx, data)
+}
+
+var errLeaseLost = errors.New("saga lease was taken over by another process")
+
+// save persists s and renews the lease; retryIn > 0 also schedules the next
+// attempt and releases the lease so any replica may pick the saga up.
+func (o *Orchestrator[T]) save(ctx context.Context, s *state[T], lastError string, retryIn time.Duration) error {
+	const op = "saga.save"
+
+	payload, err := json.Marshal(s.data)
+	if err != nil {
+		return fmt.Errorf("%s: marshal data: %w", op, err)
+	}
+
+	lease := o.cfg.Lease
+	done := s.status == StatusCompleted || s.status == StatusCompensated || s.status == StatusFailed
+	if retryIn > 0 || done {
+		lease = 0
+	}
+
+	// Persisting uses its own context so that shutting down mid-step still
+	// records the progress already made.
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	tag, err := o.db.Exec(saveCtx, `UPDATE sagas
+		SET status = $3, step = $4, data = $5, attempts = $6, last_error = NULLIF($7, ''),
+			next_attempt_at = NOW() + make_interval(secs => $8::float8),
+			locked_until = CASE WHEN $9::float8 > 0 THEN NOW() + make_interval(secs => $9::float8) END,
+			updated_at = NOW()
+		WHERE id = $1 AND lease_owner = $2`,
+		s.id, o.owner, s.status, s.step, payload, s.attempts, lastError, retryIn.Seconds(), lease.Seconds())
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errLeaseLost
+	}
+
+	return nil
+}
+
+func (o *Orchestrator[T]) backoff(attempts int) time.Duration {
+	delay := o.cfg.BaseBackoff
+	for i := 1; i < attempts && delay < o.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, o.cfg.MaxBackoff)
+}
@@ -0,0 +1,201 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// savedState is what one call to save wrote.
+type savedState struct {
+	status Status
+	step   int
+}
+
+// fakeDB records the saga updates written by save and accepts them all.
+type fakeDB struct {
+	querier
+	saves []savedState
+}
+
+func (f *fakeDB) Exec(_ context.Context, _ string, args ...any) (pgconn.CommandTag, error) {
+	f.saves = append(f.saves, savedState{status: args[2].(Status), step: args[3].(int)})
+	return pgconn.NewCommandTag("UPDATE 1"), nil
+}
+
+type testData struct {
+	Calls []string `json:"calls"`
+}
+
+var (
+	errTransient = errors.New("transient")
+	errBroken    = Permanent(errors.New("broken"))
+)
+
+// step returns a step whose action and compensation record their call and
+// fail with the given errors.
+func step(name string, actionErr, compensateErr error) Step[testData] {
+	return Step[testData]{
+		Name: name,
+		Action: func(_ context.Context, d *testData) error {
+			d.Calls = append(d.Calls, name)
+			return actionErr
+		},
+		Compensate: func(_ context.Context, d *testData) error {
+			d.Calls = append(d.Calls, "undo "+name)
+			return compensateErr
+		},
+	}
+}
+
+func TestAdvance(t *testing.T) {
+	const maxAttempts = 3
+
+	tests := []struct {
+		name      string
+		steps     []Step[testData]
+		start     state[testData]
+		wantCalls []string
+		wantState savedState
+	}{
+		{
+			name:      "every step succeeds",
+			steps:     []Step[testData]{step("a", nil, nil), step("b", nil, nil)},
+			start:     state[testData]{status: StatusRunning},
+			wantCalls: []string{"a", "b"},
+			wantState: savedState{status: StatusCompleted, step: 2},
+		},
+		{
+			name:      "permanent failure compensates the failed step and those before it",
+			steps:     []Step[testData]{step("a", nil, nil), step("b", errBroken, nil), step("c", nil, nil)},
+			start:     state[testData]{status: StatusRunning},
+			wantCalls: []string{"a", "b", "undo b", "undo a"},
+			wantState: savedState{status: StatusCompensated, step: -1},
+		},
+		{
+			name:      "transient failure is retried later",
+			steps:     []Step[testData]{step("a", nil, nil), step("b", errTransient, nil)},
+			start:     state[testData]{status: StatusRunning},
+			wantCalls: []string{"a", "b"},
+			wantState: savedState{status: StatusRunning, step: 1},
+		},
+		{
+			name:      "transient failure on the last attempt compensates",
+			steps:     []Step[testData]{step("a", nil, nil), step("b", errTransient, nil)},
+			start:     state[testData]{status: StatusRunning, step: 1, attempts: maxAttempts - 1},
+			wantCalls: []string{"b", "undo b", "undo a"},
+			wantState: savedState{status: StatusCompensated, step: -1},
+		},
+		{
+			name: "steps without a compensation are skipped",
+			steps: []Step[testData]{
+				step("a", nil, nil),
+				{Name: "b", Action: func(context.Context, *testData) error { return nil }},
+				step("c", errBroken, nil),
+			},
+			start:     state[testData]{status: StatusRunning},
+			wantCalls: []string{"a", "c", "undo c", "undo a"},
+			wantState: savedState{status: StatusCompensated, step: -1},
+		},
+		{
+			name:      "failing compensation is retried later",
+			steps:     []Step[testData]{step("a", nil, errTransient), step("b", errBroken, nil)},
+			start:     state[testData]{status: StatusRunning},
+			wantCalls: []string{"a", "b", "undo b", "undo a"},
+			wantState: savedState{status: StatusCompensating, step: 0},
+		},
+		{
+			name:      "compensation that keeps failing marks the saga failed",
+			steps:     []Step[testData]{step("a", nil, errTransient), step("b", nil, nil)},
+			start:     state[testData]{status: StatusCompensating, step: 0, attempts: maxAttempts - 1},
+			wantCalls: []string{"undo a"},
+			wantState: savedState{status: StatusFailed, step: 0},
+		},
+		{
+			name:      "resumed compensation continues where it stopped",
+			steps:     []Step[testData]{step("a", nil, nil), step("b", nil, nil), step("c", nil, nil)},
+			start:     state[testData]{status: StatusCompensating, step: 1},
+			wantCalls: []string{"undo b", "undo a"},
+			wantState: savedState{status: StatusCompensated, step: -1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeDB{}
+			o := New(nil, Definition[testData]{Name: "test", Steps: tt.steps},
+				slog.New(slog.NewTextHandler(io.Discard, nil)), Config{MaxAttempts: maxAttempts})
+			o.db = db
+
+			s := tt.start
+			if err := o.advance(context.Background(), &s); err != nil {
+				t.Fatalf("advance() error = %v", err)
+			}
+
+			if !slices.Equal(s.data.Calls, tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", s.data.Calls, tt.wantCalls)
+			}
+			if len(db.saves) == 0 {
+				t.Fatal("advance() saved nothing")
+			}
+			if got := db.saves[len(db.saves)-1]; got != tt.wantState {
+				t.Errorf("last saved state = %+v, want %+v", got, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	o := New(nil, Definition[testData]{Name: "test"}, slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Config{BaseBackoff: time.Second, MaxBackoff: 10 * time.Second})
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: time.Second},
+		{attempts: 2, want: 2 * time.Second},
+		{attempts: 4, want: 8 * time.Second},
+		{attempts: 5, want: 10 * time.Second},
+		{attempts: 50, want: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := o.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	base := errors.New("declined")
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: Permanent(nil), want: false},
+		{name: "plain error", err: base, want: false},
+		{name: "permanent", err: Permanent(base), want: true},
+		{name: "wrapped permanent", err: errors.Join(errors.New("step"), Permanent(base)), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsPermanent(tt.err); got != tt.want {
+				t.Errorf("IsPermanent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if !errors.Is(Permanent(base), base) {
+		t.Error("Permanent() hides the original error")
+	}
+}
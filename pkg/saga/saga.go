@@ -0,0 +1,76 @@
+// Package saga runs multi-step workflows that span services without a
+// distributed transaction. Each step has an action and an optional
+// compensation; when a step fails for good, the compensations of the failed
+// step and of every step before it run in reverse order.
+//
+// Saga state lives in the service's `sagas` table and is saved after every
+// step, so an Orchestrator started after a crash picks up where the previous
+// process stopped. That means actions and compensations run at least once:
+// they must be idempotent, and a compensation must tolerate an action that
+// never took effect.
+package saga
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusRunning      Status = "running"
+	StatusCompensating Status = "compensating"
+	StatusCompleted    Status = "completed"
+	StatusCompensated  Status = "compensated"
+	// StatusFailed means a compensation kept failing and the saga needs a
+	// human to look at it.
+	StatusFailed Status = "failed"
+)
+
+// Step is one unit of work. Action and Compensate may update data; the
+// changes are persisted with the saga state.
+type Step[T any] struct {
+	Name       string
+	Action     func(ctx context.Context, data *T) error
+	Compensate func(ctx context.Context, data *T) error
+}
+
+type Definition[T any] struct {
+	Name  string
+	Steps []Step[T]
+}
+
+// Instance is the persisted state of one saga run.
+type Instance[T any] struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Status    Status    `json:"status"`
+	Step      string    `json:"step,omitempty"`
+	Data      T         `json:"data"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+var ErrNotFound = errors.New("saga not found")
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying: the saga starts compensating
+// immediately instead of backing off.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
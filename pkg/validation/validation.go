@@ -0,0 +1,123 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Rules shared by every service, so a username or email accepted by one
+// handler is accepted by all of them.
+const (
+	UsernameRule  = "username"
+	EmailRule     = "email,max=255"
+	AvatarURLRule = "omitempty,http_url,max=2048"
+)
+
+var usernamePattern = regexp.MustCompile(`^[\p{L}\p{N}._-]{3,100}$`)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// Report fields by their JSON names, which is what clients sent.
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+
+	if err := v.RegisterValidation(UsernameRule, func(fl validator.FieldLevel) bool {
+		return usernamePattern.MatchString(fl.Field().String())
+	}); err != nil {
+		panic(err)
+	}
+
+	return v
+}
+
+// FieldError describes one failed rule on one request field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Errors is returned by Struct when at least one rule failed.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Field+" "+fe.Message)
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Struct evaluates the validate tags on v. It returns Errors when the input
+// is invalid and any other error only for programming mistakes, such as
+// passing a non-struct.
+func Struct(v any) error {
+	return fieldErrors(validate.Struct(v))
+}
+
+// Partial is Struct restricted to the named Go fields, for requests such as
+// merge patches where only the members that were sent are checked.
+func Partial(v any, fields ...string) error {
+	return fieldErrors(validate.StructPartial(v, fields...))
+}
+
+func fieldErrors(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+
+	out := make(Errors, 0, len(verrs))
+	for _, fe := range verrs {
+		out = append(out, FieldError{
+			Field:   fe.Field(),
+			Rule:    fe.Tag(),
+			Message: message(fe),
+		})
+	}
+	return out
+}
+
+// Var checks a single value against rule, e.g. Var(email, EmailRule).
+func Var(value any, rule string) bool {
+	return validate.Var(value, rule) == nil
+}
+
+func message(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case UsernameRule:
+		return "must be 3 to 100 letters, digits, '.', '_' or '-'"
+	case "http_url":
+		return "must be an absolute http or https URL"
+	case "min":
+		return fmt.Sprintf("must be at least %s characters", fe.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s characters", fe.Param())
+	default:
+		return fmt.Sprintf("failed the %s rule", fe.Tag())
+	}
+}
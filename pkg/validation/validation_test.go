@@ -0,0 +1,135 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type signUp struct {
+	Username string `json:"username" validate:"required,username"`
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Avatar   string `json:"avatar" validate:"omitempty,http_url,max=2048"`
+	Password string `json:"-" validate:"omitempty,min=8"`
+}
+
+func TestStruct(t *testing.T) {
+	valid := signUp{Username: "alice_1", Email: "alice@example.com"}
+
+	tests := []struct {
+		name       string
+		modify     func(*signUp)
+		wantFields map[string]string
+	}{
+		{name: "valid", modify: func(*signUp) {}},
+		{name: "unicode username", modify: func(s *signUp) { s.Username = "Zoë.K" }},
+		{name: "optional avatar", modify: func(s *signUp) { s.Avatar = "https://cdn.example.com/a.png" }},
+		{
+			name:       "missing fields",
+			modify:     func(s *signUp) { *s = signUp{} },
+			wantFields: map[string]string{"username": "required", "email": "required"},
+		},
+		{
+			name:       "username too short",
+			modify:     func(s *signUp) { s.Username = "al" },
+			wantFields: map[string]string{"username": UsernameRule},
+		},
+		{
+			name:       "username with spaces",
+			modify:     func(s *signUp) { s.Username = "alice smith" },
+			wantFields: map[string]string{"username": UsernameRule},
+		},
+		{
+			name:       "bad email",
+			modify:     func(s *signUp) { s.Email = "alice" },
+			wantFields: map[string]string{"email": "email"},
+		},
+		{
+			name:       "avatar is not http",
+			modify:     func(s *signUp) { s.Avatar = "javascript:alert(1)" },
+			wantFields: map[string]string{"avatar": "http_url"},
+		},
+		{
+			name:       "field without a json name uses the Go name",
+			modify:     func(s *signUp) { s.Password = "short" },
+			wantFields: map[string]string{"Password": "min"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := valid
+			tt.modify(&in)
+
+			err := Struct(in)
+			if tt.wantFields == nil {
+				if err != nil {
+					t.Fatalf("Struct() error = %v", err)
+				}
+				return
+			}
+
+			var verrs Errors
+			if !errors.As(err, &verrs) {
+				t.Fatalf("Struct() error = %v, want Errors", err)
+			}
+			if len(verrs) != len(tt.wantFields) {
+				t.Fatalf("Struct() = %v, want %v", verrs, tt.wantFields)
+			}
+			for _, fe := range verrs {
+				if rule, ok := tt.wantFields[fe.Field]; !ok || rule != fe.Rule {
+					t.Errorf("unexpected %s failing %s", fe.Field, fe.Rule)
+				}
+				if fe.Message == "" || strings.HasPrefix(fe.Message, "failed the") {
+					t.Errorf("%s has no readable message: %q", fe.Field, fe.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestPartial(t *testing.T) {
+	// Username is empty but not checked, since it was not sent.
+	err := Partial(signUp{Email: "alice"}, "Email")
+
+	var verrs Errors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("Partial() error = %v, want Errors", err)
+	}
+	if len(verrs) != 1 || verrs[0].Field != "email" {
+		t.Errorf("Partial() = %v, want only email", verrs)
+	}
+}
+
+func TestStructRejectsNonStruct(t *testing.T) {
+	err := Struct("alice")
+	if err == nil {
+		t.Fatal("Struct() error = nil")
+	}
+
+	var verrs Errors
+	if errors.As(err, &verrs) {
+		t.Errorf("Struct() = %v, want a non-validation error", verrs)
+	}
+}
+
+func TestVar(t *testing.T) {
+	tests := []struct {
+		value string
+		rule  string
+		want  bool
+	}{
+		{value: "alice@example.com", rule: EmailRule, want: true},
+		{value: "alice@", rule: EmailRule, want: false},
+		{value: "bob", rule: UsernameRule, want: true},
+		{value: "b", rule: UsernameRule, want: false},
+		{value: "", rule: AvatarURLRule, want: true},
+		{value: "/relative.png", rule: AvatarURLRule, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := Var(tt.value, tt.rule); got != tt.want {
+			t.Errorf("Var(%q, %q) = %v, want %v", tt.value, tt.rule, got, tt.want)
+		}
+	}
+}
@@ -1,5 +1,89 @@
 package main
 
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/go-market/pkg/logger/handlers/slogpretty"
+	"github.com/go-market/pkg/migrate"
+	"github.com/go-market/services/auth/internal/app"
+	"github.com/go-market/services/auth/internal/config"
+	"github.com/go-market/services/auth/internal/repository/postgres"
+)
+
+const (
+	envLocal = "local"
+	envProd  = "prod"
+	envDev   = "dev"
+)
+
 func main() {
+	cfg := config.MustLoad()
+	log := setupLogger(cfg.Env)
+
+	log.Info("starting auth service", slog.String("env", cfg.Env))
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(*cfg, log, os.Args[2:]); err != nil {
+			log.Error("migrate failed", slog.Any("err", err))
+			os.Exit(1)
+		}
+		return
+	}
+
+	application, err := app.NewApp(*cfg, log)
+	if err != nil {
+		log.Error("failed to initialize app", slog.Any("err", err))
+		os.Exit(1)
+	}
+
+	if err := application.Run(); err != nil {
+		log.Error("app stopped with error", slog.Any("err", err))
+		os.Exit(1)
+	}
+}
+
+// runMigrate handles `<binary> migrate up|down|status`.
+func runMigrate(cfg config.Config, log *slog.Logger, args []string) error {
+	repo, err := postgres.New(cfg.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	migrator, err := migrate.New(repo.Pool(), cfg.MigrationsPath, log)
+	if err != nil {
+		return err
+	}
+
+	return migrate.Command(context.Background(), migrator, args, os.Stdout)
+}
+
+func setupLogger(env string) *slog.Logger {
+	var log *slog.Logger
+
+	switch env {
+	case envProd:
+		log = slog.New(
+			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}),
+		)
+	case envDev:
+		log = slog.New(
+			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		)
+	default:
+		log = setupPrettyLogger()
+	}
+
+	return log
+}
+
+func setupPrettyLogger() *slog.Logger {
+	opts := slogpretty.PrettyHandlerOptions{
+		SlogOpts: &slog.HandlerOptions{Level: slog.LevelDebug},
+	}
+	handler := opts.NewPrettyHandler(os.Stdout)
 
+	return slog.New(handler)
 }
@@ -0,0 +1,211 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-market/pkg/authz"
+	"github.com/go-market/pkg/mailer"
+	"github.com/go-market/pkg/migrate"
+	pkgRedis "github.com/go-market/pkg/redis"
+	"github.com/go-market/services/auth/internal/config"
+	authHTTP "github.com/go-market/services/auth/internal/derivery/http"
+	"github.com/go-market/services/auth/internal/model"
+	"github.com/go-market/services/auth/internal/repository/postgres"
+	authRedis "github.com/go-market/services/auth/internal/repository/redis"
+	"github.com/go-market/services/auth/internal/service"
+	"github.com/redis/go-redis/v9"
+)
+
+type App struct {
+	server *http.Server
+	keys   *service.Keyring
+	mail   *mailer.Queue
+	repo   *postgres.PostgresRepo
+	rdb    *redis.Client
+	log    *slog.Logger
+}
+
+func NewApp(cfg config.Config, log *slog.Logger) (*App, error) {
+	const op = "app.NewApp"
+
+	logger := log.With(slog.String("op", op))
+
+	repo, err := postgres.New(cfg.DatabaseURL)
+	if err != nil {
+		logger.Error("failed to init postgres", slog.String("op", op), slog.Any("err", err))
+		return nil, err
+	}
+
+	if err := migrateSchema(repo, cfg.MigrationsPath, log); err != nil {
+		logger.Error("failed to migrate database", slog.Any("err", err))
+		repo.Close()
+		return nil, err
+	}
+
+	rdb := pkgRedis.NewClient(cfg.RedisAddr)
+	if err := rdb.Ping(context.Background()).Err(); err != nil {
+		logger.Warn("redis is unreachable, revoked access tokens cannot be denied and two-factor sign-in is unavailable", slog.Any("err", err))
+	}
+	denied := authz.NewRedisDenyList(rdb)
+
+	sealer, err := service.NewSealer(cfg.Secrets.Key)
+	if err != nil {
+		logger.Error("failed to init secrets key", slog.Any("err", err))
+		rdb.Close()
+		repo.Close()
+		return nil, err
+	}
+
+	keys, err := newKeyring(repo, sealer, cfg, log)
+	if err != nil {
+		logger.Error("failed to init signing keys", slog.Any("err", err))
+		rdb.Close()
+		repo.Close()
+		return nil, err
+	}
+
+	clients := make([]model.ServiceClient, 0, len(cfg.Clients))
+	for _, c := range cfg.Clients {
+		clients = append(clients, model.ServiceClient{ID: c.ID, Secret: c.Secret, Roles: c.Roles})
+	}
+	svc := service.New(repo, denied, authRedis.NewMFAStore(rdb), keys, sealer, service.Options{
+		Issuer:     cfg.Issuer,
+		Audience:   cfg.Audience,
+		AccessTTL:  cfg.AccessTokenTTL,
+		RefreshTTL: cfg.RefreshTokenTTL,
+		Clients:    clients,
+		MFA: service.MFAOptions{
+			Issuer:        cfg.MFA.Issuer,
+			ChallengeTTL:  cfg.MFA.ChallengeTTL,
+			AttemptLimit:  cfg.MFA.AttemptLimit,
+			AttemptWindow: cfg.MFA.AttemptWindow,
+			RecoveryCodes: cfg.MFA.RecoveryCodes,
+		},
+	})
+
+	mail, err := mailer.New(cfg.Mailer, log)
+	if err != nil {
+		logger.Error("failed to init mailer", slog.Any("err", err))
+		rdb.Close()
+		repo.Close()
+		return nil, err
+	}
+	mailQueue := mailer.NewQueue(mail, log, mailer.QueueConfig{})
+	passwords := service.NewPasswordReset(repo, denied, authRedis.NewRateLimiter(rdb), mailQueue, log, service.PasswordResetOptions{
+		TokenTTL:    cfg.PasswordReset.TokenTTL,
+		LinkURL:     cfg.PasswordReset.LinkURL,
+		EmailLimit:  cfg.PasswordReset.EmailLimit,
+		EmailWindow: cfg.PasswordReset.EmailWindow,
+		IPLimit:     cfg.PasswordReset.IPLimit,
+		IPWindow:    cfg.PasswordReset.IPWindow,
+	})
+
+	authHandler := authHTTP.New(log, svc, passwords, keys)
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(middleware.Logger)
+	r.Use(middleware.Recoverer)
+
+	authHTTP.RegisterWellKnownRoutes(r, authHandler)
+	r.Route("/api/v1", func(r chi.Router) {
+		authHTTP.RegisterAuthRoutes(r, authHandler, authz.NewVerifier(keys, cfg.Issuer, cfg.Audience), denied)
+	})
+
+	server := &http.Server{
+		Addr:         cfg.HTTPAddr.Address,
+		Handler:      r,
+		ReadTimeout:  cfg.HTTPAddr.Timeout,
+		WriteTimeout: cfg.HTTPAddr.Timeout,
+		IdleTimeout:  cfg.HTTPAddr.IdleTimeout,
+	}
+
+	return &App{server: server, keys: keys, mail: mailQueue, repo: repo, rdb: rdb, log: log}, nil
+}
+
+// newKeyring loads the signing keys. A key stays published for the access
+// token TTL after it stops signing, plus the clock skew verifiers allow.
+func newKeyring(repo *postgres.PostgresRepo, sealer *service.Sealer, cfg config.Config, log *slog.Logger) (*service.Keyring, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	return service.NewKeyring(ctx, repo, sealer, log, service.KeyringOptions{
+		Alg:              cfg.Keys.Alg,
+		RotationInterval: cfg.Keys.RotationInterval,
+		Prepublish:       cfg.Keys.Prepublish,
+		Retain:           cfg.AccessTokenTTL + time.Minute,
+		CheckInterval:    cfg.Keys.CheckInterval,
+	})
+}
+
+// migrateSchema applies pending migrations and then verifies that the schema
+// matches what the repository queries expect.
+func migrateSchema(repo *postgres.PostgresRepo, path string, log *slog.Logger) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	migrator, err := migrate.New(repo.Pool(), path, log)
+	if err != nil {
+		return err
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		return err
+	}
+
+	return repo.CheckSchema(ctx)
+}
+
+func (a *App) Run() error {
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+
+	// Rotates signing keys and picks up keys rotated by other replicas.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.keys.Run(bgCtx)
+	}()
+
+	// Delivers password reset mail queued by requests.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.mail.Run(bgCtx)
+	}()
+
+	go func() {
+		if err := a.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			a.log.Error("listen failed", slog.Any("err", err))
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	a.log.Info("shutting down server")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := a.server.Shutdown(ctx)
+
+	stopBackground()
+	wg.Wait()
+
+	if err := a.rdb.Close(); err != nil {
+		a.log.Error("failed to close redis client", slog.Any("err", err))
+	}
+	a.repo.Close()
+
+	return err
+}
@@ -0,0 +1,151 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/render"
+	authErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/pkg/problem"
+	"github.com/go-market/pkg/validation"
+	"github.com/go-market/services/auth/internal/service"
+)
+
+type AuthHandler struct {
+	log       *slog.Logger
+	svc       *service.Service
+	passwords *service.PasswordReset
+	keys      *service.Keyring
+}
+
+func New(log *slog.Logger, svc *service.Service, passwords *service.PasswordReset, keys *service.Keyring) *AuthHandler {
+	return &AuthHandler{
+		log:       log,
+		svc:       svc,
+		passwords: passwords,
+		keys:      keys,
+	}
+}
+
+type RegisterRequest struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+	// bcrypt ignores everything past 72 bytes.
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type SuccessResponse struct {
+	Data    interface{} `json:"data,omitempty"`
+	Message string      `json:"message,omitempty"`
+}
+
+func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
+	const op = "AuthHandler.Register"
+	log := h.log.With(slog.String("op", op))
+
+	var req RegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("failed to decode request", slog.String("error", err.Error()))
+		problem.Write(w, r, authErr.Wrap(authErr.ErrInvalidRequestBody, err))
+		return
+	}
+	if err := validation.Struct(req); err != nil {
+		log.Error("invalid request", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	tokens, err := h.svc.Register(r.Context(), req.Email, req.Password, clientFrom(r))
+	if err != nil {
+		log.Error("failed to register", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, SuccessResponse{Data: tokens})
+}
+
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	const op = "AuthHandler.Login"
+	log := h.log.With(slog.String("op", op))
+
+	var req LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("failed to decode request", slog.String("error", err.Error()))
+		problem.Write(w, r, authErr.Wrap(authErr.ErrInvalidRequestBody, err))
+		return
+	}
+	if err := validation.Struct(req); err != nil {
+		log.Error("invalid request", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	result, err := h.svc.Login(r.Context(), req.Email, req.Password, clientFrom(r))
+	if err != nil {
+		log.Error("failed to login", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	// Users with two-factor authentication get a challenge to answer at
+	// /auth/login/mfa instead of tokens.
+	if result.Challenge != nil {
+		render.JSON(w, r, SuccessResponse{Data: result.Challenge})
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Data: result.Tokens})
+}
+
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	const op = "AuthHandler.Refresh"
+	log := h.log.With(slog.String("op", op))
+
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("failed to decode request", slog.String("error", err.Error()))
+		problem.Write(w, r, authErr.Wrap(authErr.ErrInvalidRequestBody, err))
+		return
+	}
+
+	tokens, err := h.svc.Refresh(r.Context(), req.RefreshToken, clientFrom(r))
+	if err != nil {
+		log.Error("failed to refresh tokens", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Data: tokens})
+}
+
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	const op = "AuthHandler.Logout"
+	log := h.log.With(slog.String("op", op))
+
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("failed to decode request", slog.String("error", err.Error()))
+		problem.Write(w, r, authErr.Wrap(authErr.ErrInvalidRequestBody, err))
+		return
+	}
+
+	err := h.svc.Logout(r.Context(), req.RefreshToken)
+	if err != nil {
+		log.Error("failed to logout", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Message: "logged out successfully"})
+}
@@ -0,0 +1,44 @@
+package http
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-market/pkg/authz"
+)
+
+func RegisterAuthRoutes(r chi.Router, h *AuthHandler, verifier *authz.Verifier, denied authz.DenyList) {
+	r.Route("/auth", func(r chi.Router) {
+		r.Post("/token", h.Token)
+		r.Post("/register", h.Register)
+		r.Post("/login", h.Login)
+		r.Post("/login/mfa", h.LoginMFA)
+		r.Post("/refresh", h.Refresh)
+		r.Post("/logout", h.Logout)
+		r.Post("/password/forgot", h.ForgotPassword)
+		r.Post("/password/reset", h.ResetPassword)
+	})
+
+	// Sessions are refresh-token families, which only this service stores.
+	r.Route("/users/me/sessions", func(r chi.Router) {
+		r.Use(authz.Authenticate(verifier, authz.WithDenyList(denied)))
+
+		r.Get("/", h.ListSessions)
+		r.Delete("/", h.RevokeSessions)
+		r.Delete("/{id}", h.RevokeSession)
+	})
+
+	r.Route("/users/me/mfa", func(r chi.Router) {
+		r.Use(authz.Authenticate(verifier, authz.WithDenyList(denied)))
+
+		r.Get("/", h.MFAStatus)
+		r.Post("/totp", h.EnrollTOTP)
+		r.Post("/totp/confirm", h.ConfirmTOTP)
+		r.Delete("/totp", h.DisableTOTP)
+		r.Post("/recovery-codes", h.RegenerateRecoveryCodes)
+	})
+}
+
+// RegisterWellKnownRoutes publishes the verification keys. It is mounted at
+// the server root, where verifiers expect to find them.
+func RegisterWellKnownRoutes(r chi.Router, h *AuthHandler) {
+	r.Get("/.well-known/jwks.json", h.JWKS)
+}
@@ -0,0 +1,214 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/go-market/pkg/authz"
+	authErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/pkg/problem"
+	"github.com/go-market/pkg/validation"
+)
+
+type LoginMFARequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	// Code is a code from the authenticator app or a recovery code.
+	Code string `json:"code" validate:"required,max=64"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required,max=64"`
+}
+
+// DisableTOTPRequest may leave Code empty when the factor was never
+// confirmed.
+type DisableTOTPRequest struct {
+	Code string `json:"code" validate:"omitempty,max=64"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// LoginMFA serves POST /auth/login/mfa, the second step of signing in for
+// users with two-factor authentication.
+func (h *AuthHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	const op = "AuthHandler.LoginMFA"
+	log := h.log.With(slog.String("op", op))
+
+	var req LoginMFARequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("failed to decode request", slog.String("error", err.Error()))
+		problem.Write(w, r, authErr.Wrap(authErr.ErrInvalidRequestBody, err))
+		return
+	}
+	if err := validation.Struct(req); err != nil {
+		log.Error("invalid request", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	tokens, err := h.svc.LoginMFA(r.Context(), req.MFAToken, req.Code, clientFrom(r))
+	if err != nil {
+		log.Error("failed to verify second factor", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Data: tokens})
+}
+
+// MFAStatus serves GET /users/me/mfa.
+func (h *AuthHandler) MFAStatus(w http.ResponseWriter, r *http.Request) {
+	const op = "AuthHandler.MFAStatus"
+	log := h.log.With(slog.String("op", op))
+
+	principal, ok := authz.FromContext(r.Context())
+	if !ok {
+		log.Error("failed to extract user id from context")
+		problem.Write(w, r, authErr.ErrUnauthenticated)
+		return
+	}
+
+	status, err := h.svc.MFAStatus(r.Context(), principal.UserID)
+	if err != nil {
+		log.Error("failed to get mfa status", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Data: status})
+}
+
+// EnrollTOTP serves POST /users/me/mfa/totp. The response carries the
+// otpauth URI and a QR code PNG (base64 in JSON) for the authenticator app.
+func (h *AuthHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	const op = "AuthHandler.EnrollTOTP"
+	log := h.log.With(slog.String("op", op))
+
+	principal, ok := authz.FromContext(r.Context())
+	if !ok {
+		log.Error("failed to extract user id from context")
+		problem.Write(w, r, authErr.ErrUnauthenticated)
+		return
+	}
+
+	enrollment, err := h.svc.EnrollTOTP(r.Context(), principal.UserID)
+	if err != nil {
+		log.Error("failed to enroll totp", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, SuccessResponse{Data: enrollment})
+}
+
+// ConfirmTOTP serves POST /users/me/mfa/totp/confirm and answers with the
+// recovery codes, which are never shown again.
+func (h *AuthHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	const op = "AuthHandler.ConfirmTOTP"
+	log := h.log.With(slog.String("op", op))
+
+	principal, ok := authz.FromContext(r.Context())
+	if !ok {
+		log.Error("failed to extract user id from context")
+		problem.Write(w, r, authErr.ErrUnauthenticated)
+		return
+	}
+
+	var req MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("failed to decode request", slog.String("error", err.Error()))
+		problem.Write(w, r, authErr.Wrap(authErr.ErrInvalidRequestBody, err))
+		return
+	}
+	if err := validation.Struct(req); err != nil {
+		log.Error("invalid request", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	codes, err := h.svc.ConfirmTOTP(r.Context(), principal.UserID, req.Code)
+	if err != nil {
+		log.Error("failed to confirm totp", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	render.JSON(w, r, SuccessResponse{Data: RecoveryCodesResponse{RecoveryCodes: codes}})
+}
+
+// DisableTOTP serves DELETE /users/me/mfa/totp. The body carries a current
+// code or a recovery code, unless the factor is still pending.
+func (h *AuthHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	const op = "AuthHandler.DisableTOTP"
+	log := h.log.With(slog.String("op", op))
+
+	principal, ok := authz.FromContext(r.Context())
+	if !ok {
+		log.Error("failed to extract user id from context")
+		problem.Write(w, r, authErr.ErrUnauthenticated)
+		return
+	}
+
+	var req DisableTOTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("failed to decode request", slog.String("error", err.Error()))
+		problem.Write(w, r, authErr.Wrap(authErr.ErrInvalidRequestBody, err))
+		return
+	}
+	if err := validation.Struct(req); err != nil {
+		log.Error("invalid request", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	if err := h.svc.DisableTOTP(r.Context(), principal.UserID, req.Code); err != nil {
+		log.Error("failed to disable totp", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Message: "two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes serves POST /users/me/mfa/recovery-codes. The
+// previous codes stop working.
+func (h *AuthHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	const op = "AuthHandler.RegenerateRecoveryCodes"
+	log := h.log.With(slog.String("op", op))
+
+	principal, ok := authz.FromContext(r.Context())
+	if !ok {
+		log.Error("failed to extract user id from context")
+		problem.Write(w, r, authErr.ErrUnauthenticated)
+		return
+	}
+
+	var req MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("failed to decode request", slog.String("error", err.Error()))
+		problem.Write(w, r, authErr.Wrap(authErr.ErrInvalidRequestBody, err))
+		return
+	}
+	if err := validation.Struct(req); err != nil {
+		log.Error("invalid request", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	codes, err := h.svc.RegenerateRecoveryCodes(r.Context(), principal.UserID, req.Code)
+	if err != nil {
+		log.Error("failed to regenerate recovery codes", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	render.JSON(w, r, SuccessResponse{Data: RecoveryCodesResponse{RecoveryCodes: codes}})
+}
@@ -0,0 +1,81 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/render"
+	authErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/pkg/problem"
+	"github.com/go-market/pkg/validation"
+)
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required"`
+}
+
+type ResetPasswordRequest struct {
+	Token string `json:"token" validate:"required"`
+	// bcrypt ignores everything past 72 bytes.
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+// ForgotPassword answers 202 whether or not the email belongs to an
+// account, and also when starting the reset failed: an error only some
+// addresses can cause would give them away. A client over its request limit
+// gets 429.
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	const op = "AuthHandler.ForgotPassword"
+	log := h.log.With(slog.String("op", op))
+
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("failed to decode request", slog.String("error", err.Error()))
+		problem.Write(w, r, authErr.Wrap(authErr.ErrInvalidRequestBody, err))
+		return
+	}
+	if err := validation.Struct(req); err != nil {
+		log.Error("invalid request", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	err := h.passwords.Forgot(r.Context(), req.Email, clientFrom(r).IP)
+	if _, throttled := authErr.RetryAfter(err); throttled {
+		problem.Write(w, r, err)
+		return
+	}
+	if err != nil {
+		log.Error("failed to start password reset", slog.String("error", err.Error()))
+	}
+
+	render.Status(r, http.StatusAccepted)
+	render.JSON(w, r, SuccessResponse{Message: "if the email belongs to an account, a reset link has been sent"})
+}
+
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	const op = "AuthHandler.ResetPassword"
+	log := h.log.With(slog.String("op", op))
+
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("failed to decode request", slog.String("error", err.Error()))
+		problem.Write(w, r, authErr.Wrap(authErr.ErrInvalidRequestBody, err))
+		return
+	}
+	if err := validation.Struct(req); err != nil {
+		log.Error("invalid request", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	err := h.passwords.Reset(r.Context(), req.Token, req.Password)
+	if err != nil {
+		log.Error("failed to reset password", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Message: "password has been reset, sign in again"})
+}
@@ -0,0 +1,126 @@
+package http
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/go-market/pkg/authz"
+	authErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/pkg/problem"
+	"github.com/go-market/services/auth/internal/model"
+)
+
+// maxUserAgentLength keeps a hostile client from storing arbitrary amounts
+// of text with every session.
+const maxUserAgentLength = 512
+
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	Current    bool      `json:"current"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// ListSessions serves GET /users/me/sessions. The session of the access
+// token making the request is flagged as current.
+func (h *AuthHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	const op = "AuthHandler.ListSessions"
+	log := h.log.With(slog.String("op", op))
+
+	principal, ok := authz.FromContext(r.Context())
+	if !ok {
+		log.Error("failed to extract user id from context")
+		problem.Write(w, r, authErr.ErrUnauthenticated)
+		return
+	}
+
+	sessions, err := h.svc.Sessions(r.Context(), principal.UserID)
+	if err != nil {
+		log.Error("failed to list sessions", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	response := make([]SessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		response = append(response, SessionResponse{
+			ID:         s.ID,
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			Current:    s.ID == principal.SessionID,
+			CreatedAt:  s.CreatedAt,
+			LastUsedAt: s.LastUsedAt,
+			ExpiresAt:  s.ExpiresAt,
+		})
+	}
+
+	render.JSON(w, r, SuccessResponse{Data: response})
+}
+
+// RevokeSession serves DELETE /users/me/sessions/{id}.
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	const op = "AuthHandler.RevokeSession"
+	log := h.log.With(slog.String("op", op))
+
+	principal, ok := authz.FromContext(r.Context())
+	if !ok {
+		log.Error("failed to extract user id from context")
+		problem.Write(w, r, authErr.ErrUnauthenticated)
+		return
+	}
+
+	err := h.svc.RevokeSession(r.Context(), principal.UserID, chi.URLParam(r, "id"))
+	if err != nil {
+		log.Error("failed to revoke session", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Message: "session revoked"})
+}
+
+// RevokeSessions serves DELETE /users/me/sessions, signing the user out
+// everywhere, including the session making the request.
+func (h *AuthHandler) RevokeSessions(w http.ResponseWriter, r *http.Request) {
+	const op = "AuthHandler.RevokeSessions"
+	log := h.log.With(slog.String("op", op))
+
+	principal, ok := authz.FromContext(r.Context())
+	if !ok {
+		log.Error("failed to extract user id from context")
+		problem.Write(w, r, authErr.ErrUnauthenticated)
+		return
+	}
+
+	if err := h.svc.RevokeSessions(r.Context(), principal.UserID); err != nil {
+		log.Error("failed to revoke sessions", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Message: "all sessions revoked"})
+}
+
+// clientFrom describes the caller's device for the session list. The IP is
+// the direct peer: the service does not sit behind a trusted proxy, so
+// forwarding headers are not believed.
+func clientFrom(r *http.Request) model.Client {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	ua := r.UserAgent()
+	if len(ua) > maxUserAgentLength {
+		ua = ua[:maxUserAgentLength]
+	}
+
+	return model.Client{UserAgent: ua, IP: ip}
+}
@@ -0,0 +1,99 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// jwksMaxAge lets verifiers cache the key set. Keys are published well
+// before they sign (see KeyringOptions.Prepublish), so caching cannot make
+// a verifier miss a new key.
+const jwksMaxAge = 300
+
+// oauthError is the error body defined by RFC 6749, section 5.2.
+type oauthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// JWKS serves GET /.well-known/jwks.json, the public keys that verify
+// access tokens.
+func (h *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	const op = "AuthHandler.JWKS"
+	log := h.log.With(slog.String("op", op))
+
+	set, err := h.keys.JWKS()
+	if err != nil {
+		log.Error("failed to build jwks", slog.String("error", err.Error()))
+		http.Error(w, "failed to build jwks", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(jwksMaxAge))
+	_ = json.NewEncoder(w).Encode(set)
+}
+
+// Token serves POST /auth/token, the OAuth 2.0 token endpoint. Only the
+// client credentials grant is supported; it is how services obtain tokens
+// to call each other. Clients authenticate with HTTP Basic or with
+// client_id and client_secret form fields.
+func (h *AuthHandler) Token(w http.ResponseWriter, r *http.Request) {
+	const op = "AuthHandler.Token"
+	log := h.log.With(slog.String("op", op))
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, oauthError{Error: "invalid_request"})
+		return
+	}
+	if grant := r.PostForm.Get("grant_type"); grant != "client_credentials" {
+		writeOAuthError(w, http.StatusBadRequest, oauthError{Error: "unsupported_grant_type"})
+		return
+	}
+
+	clientID, clientSecret, ok := basicClientAuth(r)
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	token, err := h.svc.ClientToken(clientID, clientSecret)
+	if err != nil {
+		log.Error("failed to issue client token", slog.String("client_id", clientID), slog.String("error", err.Error()))
+		w.Header().Set("WWW-Authenticate", `Basic realm="auth"`)
+		writeOAuthError(w, http.StatusUnauthorized, oauthError{Error: "invalid_client"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(token)
+}
+
+// basicClientAuth reads HTTP Basic credentials, which RFC 6749 requires to
+// be form-encoded before being put in the header.
+func basicClientAuth(r *http.Request) (string, string, bool) {
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		return "", "", false
+	}
+
+	id, err := url.QueryUnescape(id)
+	if err != nil {
+		return "", "", false
+	}
+	secret, err = url.QueryUnescape(secret)
+	if err != nil {
+		return "", "", false
+	}
+	return id, secret, true
+}
+
+func writeOAuthError(w http.ResponseWriter, status int, e oauthError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(e)
+}
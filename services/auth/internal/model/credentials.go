@@ -0,0 +1,132 @@
+package model
+
+import "time"
+
+type Credentials struct {
+	ID           string    `json:"id"`
+	Email        string    `json:"email"`
+	PasswordHash string    `json:"-"`
+	Roles        []string  `json:"roles"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// RefreshToken belongs to the session it was rotated within. SessionID is
+// empty for tokens issued before sessions existed.
+type RefreshToken struct {
+	ID        string
+	UserID    string
+	SessionID string
+	TokenHash string
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// Session is a refresh-token family: it starts at login and lives on
+// through every rotation. AccessJTI is the id of the newest access token
+// issued for it, which is denied when the session is revoked.
+type Session struct {
+	ID              string     `json:"id"`
+	UserID          string     `json:"-"`
+	UserAgent       string     `json:"user_agent"`
+	IP              string     `json:"ip"`
+	AccessJTI       string     `json:"-"`
+	AccessExpiresAt time.Time  `json:"-"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	RevokedAt       *time.Time `json:"-"`
+	CreatedAt       time.Time  `json:"created_at"`
+	LastUsedAt      time.Time  `json:"last_used_at"`
+	// AMR lists the authentication methods used to start the session.
+	AMR []string `json:"amr"`
+}
+
+// Client describes the device a request came from.
+type Client struct {
+	UserAgent string
+	IP        string
+}
+
+// PasswordResetToken is stored hashed, like refresh tokens. UsedAt is set
+// once the token has been redeemed or superseded by a newer one.
+type PasswordResetToken struct {
+	ID        string
+	UserID    string
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// SigningKey is a key pair that signs access tokens. PrivateKey holds the
+// PKCS #8 PEM encoding, sealed with the service's secrets key.
+type SigningKey struct {
+	Kid         string
+	Alg         string
+	PrivateKey  string
+	CreatedAt   time.Time
+	ActivatesAt time.Time
+	ExpiresAt   time.Time
+}
+
+// ServiceClient is a service allowed to obtain tokens with the client
+// credentials grant.
+type ServiceClient struct {
+	ID     string
+	Secret string
+	Roles  []string
+}
+
+// ClientToken is the response to a client credentials grant.
+type ClientToken struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// TOTPFactor is a user's authenticator app. It is only required at sign-in
+// once ConfirmedAt is set.
+type TOTPFactor struct {
+	UserID       string
+	Secret       string
+	LastUsedStep int64
+	ConfirmedAt  *time.Time
+	CreatedAt    time.Time
+}
+
+// TOTPEnrollment is what an authenticator app needs to add the factor:
+// either the otpauth URI or its QR code as a PNG image.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+	QRCode []byte `json:"qr_code_png"`
+}
+
+// MFAChallenge is handed out instead of tokens when the password was right
+// but a second factor is still needed. Token is exchanged, together with a
+// code, for a token pair.
+type MFAChallenge struct {
+	Required  bool      `json:"mfa_required"`
+	Token     string    `json:"mfa_token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// LoginResult holds either Tokens or, for users with a second factor, the
+// Challenge to answer.
+type LoginResult struct {
+	Tokens    *TokenPair
+	Challenge *MFAChallenge
+}
+
+type MFAStatus struct {
+	TOTPEnabled       bool `json:"totp_enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+type TokenPair struct {
+	AccessToken      string    `json:"access_token"`
+	RefreshToken     string    `json:"refresh_token"`
+	TokenType        string    `json:"token_type"`
+	AccessExpiresAt  time.Time `json:"access_expires_at"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
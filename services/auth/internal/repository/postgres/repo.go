@@ -0,0 +1,681 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	authErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/pkg/migrate"
+	"github.com/go-market/services/auth/internal/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const uniqueViolation = "23505"
+
+// schema lists every column the queries below rely on.
+var schema = map[string][]string{
+	"credentials":           {"id", "email", "password_hash", "roles", "created_at", "updated_at"},
+	"refresh_tokens":        {"id", "user_id", "session_id", "token_hash", "expires_at", "revoked_at", "created_at"},
+	"signing_keys":          {"kid", "alg", "private_key", "created_at", "activates_at", "expires_at"},
+	"sessions":              {"id", "user_id", "user_agent", "ip", "access_jti", "access_expires_at", "expires_at", "revoked_at", "created_at", "last_used_at", "amr"},
+	"password_reset_tokens": {"id", "user_id", "token_hash", "expires_at", "used_at", "created_at"},
+	"totp_factors":          {"user_id", "secret", "last_used_step", "confirmed_at", "created_at"},
+	"recovery_codes":        {"id", "user_id", "code_hash", "used_at", "created_at"},
+}
+
+type PostgresRepo struct {
+	db *pgxpool.Pool
+}
+
+func New(db_url string) (*PostgresRepo, error) {
+	ctx := context.Background()
+	db, err := pgxpool.New(ctx, db_url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect database %w", err)
+	}
+
+	return &PostgresRepo{db: db}, nil
+}
+
+// Pool exposes the connection pool for components that share the database,
+// such as the migrator.
+func (r *PostgresRepo) Pool() *pgxpool.Pool {
+	return r.db
+}
+
+func (r *PostgresRepo) CreateCredentials(ctx context.Context, creds model.Credentials) (*model.Credentials, error) {
+	const op = "repo.CreateCredentials"
+
+	c := &model.Credentials{}
+	query := `INSERT INTO credentials (email, password_hash, roles)
+		VALUES ($1, $2, $3)
+		RETURNING id, email, password_hash, roles, created_at, updated_at`
+	err := r.db.QueryRow(ctx, query, creds.Email, creds.PasswordHash, creds.Roles).
+		Scan(&c.ID, &c.Email, &c.PasswordHash, &c.Roles, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return nil, authErr.ErrUserExists
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return c, nil
+}
+
+func (r *PostgresRepo) GetByEmail(ctx context.Context, email string) (*model.Credentials, error) {
+	const op = "repo.GetByEmail"
+
+	c := &model.Credentials{}
+	query := `SELECT id, email, password_hash, roles, created_at, updated_at FROM credentials WHERE email = $1`
+	err := r.db.QueryRow(ctx, query, email).
+		Scan(&c.ID, &c.Email, &c.PasswordHash, &c.Roles, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, authErr.ErrUserNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return c, nil
+}
+
+func (r *PostgresRepo) GetByID(ctx context.Context, id string) (*model.Credentials, error) {
+	const op = "repo.GetByID"
+
+	c := &model.Credentials{}
+	query := `SELECT id, email, password_hash, roles, created_at, updated_at FROM credentials WHERE id = $1`
+	err := r.db.QueryRow(ctx, query, id).
+		Scan(&c.ID, &c.Email, &c.PasswordHash, &c.Roles, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, authErr.ErrUserNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return c, nil
+}
+
+func (r *PostgresRepo) GetRefreshToken(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	const op = "repo.GetRefreshToken"
+
+	t := &model.RefreshToken{}
+	query := `SELECT id, user_id, COALESCE(session_id::text, ''), token_hash, expires_at, revoked_at, created_at
+		FROM refresh_tokens WHERE token_hash = $1`
+	err := r.db.QueryRow(ctx, query, tokenHash).
+		Scan(&t.ID, &t.UserID, &t.SessionID, &t.TokenHash, &t.ExpiresAt, &t.RevokedAt, &t.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, authErr.ErrInvalidToken
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return t, nil
+}
+
+// CreateSession starts a session together with its first refresh token.
+func (r *PostgresRepo) CreateSession(ctx context.Context, session model.Session, token model.RefreshToken) error {
+	const op = "repo.CreateSession"
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `INSERT INTO sessions (id, user_id, user_agent, ip, access_jti, access_expires_at, expires_at, amr)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err = tx.Exec(ctx, query, session.ID, session.UserID, session.UserAgent, session.IP,
+		session.AccessJTI, session.AccessExpiresAt, session.ExpiresAt, session.AMR)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query = `INSERT INTO refresh_tokens (user_id, session_id, token_hash, expires_at) VALUES ($1, $2, $3, $4)`
+	if _, err := tx.Exec(ctx, query, token.UserID, session.ID, token.TokenHash, token.ExpiresAt); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RotateRefreshToken marks usedID as used, issues next in the same session
+// and records the new access token and client on the session. The used
+// token is claimed with a conditional update, so two concurrent refreshes
+// with the same token cannot both win, and a revoked session cannot be
+// refreshed.
+func (r *PostgresRepo) RotateRefreshToken(ctx context.Context, usedID string, session model.Session, next model.RefreshToken) error {
+	const op = "repo.RotateRefreshToken"
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `UPDATE refresh_tokens SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`
+	result, err := tx.Exec(ctx, query, usedID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if result.RowsAffected() == 0 {
+		return authErr.ErrInvalidToken
+	}
+
+	query = `UPDATE sessions
+		SET user_agent = $2, ip = $3, access_jti = $4, access_expires_at = $5, expires_at = $6, last_used_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL`
+	result, err = tx.Exec(ctx, query, session.ID, session.UserAgent, session.IP,
+		session.AccessJTI, session.AccessExpiresAt, session.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if result.RowsAffected() == 0 {
+		return authErr.ErrInvalidToken
+	}
+
+	query = `INSERT INTO refresh_tokens (user_id, session_id, token_hash, expires_at) VALUES ($1, $2, $3, $4)`
+	if _, err := tx.Exec(ctx, query, next.UserID, session.ID, next.TokenHash, next.ExpiresAt); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *PostgresRepo) GetSession(ctx context.Context, id string) (*model.Session, error) {
+	const op = "repo.GetSession"
+
+	session := &model.Session{}
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE id = $1`
+	if err := r.db.QueryRow(ctx, query, id).Scan(sessionFields(session)...); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, authErr.ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return session, nil
+}
+
+// ListSessions returns the user's sessions that can still be refreshed,
+// most recently used first.
+func (r *PostgresRepo) ListSessions(ctx context.Context, userID string) ([]model.Session, error) {
+	const op = "repo.ListSessions"
+
+	query := `SELECT ` + sessionColumns + ` FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_used_at DESC`
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sessions, err := scanSessions(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession revokes one session of the user and every refresh token in
+// it. A session that is unknown, someone else's or already revoked is
+// reported as ErrSessionNotFound.
+func (r *PostgresRepo) RevokeSession(ctx context.Context, userID, id string) (*model.Session, error) {
+	const op = "repo.RevokeSession"
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `UPDATE sessions SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+		RETURNING ` + sessionColumns
+	session := &model.Session{}
+	err = tx.QueryRow(ctx, query, id, userID).Scan(sessionFields(session)...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, authErr.ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	query = `UPDATE refresh_tokens SET revoked_at = NOW() WHERE session_id = $1 AND revoked_at IS NULL`
+	if _, err := tx.Exec(ctx, query, id); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return session, nil
+}
+
+// RevokeSessions revokes every session of the user and returns the ones
+// that were still active.
+func (r *PostgresRepo) RevokeSessions(ctx context.Context, userID string) ([]model.Session, error) {
+	const op = "repo.RevokeSessions"
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	sessions, err := revokeSessions(ctx, tx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sessions, nil
+}
+
+func revokeSessions(ctx context.Context, tx pgx.Tx, userID string) ([]model.Session, error) {
+	query := `UPDATE sessions SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+		RETURNING ` + sessionColumns
+	rows, err := tx.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := scanSessions(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	query = `UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`
+	if _, err := tx.Exec(ctx, query, userID); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+const sessionColumns = `id, user_id, user_agent, ip, access_jti, access_expires_at, expires_at, revoked_at, created_at, last_used_at, amr`
+
+// sessionFields returns scan targets in sessionColumns order.
+func sessionFields(s *model.Session) []any {
+	return []any{&s.ID, &s.UserID, &s.UserAgent, &s.IP, &s.AccessJTI, &s.AccessExpiresAt,
+		&s.ExpiresAt, &s.RevokedAt, &s.CreatedAt, &s.LastUsedAt, &s.AMR}
+}
+
+func scanSessions(rows pgx.Rows) ([]model.Session, error) {
+	defer rows.Close()
+
+	sessions := make([]model.Session, 0)
+	for rows.Next() {
+		var s model.Session
+		if err := rows.Scan(sessionFields(&s)...); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// SavePasswordResetToken stores a new reset token and retires any the user
+// still had outstanding, so only the most recent email works.
+func (r *PostgresRepo) SavePasswordResetToken(ctx context.Context, token model.PasswordResetToken) error {
+	const op = "repo.SavePasswordResetToken"
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `UPDATE password_reset_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL`
+	if _, err := tx.Exec(ctx, query, token.UserID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query = `INSERT INTO password_reset_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`
+	if _, err := tx.Exec(ctx, query, token.UserID, token.TokenHash, token.ExpiresAt); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ResetPassword redeems the reset token, sets the new password hash and
+// revokes every session of the user, all in one transaction. The revoked
+// sessions are returned so their access tokens can be denied. The token
+// is claimed with a conditional update, so it can be redeemed only once even
+// under concurrent requests.
+func (r *PostgresRepo) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (*model.Credentials, []model.Session, error) {
+	const op = "repo.ResetPassword"
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	var userID string
+	query := `UPDATE password_reset_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id`
+	if err := tx.QueryRow(ctx, query, tokenHash).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, authErr.ErrInvalidResetToken
+		}
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	c := &model.Credentials{}
+	query = `UPDATE credentials SET password_hash = $2, updated_at = NOW()
+		WHERE id = $1
+		RETURNING id, email, password_hash, roles, created_at, updated_at`
+	err = tx.QueryRow(ctx, query, userID, passwordHash).
+		Scan(&c.ID, &c.Email, &c.PasswordHash, &c.Roles, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sessions, err := revokeSessions(ctx, tx, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return c, sessions, nil
+}
+
+// ListSigningKeys returns the keys that are still published, oldest
+// activation first.
+func (r *PostgresRepo) ListSigningKeys(ctx context.Context) ([]model.SigningKey, error) {
+	const op = "repo.ListSigningKeys"
+
+	query := `SELECT kid, alg, private_key, created_at, activates_at, expires_at
+		FROM signing_keys WHERE expires_at > NOW()
+		ORDER BY activates_at`
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	keys := make([]model.SigningKey, 0)
+	for rows.Next() {
+		var k model.SigningKey
+		if err := rows.Scan(&k.Kid, &k.Alg, &k.PrivateKey, &k.CreatedAt, &k.ActivatesAt, &k.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// signingKeysLock serialises rotation between replicas of the service.
+const signingKeysLock = "auth.signing_keys"
+
+// AddSigningKey stores key unless a live key already activates after
+// unlessActivatedAfter, in which case another replica rotated first and
+// false is returned.
+func (r *PostgresRepo) AddSigningKey(ctx context.Context, key model.SigningKey, unlessActivatedAfter time.Time) (bool, error) {
+	const op = "repo.AddSigningKey"
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`, signingKeysLock); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM signing_keys WHERE activates_at > $1 AND expires_at > NOW())`
+	if err := tx.QueryRow(ctx, query, unlessActivatedAfter).Scan(&exists); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	if exists {
+		return false, nil
+	}
+
+	query = `INSERT INTO signing_keys (kid, alg, private_key, activates_at, expires_at) VALUES ($1, $2, $3, $4, $5)`
+	if _, err := tx.Exec(ctx, query, key.Kid, key.Alg, key.PrivateKey, key.ActivatesAt, key.ExpiresAt); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return true, nil
+}
+
+func (r *PostgresRepo) DeleteExpiredSigningKeys(ctx context.Context) error {
+	const op = "repo.DeleteExpiredSigningKeys"
+
+	if _, err := r.db.Exec(ctx, `DELETE FROM signing_keys WHERE expires_at <= NOW()`); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *PostgresRepo) GetTOTPFactor(ctx context.Context, userID string) (*model.TOTPFactor, error) {
+	const op = "repo.GetTOTPFactor"
+
+	f := &model.TOTPFactor{}
+	query := `SELECT user_id, secret, last_used_step, confirmed_at, created_at FROM totp_factors WHERE user_id = $1`
+	err := r.db.QueryRow(ctx, query, userID).
+		Scan(&f.UserID, &f.Secret, &f.LastUsedStep, &f.ConfirmedAt, &f.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, authErr.ErrMFANotEnrolled
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return f, nil
+}
+
+// SaveTOTPFactor starts an enrollment, replacing one that was never
+// confirmed. A confirmed factor is left alone and ErrMFAAlreadyEnabled is
+// returned.
+func (r *PostgresRepo) SaveTOTPFactor(ctx context.Context, userID, secret string) error {
+	const op = "repo.SaveTOTPFactor"
+
+	query := `INSERT INTO totp_factors (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0, created_at = NOW()
+		WHERE totp_factors.confirmed_at IS NULL`
+	result, err := r.db.Exec(ctx, query, userID, secret)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if result.RowsAffected() == 0 {
+		return authErr.ErrMFAAlreadyEnabled
+	}
+
+	return nil
+}
+
+// ConfirmTOTPFactor turns on a pending factor with the first code accepted
+// for it and stores its recovery codes.
+func (r *PostgresRepo) ConfirmTOTPFactor(ctx context.Context, userID string, step int64, recoveryCodeHashes []string) error {
+	const op = "repo.ConfirmTOTPFactor"
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `UPDATE totp_factors SET confirmed_at = NOW(), last_used_step = $2
+		WHERE user_id = $1 AND confirmed_at IS NULL AND last_used_step < $2`
+	result, err := tx.Exec(ctx, query, userID, step)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if result.RowsAffected() == 0 {
+		return authErr.ErrInvalidMFACode
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// UseTOTPStep records that a code for step was accepted. It returns false
+// when a code for that step or a later one was accepted before, so each code
+// works once even under concurrent requests.
+func (r *PostgresRepo) UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	const op = "repo.UseTOTPStep"
+
+	query := `UPDATE totp_factors SET last_used_step = $2
+		WHERE user_id = $1 AND confirmed_at IS NOT NULL AND last_used_step < $2`
+	result, err := r.db.Exec(ctx, query, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+// DeleteTOTPFactor removes the factor together with its recovery codes.
+func (r *PostgresRepo) DeleteTOTPFactor(ctx context.Context, userID string) error {
+	const op = "repo.DeleteTOTPFactor"
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `DELETE FROM totp_factors WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if result.RowsAffected() == 0 {
+		return authErr.ErrMFANotEnrolled
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ReplaceRecoveryCodes invalidates the user's recovery codes and stores a
+// new set.
+func (r *PostgresRepo) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	const op = "repo.ReplaceRecoveryCodes"
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID string, codeHashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO recovery_codes (user_id, code_hash) SELECT $1, UNNEST($2::text[])`
+	if _, err := tx.Exec(ctx, query, userID, codeHashes); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UseRecoveryCode redeems a recovery code. It returns false when the code
+// is unknown or was used before.
+func (r *PostgresRepo) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	const op = "repo.UseRecoveryCode"
+
+	query := `UPDATE recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+	result, err := r.db.Exec(ctx, query, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+// CountRecoveryCodes returns how many recovery codes the user has left.
+func (r *PostgresRepo) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	const op = "repo.CountRecoveryCodes"
+
+	var n int
+	query := `SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL`
+	if err := r.db.QueryRow(ctx, query, userID).Scan(&n); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
+
+// CheckSchema fails when the database is missing a column used by the
+// repository queries.
+func (r *PostgresRepo) CheckSchema(ctx context.Context) error {
+	return migrate.VerifyColumns(ctx, r.db, schema)
+}
+
+func (r *PostgresRepo) Close() {
+	r.db.Close()
+}
@@ -0,0 +1,70 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	authErr "github.com/go-market/pkg/errs"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	challengeKeyPrefix = "auth:mfa:challenge:"
+	attemptsKeyPrefix  = "auth:mfa:attempts:"
+)
+
+// MFAStore keeps sign-in challenges as keys that expire on their own, along
+// with per-user counters of code attempts.
+type MFAStore struct {
+	rdb *redis.Client
+}
+
+func NewMFAStore(rdb *redis.Client) *MFAStore {
+	return &MFAStore{rdb: rdb}
+}
+
+func (s *MFAStore) SaveChallenge(ctx context.Context, tokenHash, userID string, ttl time.Duration) error {
+	const op = "redis.SaveChallenge"
+
+	if err := s.rdb.Set(ctx, challengeKeyPrefix+tokenHash, userID, ttl).Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *MFAStore) Challenge(ctx context.Context, tokenHash string) (string, error) {
+	const op = "redis.Challenge"
+
+	userID, err := s.rdb.Get(ctx, challengeKeyPrefix+tokenHash).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", authErr.ErrInvalidMFAToken
+		}
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return userID, nil
+}
+
+func (s *MFAStore) DeleteChallenge(ctx context.Context, tokenHash string) (bool, error) {
+	const op = "redis.DeleteChallenge"
+
+	n, err := s.rdb.Del(ctx, challengeKeyPrefix+tokenHash).Result()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n == 1, nil
+}
+
+// AllowAttempt counts code attempts per user in fixed windows.
+func (s *MFAStore) AllowAttempt(ctx context.Context, userID string, limit int, window time.Duration) (bool, time.Duration, error) {
+	ok, retryAfter, err := allow(ctx, s.rdb, attemptsKeyPrefix+userID, limit, window)
+	if err != nil {
+		return false, 0, fmt.Errorf("redis.AllowAttempt: %w", err)
+	}
+	return ok, retryAfter, nil
+}
@@ -0,0 +1,52 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const rateLimitKeyPrefix = "auth:ratelimit:"
+
+// RateLimiter counts requests per key in fixed windows.
+type RateLimiter struct {
+	rdb *redis.Client
+}
+
+func NewRateLimiter(rdb *redis.Client) *RateLimiter {
+	return &RateLimiter{rdb: rdb}
+}
+
+func (l *RateLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	ok, retryAfter, err := allow(ctx, l.rdb, rateLimitKeyPrefix+key, limit, window)
+	if err != nil {
+		return false, 0, fmt.Errorf("redis.Allow: %w", err)
+	}
+	return ok, retryAfter, nil
+}
+
+// allow is a fixed-window counter: the first request in a window starts its
+// expiry. Once limit is used up it returns false and the time until the
+// window ends.
+func allow(ctx context.Context, rdb *redis.Client, key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	var (
+		count *redis.IntCmd
+		ttl   *redis.DurationCmd
+	)
+	_, err := rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		count = pipe.Incr(ctx, key)
+		pipe.ExpireNX(ctx, key, window)
+		ttl = pipe.PTTL(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return false, 0, err
+	}
+
+	if count.Val() > int64(limit) {
+		return false, ttl.Val(), nil
+	}
+	return true, 0, nil
+}
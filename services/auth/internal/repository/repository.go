@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-market/services/auth/internal/model"
+)
+
+type Repository interface {
+	CreateCredentials(ctx context.Context, creds model.Credentials) (*model.Credentials, error)
+	GetByEmail(ctx context.Context, email string) (*model.Credentials, error)
+	GetByID(ctx context.Context, id string) (*model.Credentials, error)
+	GetRefreshToken(ctx context.Context, tokenHash string) (*model.RefreshToken, error)
+	CreateSession(ctx context.Context, session model.Session, token model.RefreshToken) error
+	RotateRefreshToken(ctx context.Context, usedID string, session model.Session, next model.RefreshToken) error
+	GetSession(ctx context.Context, id string) (*model.Session, error)
+	ListSessions(ctx context.Context, userID string) ([]model.Session, error)
+	RevokeSession(ctx context.Context, userID, id string) (*model.Session, error)
+	RevokeSessions(ctx context.Context, userID string) ([]model.Session, error)
+	SavePasswordResetToken(ctx context.Context, token model.PasswordResetToken) error
+	ResetPassword(ctx context.Context, tokenHash, passwordHash string) (*model.Credentials, []model.Session, error)
+	ListSigningKeys(ctx context.Context) ([]model.SigningKey, error)
+	AddSigningKey(ctx context.Context, key model.SigningKey, unlessActivatedAfter time.Time) (bool, error)
+	DeleteExpiredSigningKeys(ctx context.Context) error
+	GetTOTPFactor(ctx context.Context, userID string) (*model.TOTPFactor, error)
+	SaveTOTPFactor(ctx context.Context, userID, secret string) error
+	ConfirmTOTPFactor(ctx context.Context, userID string, step int64, recoveryCodeHashes []string) error
+	UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error)
+	DeleteTOTPFactor(ctx context.Context, userID string) error
+	ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error)
+	CountRecoveryCodes(ctx context.Context, userID string) (int, error)
+}
+
+// MFAStore keeps short-lived second factor state in Redis.
+type MFAStore interface {
+	// SaveChallenge records that userID passed the password check and may
+	// finish signing in with a code until ttl runs out.
+	SaveChallenge(ctx context.Context, tokenHash, userID string, ttl time.Duration) error
+	// Challenge returns the user the challenge belongs to.
+	Challenge(ctx context.Context, tokenHash string) (string, error)
+	// DeleteChallenge reports whether the challenge still existed, so only
+	// one request can redeem it.
+	DeleteChallenge(ctx context.Context, tokenHash string) (bool, error)
+	// AllowAttempt counts a code attempt for userID. When the limit for the
+	// current window is used up it returns false and the time until the
+	// window ends.
+	AllowAttempt(ctx context.Context, userID string, limit int, window time.Duration) (bool, time.Duration, error)
+}
+
+// RateLimiter counts requests per key in fixed windows.
+type RateLimiter interface {
+	// Allow counts a request for key. When the limit for the current window
+	// is used up it returns false and the time until the window ends.
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, time.Duration, error)
+}
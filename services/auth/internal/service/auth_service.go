@@ -0,0 +1,293 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-market/pkg/authz"
+	authErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/pkg/validation"
+	"github.com/go-market/services/auth/internal/model"
+	authRepo "github.com/go-market/services/auth/internal/repository"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const minPasswordLength = 8
+
+type Options struct {
+	// Issuer and Audience go into the `iss` and `aud` claims of every
+	// access token.
+	Issuer     string
+	Audience   string
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+	// Clients are the services allowed to use the client credentials grant.
+	Clients []model.ServiceClient
+	MFA     MFAOptions
+}
+
+type Service struct {
+	repo   authRepo.Repository
+	denied authz.DenyList
+	mfa    authRepo.MFAStore
+	keys   *Keyring
+	sealer *Sealer
+	opts   Options
+}
+
+func New(repo authRepo.Repository, denied authz.DenyList, mfa authRepo.MFAStore, keys *Keyring, sealer *Sealer, opts Options) *Service {
+	return &Service{
+		repo:   repo,
+		denied: denied,
+		mfa:    mfa,
+		keys:   keys,
+		sealer: sealer,
+		opts:   opts,
+	}
+}
+
+func (s *Service) Register(ctx context.Context, email, password string, client model.Client) (*model.TokenPair, error) {
+	email = normalizeEmail(email)
+	if !validation.Var(email, validation.EmailRule) {
+		return nil, authErr.ErrInvalidEmail
+	}
+	if len(password) < minPasswordLength {
+		return nil, authErr.ErrInvalidPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	creds, err := s.repo.CreateCredentials(ctx, model.Credentials{
+		Email:        email,
+		PasswordHash: string(hash),
+		Roles:        []string{authz.RoleUser},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.startSession(ctx, creds, client, []string{authz.AMRPassword})
+}
+
+// Login checks the password. Users with a second factor get a challenge to
+// answer with Service.LoginMFA instead of tokens.
+func (s *Service) Login(ctx context.Context, email, password string, client model.Client) (*model.LoginResult, error) {
+	creds, err := s.repo.GetByEmail(ctx, normalizeEmail(email))
+	if err != nil {
+		if errors.Is(err, authErr.ErrUserNotFound) {
+			return nil, authErr.ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(creds.PasswordHash), []byte(password)); err != nil {
+		return nil, authErr.ErrInvalidCredentials
+	}
+
+	enabled, err := s.totpEnabled(ctx, creds.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		challenge, err := s.challenge(ctx, creds.ID)
+		if err != nil {
+			return nil, err
+		}
+		return &model.LoginResult{Challenge: challenge}, nil
+	}
+
+	tokens, err := s.startSession(ctx, creds, client, []string{authz.AMRPassword})
+	if err != nil {
+		return nil, err
+	}
+	return &model.LoginResult{Tokens: tokens}, nil
+}
+
+// Refresh exchanges a refresh token for a new pair in the same session.
+// Each refresh token works once. Presenting one that was already used means
+// it leaked, since the legitimate client moved on to its successor, so the
+// whole session is revoked for both parties.
+func (s *Service) Refresh(ctx context.Context, refreshToken string, client model.Client) (*model.TokenPair, error) {
+	if refreshToken == "" {
+		return nil, authErr.ErrInvalidToken
+	}
+
+	stored, err := s.repo.GetRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if stored.SessionID == "" {
+		return nil, authErr.ErrInvalidToken
+	}
+	if stored.RevokedAt != nil {
+		if err := s.revokeSession(ctx, stored.UserID, stored.SessionID); err != nil &&
+			!errors.Is(err, authErr.ErrSessionNotFound) {
+			return nil, err
+		}
+		return nil, authErr.ErrInvalidToken
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, authErr.ErrInvalidToken
+	}
+
+	creds, err := s.repo.GetByID(ctx, stored.UserID)
+	if err != nil {
+		if errors.Is(err, authErr.ErrUserNotFound) {
+			return nil, authErr.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	// The session keeps the authentication methods of its login, so a
+	// refreshed token is exactly as strong as the first one.
+	current, err := s.repo.GetSession(ctx, stored.SessionID)
+	if err != nil {
+		if errors.Is(err, authErr.ErrSessionNotFound) {
+			return nil, authErr.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	pair, session, next, err := s.newTokens(creds, stored.SessionID, current.AMR, client)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.RotateRefreshToken(ctx, stored.ID, *session, *next); err != nil {
+		return nil, err
+	}
+
+	return pair, nil
+}
+
+// Logout ends the session the refresh token belongs to.
+func (s *Service) Logout(ctx context.Context, refreshToken string) error {
+	if refreshToken == "" {
+		return authErr.ErrInvalidToken
+	}
+
+	stored, err := s.repo.GetRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		return err
+	}
+	if stored.SessionID == "" {
+		return authErr.ErrInvalidToken
+	}
+
+	if err := s.revokeSession(ctx, stored.UserID, stored.SessionID); err != nil {
+		if errors.Is(err, authErr.ErrSessionNotFound) {
+			return authErr.ErrInvalidToken
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *Service) Sessions(ctx context.Context, userID string) ([]model.Session, error) {
+	return s.repo.ListSessions(ctx, userID)
+}
+
+// RevokeSession signs one of the user's sessions out everywhere: its
+// refresh tokens stop working and its current access token is denied.
+func (s *Service) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	if uuid.Validate(sessionID) != nil {
+		return authErr.ErrSessionNotFound
+	}
+	return s.revokeSession(ctx, userID, sessionID)
+}
+
+// RevokeSessions signs the user out of every session.
+func (s *Service) RevokeSessions(ctx context.Context, userID string) error {
+	sessions, err := s.repo.RevokeSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return denyAccess(ctx, s.denied, sessions...)
+}
+
+func (s *Service) revokeSession(ctx context.Context, userID, sessionID string) error {
+	session, err := s.repo.RevokeSession(ctx, userID, sessionID)
+	if err != nil {
+		return err
+	}
+	return denyAccess(ctx, s.denied, *session)
+}
+
+// denyAccess puts the newest access token of each session on the deny list.
+// Older ones were issued before the last refresh and expire within the
+// access token TTL.
+func denyAccess(ctx context.Context, denied authz.DenyList, sessions ...model.Session) error {
+	for _, session := range sessions {
+		if err := denied.Deny(ctx, session.AccessJTI, session.AccessExpiresAt); err != nil {
+			return fmt.Errorf("failed to deny access token: %w", err)
+		}
+	}
+	return nil
+}
+
+// startSession signs the user in with the authentication methods in amr.
+func (s *Service) startSession(ctx context.Context, creds *model.Credentials, client model.Client, amr []string) (*model.TokenPair, error) {
+	pair, session, token, err := s.newTokens(creds, uuid.NewString(), amr, client)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateSession(ctx, *session, *token); err != nil {
+		return nil, err
+	}
+
+	return pair, nil
+}
+
+// newTokens issues an access and a refresh token for sessionID, along with
+// the session state and refresh token record to persist.
+func (s *Service) newTokens(creds *model.Credentials, sessionID string, amr []string, client model.Client) (*model.TokenPair, *model.Session, *model.RefreshToken, error) {
+	now := time.Now()
+
+	accessToken, jti, accessExpiresAt, err := s.newAccessToken(creds, sessionID, amr, now)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	refreshToken, err := randomString(32)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	refreshExpiresAt := now.Add(s.opts.RefreshTTL)
+
+	session := &model.Session{
+		ID:              sessionID,
+		UserID:          creds.ID,
+		UserAgent:       client.UserAgent,
+		IP:              client.IP,
+		AccessJTI:       jti,
+		AccessExpiresAt: accessExpiresAt,
+		ExpiresAt:       refreshExpiresAt,
+		AMR:             amr,
+	}
+	token := &model.RefreshToken{
+		UserID:    creds.ID,
+		SessionID: sessionID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: refreshExpiresAt,
+	}
+	pair := &model.TokenPair{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		TokenType:        tokenTypeBearer,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshExpiresAt: refreshExpiresAt,
+	}
+
+	return pair, session, token, nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
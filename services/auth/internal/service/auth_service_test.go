@@ -0,0 +1,608 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-market/pkg/authz"
+	authErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/services/auth/internal/model"
+	authRepo "github.com/go-market/services/auth/internal/repository"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/pquerna/otp/totp"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// memRepo keeps what the token, key and MFA tests need in memory and
+// mirrors the conditional updates of the Postgres repository.
+type memRepo struct {
+	authRepo.Repository
+
+	mu       sync.Mutex
+	creds    map[string]*model.Credentials
+	tokens   map[string]*model.RefreshToken
+	sessions map[string]*model.Session
+	keys     []model.SigningKey
+	factors  map[string]*model.TOTPFactor
+	recovery map[string]map[string]bool
+}
+
+func newMemRepo() *memRepo {
+	return &memRepo{
+		creds:    make(map[string]*model.Credentials),
+		tokens:   make(map[string]*model.RefreshToken),
+		sessions: make(map[string]*model.Session),
+		factors:  make(map[string]*model.TOTPFactor),
+		recovery: make(map[string]map[string]bool),
+	}
+}
+
+func (m *memRepo) GetByID(_ context.Context, id string) (*model.Credentials, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.creds[id]
+	if !ok {
+		return nil, authErr.ErrUserNotFound
+	}
+	cp := *c
+	return &cp, nil
+}
+
+func (m *memRepo) GetByEmail(_ context.Context, email string) (*model.Credentials, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, c := range m.creds {
+		if c.Email == email {
+			cp := *c
+			return &cp, nil
+		}
+	}
+	return nil, authErr.ErrUserNotFound
+}
+
+func (m *memRepo) GetRefreshToken(_ context.Context, tokenHash string) (*model.RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.tokens[tokenHash]
+	if !ok {
+		return nil, authErr.ErrInvalidToken
+	}
+	cp := *t
+	return &cp, nil
+}
+
+func (m *memRepo) CreateSession(_ context.Context, session model.Session, token model.RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sessions[session.ID] = &session
+	token.ID = uuid.NewString()
+	m.tokens[token.TokenHash] = &token
+	return nil
+}
+
+func (m *memRepo) RotateRefreshToken(_ context.Context, usedID string, session model.Session, next model.RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var used *model.RefreshToken
+	for _, t := range m.tokens {
+		if t.ID == usedID {
+			used = t
+		}
+	}
+	if used == nil || used.RevokedAt != nil {
+		return authErr.ErrInvalidToken
+	}
+	current, ok := m.sessions[session.ID]
+	if !ok || current.RevokedAt != nil {
+		return authErr.ErrInvalidToken
+	}
+
+	now := time.Now()
+	used.RevokedAt = &now
+	current.AccessJTI = session.AccessJTI
+	current.AccessExpiresAt = session.AccessExpiresAt
+	next.ID = uuid.NewString()
+	m.tokens[next.TokenHash] = &next
+	return nil
+}
+
+func (m *memRepo) GetSession(_ context.Context, id string) (*model.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil, authErr.ErrSessionNotFound
+	}
+	cp := *s
+	return &cp, nil
+}
+
+func (m *memRepo) RevokeSession(_ context.Context, userID, id string) (*model.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[id]
+	if !ok || s.UserID != userID || s.RevokedAt != nil {
+		return nil, authErr.ErrSessionNotFound
+	}
+
+	now := time.Now()
+	s.RevokedAt = &now
+	for _, t := range m.tokens {
+		if t.SessionID == id && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+	cp := *s
+	return &cp, nil
+}
+
+func (m *memRepo) ListSigningKeys(context.Context) ([]model.SigningKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var keys []model.SigningKey
+	for _, k := range m.keys {
+		if k.ExpiresAt.After(time.Now()) {
+			keys = append(keys, k)
+		}
+	}
+	slices.SortFunc(keys, func(a, b model.SigningKey) int { return a.ActivatesAt.Compare(b.ActivatesAt) })
+	return keys, nil
+}
+
+func (m *memRepo) AddSigningKey(_ context.Context, key model.SigningKey, unlessActivatedAfter time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, k := range m.keys {
+		if k.ActivatesAt.After(unlessActivatedAfter) && k.ExpiresAt.After(time.Now()) {
+			return false, nil
+		}
+	}
+	m.keys = append(m.keys, key)
+	return true, nil
+}
+
+func (m *memRepo) DeleteExpiredSigningKeys(context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.keys = slices.DeleteFunc(m.keys, func(k model.SigningKey) bool { return !k.ExpiresAt.After(time.Now()) })
+	return nil
+}
+
+// shiftKeys moves every key's activation and expiry by d, which is how the
+// tests let time pass for the keyring.
+func (m *memRepo) shiftKeys(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.keys {
+		m.keys[i].ActivatesAt = m.keys[i].ActivatesAt.Add(d)
+		m.keys[i].ExpiresAt = m.keys[i].ExpiresAt.Add(d)
+	}
+}
+
+func (m *memRepo) GetTOTPFactor(_ context.Context, userID string) (*model.TOTPFactor, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, ok := m.factors[userID]
+	if !ok {
+		return nil, authErr.ErrMFANotEnrolled
+	}
+	cp := *f
+	return &cp, nil
+}
+
+func (m *memRepo) SaveTOTPFactor(_ context.Context, userID, secret string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if f, ok := m.factors[userID]; ok && f.ConfirmedAt != nil {
+		return authErr.ErrMFAAlreadyEnabled
+	}
+	m.factors[userID] = &model.TOTPFactor{UserID: userID, Secret: secret}
+	return nil
+}
+
+func (m *memRepo) ConfirmTOTPFactor(_ context.Context, userID string, step int64, recoveryCodeHashes []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, ok := m.factors[userID]
+	if !ok || f.ConfirmedAt != nil {
+		return authErr.ErrMFANotEnrolled
+	}
+	now := time.Now()
+	f.ConfirmedAt = &now
+	f.LastUsedStep = step
+
+	codes := make(map[string]bool)
+	for _, h := range recoveryCodeHashes {
+		codes[h] = true
+	}
+	m.recovery[userID] = codes
+	return nil
+}
+
+func (m *memRepo) UseTOTPStep(_ context.Context, userID string, step int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, ok := m.factors[userID]
+	if !ok || step <= f.LastUsedStep {
+		return false, nil
+	}
+	f.LastUsedStep = step
+	return true, nil
+}
+
+func (m *memRepo) UseRecoveryCode(_ context.Context, userID, codeHash string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.recovery[userID][codeHash] {
+		return false, nil
+	}
+	delete(m.recovery[userID], codeHash)
+	return true, nil
+}
+
+// memDenyList records denied token ids.
+type memDenyList struct {
+	mu     sync.Mutex
+	denied map[string]bool
+}
+
+func (d *memDenyList) Deny(_ context.Context, jti string, _ time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.denied == nil {
+		d.denied = make(map[string]bool)
+	}
+	d.denied[jti] = true
+	return nil
+}
+
+func (d *memDenyList) Denied(_ context.Context, jti string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.denied[jti], nil
+}
+
+// memMFAStore keeps challenges in memory and never throttles.
+type memMFAStore struct {
+	mu         sync.Mutex
+	challenges map[string]string
+}
+
+func (s *memMFAStore) SaveChallenge(_ context.Context, tokenHash, userID string, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.challenges == nil {
+		s.challenges = make(map[string]string)
+	}
+	s.challenges[tokenHash] = userID
+	return nil
+}
+
+func (s *memMFAStore) Challenge(_ context.Context, tokenHash string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	userID, ok := s.challenges[tokenHash]
+	if !ok {
+		return "", authErr.ErrInvalidMFAToken
+	}
+	return userID, nil
+}
+
+func (s *memMFAStore) DeleteChallenge(_ context.Context, tokenHash string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.challenges[tokenHash]
+	delete(s.challenges, tokenHash)
+	return ok, nil
+}
+
+func (s *memMFAStore) AllowAttempt(context.Context, string, int, time.Duration) (bool, time.Duration, error) {
+	return true, 0, nil
+}
+
+const (
+	testEmail    = "alice@example.com"
+	testPassword = "correct horse"
+	testIssuer   = "http://auth.test"
+	testAudience = "go-market"
+)
+
+type testService struct {
+	*Service
+	repo   *memRepo
+	denied *memDenyList
+	userID string
+}
+
+func newTestKeyring(t *testing.T, repo *memRepo) *Keyring {
+	t.Helper()
+
+	sealer, err := NewSealer(testSecretsKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := NewKeyring(context.Background(), repo, sealer, slog.New(slog.NewTextHandler(io.Discard, nil)), KeyringOptions{
+		Alg:              authz.AlgEdDSA,
+		RotationInterval: time.Hour,
+		Prepublish:       10 * time.Minute,
+		Retain:           16 * time.Minute,
+		CheckInterval:    time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	return keys
+}
+
+// newTestService returns a service with one user who signs in with
+// testEmail and testPassword.
+func newTestService(t *testing.T) *testService {
+	t.Helper()
+
+	repo := newMemRepo()
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID := uuid.NewString()
+	repo.creds[userID] = &model.Credentials{ID: userID, Email: testEmail, PasswordHash: string(hash), Roles: []string{authz.RoleUser}}
+
+	sealer, err := NewSealer(testSecretsKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	denied := &memDenyList{}
+	svc := New(repo, denied, &memMFAStore{}, newTestKeyring(t, repo), sealer, Options{
+		Issuer:     testIssuer,
+		Audience:   testAudience,
+		AccessTTL:  15 * time.Minute,
+		RefreshTTL: time.Hour,
+		MFA: MFAOptions{
+			Issuer:        "go-market",
+			ChallengeTTL:  5 * time.Minute,
+			AttemptLimit:  5,
+			AttemptWindow: 5 * time.Minute,
+			RecoveryCodes: 2,
+		},
+	})
+
+	return &testService{Service: svc, repo: repo, denied: denied, userID: userID}
+}
+
+func (s *testService) login(t *testing.T) *model.LoginResult {
+	t.Helper()
+
+	result, err := s.Login(context.Background(), testEmail, testPassword, model.Client{})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	return result
+}
+
+func (s *testService) verify(t *testing.T, token string) (authz.Principal, error) {
+	t.Helper()
+
+	claims, err := authz.NewVerifier(s.keys, testIssuer, testAudience).Verify(context.Background(), token)
+	if err != nil {
+		return authz.Principal{}, err
+	}
+	jti, _ := claims["jti"].(string)
+	if denied, _ := s.denied.Denied(context.Background(), jti); denied {
+		return authz.Principal{}, authErr.ErrInvalidToken
+	}
+
+	sub, _ := claims["sub"].(string)
+	var amr []string
+	for _, v := range claims["amr"].([]any) {
+		amr = append(amr, v.(string))
+	}
+	return authz.Principal{UserID: sub, AMR: amr}, nil
+}
+
+func TestRefresh(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+
+	first := s.login(t).Tokens
+	second, err := s.Refresh(ctx, first.RefreshToken, model.Client{})
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("Refresh() returned the same refresh token")
+	}
+	if _, err := s.verify(t, second.AccessToken); err != nil {
+		t.Fatalf("refreshed access token does not verify: %v", err)
+	}
+
+	// Replaying the used token means it leaked: the session ends for the
+	// thief and the legitimate client alike.
+	if _, err := s.Refresh(ctx, first.RefreshToken, model.Client{}); !errors.Is(err, authErr.ErrInvalidToken) {
+		t.Fatalf("Refresh() with a used token error = %v, want %v", err, authErr.ErrInvalidToken)
+	}
+	if _, err := s.Refresh(ctx, second.RefreshToken, model.Client{}); !errors.Is(err, authErr.ErrInvalidToken) {
+		t.Errorf("Refresh() after reuse error = %v, want %v", err, authErr.ErrInvalidToken)
+	}
+	if _, err := s.verify(t, second.AccessToken); !errors.Is(err, authErr.ErrInvalidToken) {
+		t.Errorf("access token after reuse error = %v, want it denied", err)
+	}
+
+	if _, err := s.Refresh(ctx, "unknown", model.Client{}); !errors.Is(err, authErr.ErrInvalidToken) {
+		t.Errorf("Refresh() with an unknown token error = %v, want %v", err, authErr.ErrInvalidToken)
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	repo := newMemRepo()
+	keys := newTestKeyring(t, repo)
+	verifier := authz.NewVerifier(keys, testIssuer, testAudience)
+
+	sign := func() (string, string) {
+		t.Helper()
+		now := time.Now()
+		token, err := keys.Sign(jwt.MapClaims{
+			"iss": testIssuer, "aud": testAudience, "sub": "u1",
+			"iat": now.Unix(), "nbf": now.Unix(), "exp": now.Add(time.Minute).Unix(),
+		})
+		if err != nil {
+			t.Fatalf("Sign() error = %v", err)
+		}
+		kid := strings.Split(token, ".")[0]
+		return token, kid
+	}
+	published := func() int {
+		t.Helper()
+		set, err := keys.JWKS()
+		if err != nil {
+			t.Fatalf("JWKS() error = %v", err)
+		}
+		return len(set.Keys)
+	}
+
+	for _, k := range repo.keys {
+		if !strings.HasPrefix(k.PrivateKey, sealedPrefix) || strings.Contains(k.PrivateKey, "PRIVATE KEY") {
+			t.Fatalf("signing key %s is stored unsealed", k.Kid)
+		}
+	}
+
+	oldToken, oldHeader := sign()
+	if published() != 1 {
+		t.Fatalf("published keys = %d, want 1", published())
+	}
+
+	// Shortly before the interval ends a successor is prepublished, but the
+	// current key keeps signing.
+	repo.shiftKeys(-55 * time.Minute)
+	if err := keys.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if published() != 2 {
+		t.Fatalf("published keys = %d, want the successor prepublished", published())
+	}
+	if _, header := sign(); header != oldHeader {
+		t.Error("successor signs before it activates")
+	}
+
+	// Once it activates the successor signs, and tokens from the old key
+	// still verify until they expire.
+	repo.shiftKeys(-10 * time.Minute)
+	if err := keys.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	newToken, newHeader := sign()
+	if newHeader == oldHeader {
+		t.Error("old key still signs after the successor activated")
+	}
+	for name, token := range map[string]string{"old": oldToken, "new": newToken} {
+		if _, err := verifier.Verify(context.Background(), token); err != nil {
+			t.Errorf("%s token does not verify: %v", name, err)
+		}
+	}
+
+	// After its retention the old key is gone, and so are its tokens.
+	repo.shiftKeys(-time.Hour)
+	if err := keys.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if _, err := verifier.Verify(context.Background(), oldToken); !errors.Is(err, authErr.ErrInvalidToken) {
+		t.Errorf("token of a dropped key error = %v, want %v", err, authErr.ErrInvalidToken)
+	}
+}
+
+func TestTOTPReplay(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+
+	enrollment, err := s.EnrollTOTP(ctx, s.userID)
+	if err != nil {
+		t.Fatalf("EnrollTOTP() error = %v", err)
+	}
+	if stored := s.repo.factors[s.userID].Secret; stored == enrollment.Secret || !strings.HasPrefix(stored, sealedPrefix) {
+		t.Fatalf("TOTP secret is stored unsealed: %q", stored)
+	}
+
+	now := time.Now()
+	code := func(at time.Time) string {
+		t.Helper()
+		c, err := totp.GenerateCodeCustom(enrollment.Secret, at, totpOpts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	current, next := code(now), code(now.Add(totpPeriod*time.Second))
+
+	recovery, err := s.ConfirmTOTP(ctx, s.userID, current)
+	if err != nil {
+		t.Fatalf("ConfirmTOTP() error = %v", err)
+	}
+
+	challenge := s.login(t).Challenge
+	if challenge == nil {
+		t.Fatal("Login() issued tokens to a user with a second factor")
+	}
+
+	// The code that confirmed the factor cannot sign in.
+	if _, err := s.LoginMFA(ctx, challenge.Token, current, model.Client{}); !errors.Is(err, authErr.ErrInvalidMFACode) {
+		t.Fatalf("LoginMFA() with the confirming code error = %v, want %v", err, authErr.ErrInvalidMFACode)
+	}
+
+	tokens, err := s.LoginMFA(ctx, challenge.Token, next, model.Client{})
+	if err != nil {
+		t.Fatalf("LoginMFA() error = %v", err)
+	}
+	principal, err := s.verify(t, tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("access token does not verify: %v", err)
+	}
+	if !principal.MFA() {
+		t.Errorf("access token amr = %v, want mfa", principal.AMR)
+	}
+
+	// Neither the challenge nor the code works twice.
+	if _, err := s.LoginMFA(ctx, challenge.Token, next, model.Client{}); !errors.Is(err, authErr.ErrInvalidMFAToken) {
+		t.Errorf("LoginMFA() with a redeemed challenge error = %v, want %v", err, authErr.ErrInvalidMFAToken)
+	}
+	again := s.login(t).Challenge
+	if _, err := s.LoginMFA(ctx, again.Token, next, model.Client{}); !errors.Is(err, authErr.ErrInvalidMFACode) {
+		t.Errorf("LoginMFA() with a replayed code error = %v, want %v", err, authErr.ErrInvalidMFACode)
+	}
+
+	// A recovery code works once.
+	if _, err := s.LoginMFA(ctx, again.Token, recovery[0], model.Client{}); err != nil {
+		t.Fatalf("LoginMFA() with a recovery code error = %v", err)
+	}
+	last := s.login(t).Challenge
+	if _, err := s.LoginMFA(ctx, last.Token, recovery[0], model.Client{}); !errors.Is(err, authErr.ErrInvalidMFACode) {
+		t.Errorf("LoginMFA() with a used recovery code error = %v, want %v", err, authErr.ErrInvalidMFACode)
+	}
+}
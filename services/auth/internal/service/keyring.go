@@ -0,0 +1,313 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/go-market/pkg/authz"
+	"github.com/go-market/services/auth/internal/model"
+	authRepo "github.com/go-market/services/auth/internal/repository"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type KeyringOptions struct {
+	// Alg is the algorithm of newly generated keys: RS256 or EdDSA.
+	Alg string
+	// RotationInterval is how long each key signs tokens.
+	RotationInterval time.Duration
+	// Prepublish is how long a new key is published before it starts
+	// signing, so verifiers with a cached JWK Set already know it.
+	Prepublish time.Duration
+	// Retain is how long a key stays published after it stopped signing.
+	// It must cover the lifetime of the tokens it signed.
+	Retain time.Duration
+	// CheckInterval is how often Run looks for due rotations and for keys
+	// added by other replicas.
+	CheckInterval time.Duration
+}
+
+type signingKey struct {
+	kid         string
+	alg         string
+	private     crypto.Signer
+	activatesAt time.Time
+	expiresAt   time.Time
+}
+
+// Keyring holds the keys that sign access tokens and rotates them. Keys
+// live in Postgres so every replica signs with and publishes the same set;
+// the private halves are sealed before they are stored.
+type Keyring struct {
+	repo   authRepo.Repository
+	sealer *Sealer
+	log    *slog.Logger
+	opts   KeyringOptions
+
+	mu   sync.RWMutex
+	keys []signingKey
+}
+
+const rsaKeyBits = 2048
+
+// NewKeyring loads the keys, creating the first one if there is none, so the
+// keyring can sign as soon as it is returned.
+func NewKeyring(ctx context.Context, repo authRepo.Repository, sealer *Sealer, log *slog.Logger, opts KeyringOptions) (*Keyring, error) {
+	if opts.Alg != authz.AlgRS256 && opts.Alg != authz.AlgEdDSA {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", opts.Alg)
+	}
+
+	k := &Keyring{
+		repo:   repo,
+		sealer: sealer,
+		log:    log.With(slog.String("component", "service.Keyring")),
+		opts:   opts,
+	}
+	if err := k.Sync(ctx); err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// Run syncs the keyring every CheckInterval until ctx is cancelled.
+func (k *Keyring) Run(ctx context.Context) {
+	ticker := time.NewTicker(k.opts.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := k.Sync(ctx); err != nil {
+				k.log.Error("failed to sync signing keys", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// Sync loads the published keys, adds a successor when the newest key is
+// due for rotation and drops keys that have expired.
+func (k *Keyring) Sync(ctx context.Context) error {
+	if err := k.load(ctx); err != nil {
+		return err
+	}
+
+	rotated, err := k.rotate(ctx)
+	if err != nil {
+		return err
+	}
+	if rotated {
+		if err := k.load(ctx); err != nil {
+			return err
+		}
+	}
+
+	return k.repo.DeleteExpiredSigningKeys(ctx)
+}
+
+func (k *Keyring) load(ctx context.Context) error {
+	stored, err := k.repo.ListSigningKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]signingKey, 0, len(stored))
+	for _, s := range stored {
+		pemKey, err := k.sealer.Open(s.PrivateKey, s.Kid)
+		if err != nil {
+			return fmt.Errorf("signing key %s: %w", s.Kid, err)
+		}
+		private, err := parsePrivateKey(pemKey)
+		if err != nil {
+			return fmt.Errorf("signing key %s: %w", s.Kid, err)
+		}
+		keys = append(keys, signingKey{
+			kid:         s.Kid,
+			alg:         s.Alg,
+			private:     private,
+			activatesAt: s.ActivatesAt,
+			expiresAt:   s.ExpiresAt,
+		})
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.mu.Unlock()
+	return nil
+}
+
+// rotate adds a successor once the newest key has been signing for
+// RotationInterval minus Prepublish, so the successor activates right when
+// the interval is over.
+func (k *Keyring) rotate(ctx context.Context) (bool, error) {
+	now := time.Now()
+	threshold := now.Add(k.opts.Prepublish - k.opts.RotationInterval)
+
+	k.mu.RLock()
+	due := true
+	for _, key := range k.keys {
+		if key.activatesAt.After(threshold) {
+			due = false
+		}
+	}
+	_, canSign := k.current(now)
+	k.mu.RUnlock()
+
+	if !due {
+		return false, nil
+	}
+
+	// Without a key that can sign, waiting for the new one to be
+	// prepublished would only mean failing logins in the meantime.
+	activatesAt := now.Add(k.opts.Prepublish)
+	if !canSign {
+		activatesAt = now
+	}
+
+	key, err := k.generate(activatesAt)
+	if err != nil {
+		return false, err
+	}
+
+	added, err := k.repo.AddSigningKey(ctx, key, threshold)
+	if err != nil {
+		return false, err
+	}
+	if added {
+		k.log.Info("added signing key",
+			slog.String("kid", key.Kid), slog.String("alg", key.Alg), slog.Time("activates_at", key.ActivatesAt))
+	}
+
+	return added, nil
+}
+
+func (k *Keyring) generate(activatesAt time.Time) (model.SigningKey, error) {
+	var (
+		private crypto.Signer
+		err     error
+	)
+	switch k.opts.Alg {
+	case authz.AlgRS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case authz.AlgEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		return model.SigningKey{}, fmt.Errorf("generate signing key: %w", err)
+	}
+
+	kid, err := authz.Thumbprint(private.Public())
+	if err != nil {
+		return model.SigningKey{}, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return model.SigningKey{}, err
+	}
+	sealed, err := k.sealer.Seal(string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), kid)
+	if err != nil {
+		return model.SigningKey{}, fmt.Errorf("seal signing key: %w", err)
+	}
+
+	return model.SigningKey{
+		Kid:         kid,
+		Alg:         k.opts.Alg,
+		PrivateKey:  sealed,
+		ActivatesAt: activatesAt,
+		ExpiresAt:   activatesAt.Add(k.opts.RotationInterval + k.opts.Retain),
+	}, nil
+}
+
+// current returns the most recently activated key. Callers hold k.mu.
+func (k *Keyring) current(now time.Time) (signingKey, bool) {
+	var (
+		cur   signingKey
+		found bool
+	)
+	for _, key := range k.keys {
+		if key.activatesAt.After(now) || !key.expiresAt.After(now) {
+			continue
+		}
+		if !found || key.activatesAt.After(cur.activatesAt) {
+			cur, found = key, true
+		}
+	}
+	return cur, found
+}
+
+// Sign signs claims with the current key and names it in the `kid` header.
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	k.mu.RLock()
+	key, ok := k.current(time.Now())
+	k.mu.RUnlock()
+	if !ok {
+		return "", errors.New("no active signing key")
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.alg), claims)
+	token.Header["kid"] = key.kid
+
+	return token.SignedString(key.private)
+}
+
+// JWKS returns the public halves of every published key, including keys
+// that do not sign yet or any more.
+func (k *Keyring) JWKS() (authz.JWKSet, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := authz.JWKSet{Keys: make([]authz.JWK, 0, len(k.keys))}
+	for _, key := range k.keys {
+		jwk, err := authz.NewJWK(key.kid, key.alg, key.private.Public())
+		if err != nil {
+			return authz.JWKSet{}, err
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set, nil
+}
+
+// PublicKey makes the keyring an authz.KeySource, so this service verifies
+// its own tokens without fetching its own JWK Set.
+func (k *Keyring) PublicKey(_ context.Context, kid string) (authz.PublicKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for _, key := range k.keys {
+		if key.kid == kid {
+			return authz.PublicKey{Alg: key.alg, Key: key.private.Public()}, nil
+		}
+	}
+	return authz.PublicKey{}, authz.ErrUnknownKey
+}
+
+func parsePrivateKey(data string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("invalid pem")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
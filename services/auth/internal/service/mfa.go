@@ -0,0 +1,395 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"image/png"
+	"strings"
+	"time"
+
+	"github.com/go-market/pkg/authz"
+	authErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/services/auth/internal/model"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+type MFAOptions struct {
+	// Issuer is the name authenticator apps show next to the account.
+	Issuer string
+	// ChallengeTTL is how long a user has to enter a code after the
+	// password was accepted.
+	ChallengeTTL time.Duration
+	// AttemptLimit is how many codes a user may try per AttemptWindow,
+	// which keeps six digits from being guessed.
+	AttemptLimit  int
+	AttemptWindow time.Duration
+	// RecoveryCodes is how many recovery codes are issued at a time.
+	RecoveryCodes int
+}
+
+const (
+	totpPeriod = 30
+	// totpSkew is how many steps a code may be off either way, for clocks
+	// that drift and users who type slowly.
+	totpSkew = 1
+
+	qrCodeSize = 256
+	// recoveryCodeBytes gives codes 80 bits of entropy, enough for a plain
+	// SHA-256 to be a safe way of storing them.
+	recoveryCodeBytes = 10
+)
+
+var (
+	totpOpts = totp.ValidateOpts{Period: totpPeriod, Digits: otp.DigitsSix, Algorithm: otp.AlgorithmSHA1}
+
+	// amrMFA is recorded for sessions started with a password and a code.
+	amrMFA = []string{authz.AMRPassword, authz.AMROTP, authz.AMRMFA}
+
+	recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+)
+
+func (s *Service) MFAStatus(ctx context.Context, userID string) (*model.MFAStatus, error) {
+	enabled, err := s.totpEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &model.MFAStatus{TOTPEnabled: enabled}
+	if enabled {
+		status.RecoveryCodesLeft, err = s.repo.CountRecoveryCodes(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return status, nil
+}
+
+// EnrollTOTP generates a new TOTP secret for the user. It does not protect
+// sign-in until ConfirmTOTP proves the authenticator app was set up. Asking
+// again before that replaces the pending secret.
+func (s *Service) EnrollTOTP(ctx context.Context, userID string) (*model.TOTPEnrollment, error) {
+	const op = "Service.EnrollTOTP"
+
+	creds, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      s.opts.MFA.Issuer,
+		AccountName: creds.Email,
+		Period:      totpPeriod,
+		Digits:      totpOpts.Digits,
+		Algorithm:   totpOpts.Algorithm,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	img, err := key.Image(qrCodeSize, qrCodeSize)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	var qr bytes.Buffer
+	if err := png.Encode(&qr, img); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sealed, err := s.sealer.Seal(key.Secret(), userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := s.repo.SaveTOTPFactor(ctx, userID, sealed); err != nil {
+		return nil, err
+	}
+
+	return &model.TOTPEnrollment{
+		Secret: key.Secret(),
+		URI:    key.URL(),
+		QRCode: qr.Bytes(),
+	}, nil
+}
+
+// ConfirmTOTP turns on the pending factor once code shows the authenticator
+// app works, and returns recovery codes. They are only stored hashed, so
+// this is the one time they can be shown.
+func (s *Service) ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error) {
+	factor, err := s.totpFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if factor.ConfirmedAt != nil {
+		return nil, authErr.ErrMFAAlreadyEnabled
+	}
+
+	if err := s.allowAttempt(ctx, userID); err != nil {
+		return nil, err
+	}
+	step, ok := matchTOTP(factor.Secret, code, time.Now())
+	if !ok {
+		return nil, authErr.ErrInvalidMFACode
+	}
+
+	codes, hashes, err := newRecoveryCodes(s.opts.MFA.RecoveryCodes)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ConfirmTOTPFactor(ctx, userID, step, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableTOTP removes the factor and its recovery codes. A confirmed factor
+// can only be removed with a current code or a recovery code, so a stolen
+// access token is not enough to turn it off. A pending one is simply
+// dropped.
+func (s *Service) DisableTOTP(ctx context.Context, userID, code string) error {
+	factor, err := s.totpFactor(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if factor.ConfirmedAt != nil {
+		if err := s.checkCode(ctx, factor, code); err != nil {
+			return err
+		}
+	}
+
+	return s.repo.DeleteTOTPFactor(ctx, userID)
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of the user. It
+// needs a code from the authenticator app.
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	factor, err := s.totpFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if factor.ConfirmedAt == nil {
+		return nil, authErr.ErrMFANotEnrolled
+	}
+	if !looksLikeTOTP(code) {
+		return nil, authErr.ErrInvalidMFACode
+	}
+	if err := s.checkCode(ctx, factor, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes(s.opts.MFA.RecoveryCodes)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// LoginMFA finishes a sign-in that Login answered with a challenge. code is
+// either a code from the authenticator app or a recovery code.
+func (s *Service) LoginMFA(ctx context.Context, mfaToken, code string, client model.Client) (*model.TokenPair, error) {
+	if mfaToken == "" {
+		return nil, authErr.ErrInvalidMFAToken
+	}
+	tokenHash := hashToken(mfaToken)
+
+	userID, err := s.mfa.Challenge(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	factor, err := s.totpFactor(ctx, userID)
+	if err != nil {
+		// The factor was removed after the password check, so the
+		// challenge no longer means anything.
+		if errors.Is(err, authErr.ErrMFANotEnrolled) {
+			return nil, authErr.ErrInvalidMFAToken
+		}
+		return nil, err
+	}
+	if factor.ConfirmedAt == nil {
+		return nil, authErr.ErrInvalidMFAToken
+	}
+
+	if err := s.checkCode(ctx, factor, code); err != nil {
+		return nil, err
+	}
+
+	redeemed, err := s.mfa.DeleteChallenge(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	if !redeemed {
+		return nil, authErr.ErrInvalidMFAToken
+	}
+
+	creds, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, authErr.ErrUserNotFound) {
+			return nil, authErr.ErrInvalidMFAToken
+		}
+		return nil, err
+	}
+
+	return s.startSession(ctx, creds, client, amrMFA)
+}
+
+// challenge lets userID, who just passed the password check, finish signing
+// in with a code.
+func (s *Service) challenge(ctx context.Context, userID string) (*model.MFAChallenge, error) {
+	token, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(s.opts.MFA.ChallengeTTL)
+	if err := s.mfa.SaveChallenge(ctx, hashToken(token), userID, s.opts.MFA.ChallengeTTL); err != nil {
+		return nil, err
+	}
+
+	return &model.MFAChallenge{Required: true, Token: token, ExpiresAt: expiresAt}, nil
+}
+
+// totpFactor loads the user's factor with its secret unsealed.
+func (s *Service) totpFactor(ctx context.Context, userID string) (*model.TOTPFactor, error) {
+	factor, err := s.repo.GetTOTPFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	factor.Secret, err = s.sealer.Open(factor.Secret, userID)
+	if err != nil {
+		return nil, fmt.Errorf("Service.totpFactor: %w", err)
+	}
+	return factor, nil
+}
+
+func (s *Service) totpEnabled(ctx context.Context, userID string) (bool, error) {
+	factor, err := s.totpFactor(ctx, userID)
+	if err != nil {
+		if errors.Is(err, authErr.ErrMFANotEnrolled) {
+			return false, nil
+		}
+		return false, err
+	}
+	return factor.ConfirmedAt != nil, nil
+}
+
+// checkCode accepts a TOTP code or a recovery code for a confirmed factor.
+// Either works only once.
+func (s *Service) checkCode(ctx context.Context, factor *model.TOTPFactor, code string) error {
+	if err := s.allowAttempt(ctx, factor.UserID); err != nil {
+		return err
+	}
+
+	if looksLikeTOTP(code) {
+		step, ok := matchTOTP(factor.Secret, code, time.Now())
+		if !ok {
+			return authErr.ErrInvalidMFACode
+		}
+		used, err := s.repo.UseTOTPStep(ctx, factor.UserID, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return authErr.ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	code = normalizeRecoveryCode(code)
+	if code == "" {
+		return authErr.ErrInvalidMFACode
+	}
+	used, err := s.repo.UseRecoveryCode(ctx, factor.UserID, hashToken(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return authErr.ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (s *Service) allowAttempt(ctx context.Context, userID string) error {
+	ok, retryAfter, err := s.mfa.AllowAttempt(ctx, userID, s.opts.MFA.AttemptLimit, s.opts.MFA.AttemptWindow)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &authErr.Throttled{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+func looksLikeTOTP(code string) bool {
+	code = strings.TrimSpace(code)
+	if len(code) != int(totpOpts.Digits) {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// matchTOTP returns the time step code belongs to, if it is valid for the
+// current step or one next to it.
+func matchTOTP(secret, code string, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if !looksLikeTOTP(code) {
+		return 0, false
+	}
+
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		want, err := totp.GenerateCodeCustom(secret, time.Unix(step*totpPeriod, 0), totpOpts)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// newRecoveryCodes returns n codes formatted for reading, like
+// "abcd-efgh-ijkl-mnop", and the hashes to store.
+func newRecoveryCodes(n int) ([]string, []string, error) {
+	codes := make([]string, 0, n)
+	hashes := make([]string, 0, n)
+	for range n {
+		b := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		raw := strings.ToLower(recoveryEncoding.EncodeToString(b))
+
+		groups := make([]string, 0, len(raw)/4)
+		for i := 0; i < len(raw); i += 4 {
+			groups = append(groups, raw[i:i+4])
+		}
+		codes = append(codes, strings.Join(groups, "-"))
+		hashes = append(hashes, hashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode drops the separators and case a user may or may not
+// type.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
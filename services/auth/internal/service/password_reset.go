@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/go-market/pkg/authz"
+	authErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/pkg/mailer"
+	"github.com/go-market/services/auth/internal/model"
+	authRepo "github.com/go-market/services/auth/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type PasswordResetOptions struct {
+	TokenTTL time.Duration
+	// LinkURL is the page the email links to; the token is appended as the
+	// "token" query parameter.
+	LinkURL string
+	// EmailLimit reset mails go to one address per EmailWindow; further
+	// requests are dropped silently. IPLimit requests per IPWindow are
+	// accepted from one client; further ones are refused.
+	EmailLimit  int
+	EmailWindow time.Duration
+	IPLimit     int
+	IPWindow    time.Duration
+}
+
+const (
+	forgotEmailKeyPrefix = "forgot:email:"
+	forgotIPKeyPrefix    = "forgot:ip:"
+)
+
+// PasswordReset lets users who forgot their password set a new one through
+// a single-use link sent to their email address.
+//
+// Mail goes through m as is; pass a mailer.Queue so requests do not wait for
+// delivery and take the same time whether or not the address is known.
+type PasswordReset struct {
+	repo   authRepo.Repository
+	denied authz.DenyList
+	limits authRepo.RateLimiter
+	mailer mailer.Mailer
+	log    *slog.Logger
+	opts   PasswordResetOptions
+}
+
+func NewPasswordReset(repo authRepo.Repository, denied authz.DenyList, limits authRepo.RateLimiter, m mailer.Mailer, log *slog.Logger, opts PasswordResetOptions) *PasswordReset {
+	return &PasswordReset{
+		repo:   repo,
+		denied: denied,
+		limits: limits,
+		mailer: m,
+		log:    log.With(slog.String("component", "service.PasswordReset")),
+		opts:   opts,
+	}
+}
+
+// Forgot mails a reset link to email if an account uses it. Unknown
+// addresses are not reported, so the endpoint cannot be used to find out
+// who has an account. Only a client over its request limit gets an error
+// that callers should show, a *authErr.Throttled.
+func (p *PasswordReset) Forgot(ctx context.Context, email, ip string) error {
+	const op = "PasswordReset.Forgot"
+
+	ok, retryAfter, err := p.limits.Allow(ctx, forgotIPKeyPrefix+ip, p.opts.IPLimit, p.opts.IPWindow)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &authErr.Throttled{RetryAfter: retryAfter}
+	}
+
+	// Counted whether or not the address has an account, so being throttled
+	// says nothing about it either.
+	email = normalizeEmail(email)
+	ok, _, err = p.limits.Allow(ctx, forgotEmailKeyPrefix+hashToken(email), p.opts.EmailLimit, p.opts.EmailWindow)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	creds, err := p.repo.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, authErr.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	token, err := randomString(32)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = p.repo.SavePasswordResetToken(ctx, model.PasswordResetToken{
+		UserID:    creds.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(p.opts.TokenTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	link, err := url.Parse(p.opts.LinkURL)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	q := link.Query()
+	q.Set("token", token)
+	link.RawQuery = q.Encode()
+
+	err = p.mailer.Send(ctx, mailer.Message{
+		To:      creds.Email,
+		Subject: "Reset your password",
+		Text: fmt.Sprintf("Someone asked to reset the password of your account.\n\n"+
+			"Choose a new password by opening this link:\n\n%s\n\n"+
+			"The link expires in %s and works once. If it was not you, ignore this email.\n",
+			link, p.opts.TokenTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Reset redeems token and sets password. Every session of the user is
+// signed out, so whoever knew the old password loses access.
+func (p *PasswordReset) Reset(ctx context.Context, token, password string) error {
+	if token == "" {
+		return authErr.ErrInvalidResetToken
+	}
+	if len(password) < minPasswordLength {
+		return authErr.ErrInvalidPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	creds, sessions, err := p.repo.ResetPassword(ctx, hashToken(token), string(hash))
+	if err != nil {
+		return err
+	}
+	if err := denyAccess(ctx, p.denied, sessions...); err != nil {
+		return err
+	}
+
+	// The password has changed whatever happens to the notification, so a
+	// mail failure is logged rather than reported to the caller.
+	err = p.mailer.Send(ctx, mailer.Message{
+		To:      creds.Email,
+		Subject: "Your password was changed",
+		Text: "The password of your account was just reset and all sessions were signed out.\n\n" +
+			"If you did not do this, reset your password again right away and contact support.\n",
+	})
+	if err != nil {
+		p.log.Error("failed to send password changed notification",
+			slog.String("user_id", creds.ID), slog.String("error", err.Error()))
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	authErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/pkg/mailer"
+	"github.com/go-market/services/auth/internal/model"
+	authRepo "github.com/go-market/services/auth/internal/repository"
+)
+
+// fakeResetRepo knows one account and records the reset tokens saved.
+type fakeResetRepo struct {
+	authRepo.Repository
+	creds  *model.Credentials
+	err    error
+	tokens []model.PasswordResetToken
+}
+
+func (f *fakeResetRepo) GetByEmail(_ context.Context, email string) (*model.Credentials, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.creds == nil || f.creds.Email != email {
+		return nil, authErr.ErrUserNotFound
+	}
+	return f.creds, nil
+}
+
+func (f *fakeResetRepo) SavePasswordResetToken(_ context.Context, token model.PasswordResetToken) error {
+	f.tokens = append(f.tokens, token)
+	return nil
+}
+
+// fakeLimiter allows limit requests per key and ignores windows.
+type fakeLimiter struct {
+	counts map[string]int
+}
+
+func (f *fakeLimiter) Allow(_ context.Context, key string, limit int, _ time.Duration) (bool, time.Duration, error) {
+	if f.counts == nil {
+		f.counts = make(map[string]int)
+	}
+	f.counts[key]++
+	if f.counts[key] > limit {
+		return false, time.Minute, nil
+	}
+	return true, 0, nil
+}
+
+type fakeMailer struct {
+	sent []mailer.Message
+}
+
+func (f *fakeMailer) Send(_ context.Context, msg mailer.Message) error {
+	f.sent = append(f.sent, msg)
+	return nil
+}
+
+var errDB = errors.New("connection reset")
+
+type forgotCall struct {
+	email string
+	ip    string
+}
+
+func TestForgot(t *testing.T) {
+	alice := &model.Credentials{ID: "u1", Email: "alice@example.com"}
+
+	tests := []struct {
+		name        string
+		repoErr     error
+		calls       []forgotCall
+		wantErr     error
+		wantTokens  int
+		wantMails   int
+		wantRetryIn bool
+	}{
+		{
+			name:       "known address gets a link",
+			calls:      []forgotCall{{" Alice@Example.com ", "10.0.0.1"}},
+			wantTokens: 1,
+			wantMails:  1,
+		},
+		{
+			name:  "unknown address is not reported",
+			calls: []forgotCall{{"bob@example.com", "10.0.0.1"}},
+		},
+		{
+			name: "mail to one address is limited silently",
+			calls: []forgotCall{
+				{"alice@example.com", "10.0.0.1"},
+				{"alice@example.com", "10.0.0.2"},
+				{"alice@example.com", "10.0.0.3"},
+			},
+			wantTokens: 2,
+			wantMails:  2,
+		},
+		{
+			name: "one client is throttled across addresses",
+			calls: []forgotCall{
+				{"bob@example.com", "10.0.0.1"},
+				{"carol@example.com", "10.0.0.1"},
+				{"dave@example.com", "10.0.0.1"},
+				{"erin@example.com", "10.0.0.1"},
+			},
+			wantErr:     authErr.ErrTooManyRequests,
+			wantRetryIn: true,
+		},
+		{
+			name:    "lookup failure is returned for logging",
+			repoErr: errDB,
+			calls:   []forgotCall{{"alice@example.com", "10.0.0.1"}},
+			wantErr: errDB,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeResetRepo{creds: alice, err: tt.repoErr}
+			mail := &fakeMailer{}
+			p := NewPasswordReset(repo, nil, &fakeLimiter{}, mail, slog.New(slog.NewTextHandler(io.Discard, nil)), PasswordResetOptions{
+				TokenTTL:   time.Hour,
+				LinkURL:    "http://localhost:3000/reset-password",
+				EmailLimit: 2,
+				IPLimit:    3,
+			})
+
+			var err error
+			for _, c := range tt.calls {
+				err = p.Forgot(context.Background(), c.email, c.ip)
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Forgot() error = %v, want %v", err, tt.wantErr)
+			}
+			if _, ok := authErr.RetryAfter(err); ok != tt.wantRetryIn {
+				t.Errorf("Forgot() throttled = %v, want %v", ok, tt.wantRetryIn)
+			}
+
+			if len(repo.tokens) != tt.wantTokens {
+				t.Errorf("tokens saved = %d, want %d", len(repo.tokens), tt.wantTokens)
+			}
+			if len(mail.sent) != tt.wantMails {
+				t.Errorf("mails sent = %d, want %d", len(mail.sent), tt.wantMails)
+			}
+			for i, token := range repo.tokens {
+				if token.UserID != alice.ID || len(token.TokenHash) != 64 {
+					t.Errorf("token %d = %+v, want a hash for %s", i, token, alice.ID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+const testSecretsKey = "bG9jYWwtZGV2LXNlY3JldHMta2V5LTMyLWJ5dGVzISE="
+
+func TestSealer(t *testing.T) {
+	s, err := NewSealer(testSecretsKey)
+	if err != nil {
+		t.Fatalf("NewSealer() error = %v", err)
+	}
+
+	sealed, err := s.Seal("JBSWY3DPEHPK3PXP", "u1")
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if strings.Contains(sealed, "JBSWY3DPEHPK3PXP") {
+		t.Fatalf("Seal() = %q, stores the plaintext", sealed)
+	}
+	if again, _ := s.Seal("JBSWY3DPEHPK3PXP", "u1"); again == sealed {
+		t.Error("Seal() is deterministic")
+	}
+
+	other, err := NewSealer(base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		sealer  *Sealer
+		value   string
+		ad      string
+		want    string
+		wantErr bool
+	}{
+		{name: "round trip", sealer: s, value: sealed, ad: "u1", want: "JBSWY3DPEHPK3PXP"},
+		{name: "not sealed", sealer: s, value: "JBSWY3DPEHPK3PXP", ad: "u1", wantErr: true},
+		{name: "moved to another row", sealer: s, value: sealed, ad: "u2", wantErr: true},
+		{name: "other key", sealer: other, value: sealed, ad: "u1", wantErr: true},
+		{name: "tampered", sealer: s, value: sealed[:len(sealed)-4] + "AAAA", ad: "u1", wantErr: true},
+		{name: "truncated", sealer: s, value: sealedPrefix + "AAAA", ad: "u1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.sealer.Open(tt.value, tt.ad)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Open() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Open() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSealerRejectsBadKeys(t *testing.T) {
+	for _, key := range []string{"", "not base64!", base64.StdEncoding.EncodeToString(make([]byte, 16))} {
+		if _, err := NewSealer(key); err == nil {
+			t.Errorf("NewSealer(%q) error = nil", key)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+ALTER TABLE credentials ADD COLUMN IF NOT EXISTS role VARCHAR(50) NOT NULL DEFAULT 'user';
+
+UPDATE credentials SET role = COALESCE(roles[1], 'user');
+
+ALTER TABLE credentials DROP COLUMN IF EXISTS roles;
@@ -0,0 +1,5 @@
+ALTER TABLE credentials ADD COLUMN IF NOT EXISTS roles TEXT[] NOT NULL DEFAULT ARRAY['user']::TEXT[];
+
+UPDATE credentials SET roles = ARRAY[role]::TEXT[];
+
+ALTER TABLE credentials DROP COLUMN IF EXISTS role;
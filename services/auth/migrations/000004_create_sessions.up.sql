@@ -0,0 +1,29 @@
+-- A session is a refresh-token family: every token rotated out of the same
+-- login belongs to it, so reuse of any of them can revoke all of them.
+CREATE TABLE IF NOT EXISTS sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES credentials (id) ON DELETE CASCADE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL DEFAULT '',
+    access_jti TEXT NOT NULL DEFAULT '',
+    access_expires_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (user_id);
+
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS session_id UUID REFERENCES sessions (id) ON DELETE CASCADE;
+
+-- Every live refresh token becomes its own session. Used and expired tokens
+-- keep a NULL session and can no longer be refreshed.
+INSERT INTO sessions (id, user_id, expires_at, created_at, last_used_at)
+SELECT id, user_id, expires_at, created_at, created_at
+FROM refresh_tokens
+WHERE revoked_at IS NULL AND expires_at > NOW();
+
+UPDATE refresh_tokens SET session_id = id WHERE revoked_at IS NULL AND expires_at > NOW();
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_session_id_idx ON refresh_tokens (session_id);
@@ -0,0 +1,13 @@
+-- Keys that sign access tokens. A key is published in the JWKS from
+-- created_at, signs from activates_at until its successor activates, and is
+-- dropped at expires_at, once every token it signed has expired.
+CREATE TABLE IF NOT EXISTS signing_keys (
+    kid TEXT PRIMARY KEY,
+    alg TEXT NOT NULL,
+    private_key TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    activates_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS signing_keys_expires_at_idx ON signing_keys (expires_at);
@@ -0,0 +1,4 @@
+ALTER TABLE sessions DROP COLUMN IF EXISTS amr;
+
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS totp_factors;
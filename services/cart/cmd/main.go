@@ -0,0 +1,62 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/go-market/pkg/logger/handlers/slogpretty"
+	"github.com/go-market/services/cart/internal/app"
+	"github.com/go-market/services/cart/internal/config"
+)
+
+const (
+	envLocal = "local"
+	envProd  = "prod"
+	envDev   = "dev"
+)
+
+func main() {
+	cfg := config.MustLoad()
+	log := setupLogger(cfg.Env)
+
+	log.Info("starting cart service", slog.String("env", cfg.Env))
+
+	application, err := app.NewApp(*cfg, log)
+	if err != nil {
+		log.Error("failed to initialize app", slog.Any("err", err))
+		os.Exit(1)
+	}
+
+	if err := application.Run(); err != nil {
+		log.Error("app stopped with error", slog.Any("err", err))
+		os.Exit(1)
+	}
+}
+
+func setupLogger(env string) *slog.Logger {
+	var log *slog.Logger
+
+	switch env {
+	case envProd:
+		log = slog.New(
+			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}),
+		)
+	case envDev:
+		log = slog.New(
+			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		)
+	default:
+		log = setupPrettyLogger()
+	}
+
+	return log
+}
+
+func setupPrettyLogger() *slog.Logger {
+	opts := slogpretty.PrettyHandlerOptions{
+		SlogOpts: &slog.HandlerOptions{Level: slog.LevelDebug},
+	}
+	handler := opts.NewPrettyHandler(os.Stdout)
+
+	return slog.New(handler)
+}
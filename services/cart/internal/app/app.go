@@ -0,0 +1,113 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-market/pkg/authz"
+	"github.com/go-market/pkg/kafka"
+	pkgRedis "github.com/go-market/pkg/redis"
+	"github.com/go-market/services/cart/internal/client/catalog"
+	"github.com/go-market/services/cart/internal/config"
+	cartHTTP "github.com/go-market/services/cart/internal/derivery/http"
+	cartRepo "github.com/go-market/services/cart/internal/repository/redis"
+	"github.com/go-market/services/cart/internal/service"
+	"github.com/redis/go-redis/v9"
+)
+
+type App struct {
+	server   *http.Server
+	relay    *service.Relay
+	rdb      *redis.Client
+	producer *kafka.Producer
+	log      *slog.Logger
+}
+
+func NewApp(cfg config.Config, log *slog.Logger) (*App, error) {
+	const op = "app.NewApp"
+
+	logger := log.With(slog.String("op", op))
+
+	rdb := pkgRedis.NewClient(cfg.RedisAddr)
+	if err := rdb.Ping(context.Background()).Err(); err != nil {
+		logger.Error("failed to connect redis", slog.Any("err", err))
+		return nil, err
+	}
+
+	repo := cartRepo.New(rdb, cfg.CartTTL)
+	producer := kafka.NewProducer(cfg.Kafka.Brokers)
+	relay := service.NewRelay(repo, producer, log, service.RelayConfig{
+		PollInterval: cfg.Outbox.PollInterval,
+		BatchSize:    cfg.Outbox.BatchSize,
+	})
+	svc := service.New(repo, catalog.New(cfg.CatalogURL))
+
+	cartHandler := cartHTTP.New(log, svc)
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(middleware.Logger)
+	r.Use(middleware.Recoverer)
+
+	r.Route("/api/v1", func(r chi.Router) {
+		cartHTTP.RegisterCartRoutes(r, cartHandler, authz.NewRemoteVerifier(cfg.Auth), authz.NewRedisDenyList(rdb))
+	})
+
+	server := &http.Server{
+		Addr:         cfg.HTTPAddr.Address,
+		Handler:      r,
+		ReadTimeout:  cfg.HTTPAddr.Timeout,
+		WriteTimeout: cfg.HTTPAddr.Timeout,
+		IdleTimeout:  cfg.HTTPAddr.IdleTimeout,
+	}
+
+	return &App{server: server, relay: relay, rdb: rdb, producer: producer, log: log}, nil
+}
+
+func (a *App) Run() error {
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.relay.Run(bgCtx)
+	}()
+
+	go func() {
+		if err := a.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			a.log.Error("listen failed", slog.Any("err", err))
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	a.log.Info("shutting down server")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := a.server.Shutdown(ctx)
+
+	stopBackground()
+	wg.Wait()
+
+	if err := a.producer.Close(); err != nil {
+		a.log.Error("failed to close kafka producer", slog.Any("err", err))
+	}
+	if err := a.rdb.Close(); err != nil {
+		a.log.Error("failed to close redis client", slog.Any("err", err))
+	}
+
+	return err
+}
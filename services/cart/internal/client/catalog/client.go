@@ -0,0 +1,68 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var ErrProductNotFound = errors.New("product not found in catalog")
+
+type Product struct {
+	ID    string `json:"id"`
+	SKU   string `json:"sku"`
+	Name  string `json:"name"`
+	Price struct {
+		Amount   int64  `json:"amount"`
+		Currency string `json:"currency"`
+	} `json:"price"`
+}
+
+// Client reads products from the catalog service's public API.
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+func New(baseURL string) *Client {
+	return &Client{
+		baseURL: baseURL,
+		http:    &http.Client{Timeout: 3 * time.Second},
+	}
+}
+
+func (c *Client) GetProduct(ctx context.Context, id string) (*Product, error) {
+	const op = "catalog.Client.GetProduct"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		c.baseURL+"/api/v1/products/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrProductNotFound
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%s: unexpected status %d", op, resp.StatusCode)
+	}
+
+	var body struct {
+		Data Product `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("%s: decode response: %w", op, err)
+	}
+
+	return &body.Data, nil
+}
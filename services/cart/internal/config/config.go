@@ -0,0 +1,55 @@
+package config
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/go-market/pkg/authz"
+	"github.com/ilyakaznacheev/cleanenv"
+)
+
+type Config struct {
+	Env        string        `yaml:"env" env-default:"local"`
+	RedisAddr  string        `yaml:"redis_addr" env-default:"localhost:6379"`
+	HTTPAddr   HTTPServer    `yaml:"http_addr"`
+	Auth       authz.Config  `yaml:"auth"`
+	CartTTL    time.Duration `yaml:"cart_ttl" env-default:"168h"`
+	CatalogURL string        `yaml:"catalog_url" env-default:"http://localhost:8082"`
+	Kafka      Kafka         `yaml:"kafka"`
+	Outbox     Outbox        `yaml:"outbox"`
+}
+
+type HTTPServer struct {
+	Address     string        `yaml:"address" env-default:"localhost:8084"`
+	Timeout     time.Duration `yaml:"timeout" env-default:"4s"`
+	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
+}
+
+type Kafka struct {
+	Brokers []string `yaml:"brokers" env-default:"localhost:9092"`
+}
+
+// Outbox configures the relay that publishes checkout commands.
+type Outbox struct {
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"1s"`
+	BatchSize    int           `yaml:"batch_size" env-default:"100"`
+}
+
+func MustLoad() *Config {
+	configPath, ok := os.LookupEnv("CONFIG_PATH")
+	if !ok || configPath == "" {
+		configPath = "./services/cart/config/local.yaml"
+	}
+
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		log.Fatalf("config file not found: %s", configPath)
+	}
+
+	cfg := Config{}
+
+	if err := cleanenv.ReadConfig(configPath, &cfg); err != nil {
+		log.Fatal(err)
+	}
+	return &cfg
+}
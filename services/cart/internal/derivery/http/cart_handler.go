@@ -0,0 +1,236 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/go-market/pkg/authz"
+	cartErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/services/cart/internal/model"
+	"github.com/go-market/services/cart/internal/service"
+	"github.com/google/uuid"
+)
+
+// cartIDHeader carries the anonymous cart id between the client and the
+// service until the user signs in and merges it.
+const (
+	cartIDHeader         = "X-Cart-ID"
+	idempotencyKeyHeader = "Idempotency-Key"
+)
+
+type CartHandler struct {
+	log *slog.Logger
+	svc *service.Service
+}
+
+func New(log *slog.Logger, svc *service.Service) *CartHandler {
+	return &CartHandler{
+		log: log,
+		svc: svc,
+	}
+}
+
+type AddItemRequest struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+}
+
+type UpdateItemRequest struct {
+	Quantity int `json:"quantity"`
+}
+
+type CheckoutConflictResponse struct {
+	Error   string              `json:"error"`
+	Changes []model.PriceChange `json:"changes"`
+}
+
+// owner resolves the cart for the request: the signed-in user's cart wins
+// over an anonymous X-Cart-ID.
+func owner(r *http.Request) (model.Owner, error) {
+	if p, ok := authz.FromContext(r.Context()); ok {
+		return model.Owner{UserID: p.UserID}, nil
+	}
+
+	id := r.Header.Get(cartIDHeader)
+	if id == "" {
+		return model.Owner{}, cartErr.ErrCartOwnerRequired
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return model.Owner{}, cartErr.ErrCartOwnerRequired
+	}
+
+	return model.Owner{AnonymousID: id}, nil
+}
+
+func (h *CartHandler) Get(w http.ResponseWriter, r *http.Request) {
+	const op = "CartHandler.Get"
+	log := h.log.With(slog.String("op", op))
+
+	o, err := owner(r)
+	if err != nil {
+		render.JSON(w, r, SuccessResponse{Data: model.Cart{Items: []model.Item{}}})
+		return
+	}
+
+	cart, err := h.svc.Get(r.Context(), o)
+	if err != nil {
+		log.Error("failed to get cart", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to get cart")
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Data: cart})
+}
+
+func (h *CartHandler) AddItem(w http.ResponseWriter, r *http.Request) {
+	const op = "CartHandler.AddItem"
+	log := h.log.With(slog.String("op", op))
+
+	var req AddItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("failed to decode request", slog.String("error", err.Error()))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	o, err := owner(r)
+	if err != nil {
+		// First item of an anonymous visitor: start a new cart.
+		o = model.Owner{AnonymousID: service.NewAnonymousID()}
+	}
+	if o.AnonymousID != "" {
+		w.Header().Set(cartIDHeader, o.AnonymousID)
+	}
+
+	cart, err := h.svc.AddItem(r.Context(), o, req.ProductID, req.Quantity)
+	if err != nil {
+		log.Error("failed to add item", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to add item")
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Data: cart})
+}
+
+func (h *CartHandler) UpdateItem(w http.ResponseWriter, r *http.Request) {
+	const op = "CartHandler.UpdateItem"
+	log := h.log.With(slog.String("op", op))
+
+	var req UpdateItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("failed to decode request", slog.String("error", err.Error()))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	o, err := owner(r)
+	if err != nil {
+		renderError(w, r, err, "failed to update item")
+		return
+	}
+
+	cart, err := h.svc.SetQuantity(r.Context(), o, chi.URLParam(r, "productID"), req.Quantity)
+	if err != nil {
+		log.Error("failed to update item", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to update item")
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Data: cart})
+}
+
+func (h *CartHandler) RemoveItem(w http.ResponseWriter, r *http.Request) {
+	const op = "CartHandler.RemoveItem"
+	log := h.log.With(slog.String("op", op))
+
+	o, err := owner(r)
+	if err != nil {
+		renderError(w, r, err, "failed to remove item")
+		return
+	}
+
+	cart, err := h.svc.RemoveItem(r.Context(), o, chi.URLParam(r, "productID"))
+	if err != nil {
+		log.Error("failed to remove item", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to remove item")
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Data: cart})
+}
+
+func (h *CartHandler) Clear(w http.ResponseWriter, r *http.Request) {
+	const op = "CartHandler.Clear"
+	log := h.log.With(slog.String("op", op))
+
+	o, err := owner(r)
+	if err != nil {
+		renderError(w, r, err, "failed to clear cart")
+		return
+	}
+
+	if err := h.svc.Clear(r.Context(), o); err != nil {
+		log.Error("failed to clear cart", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to clear cart")
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Message: "cart cleared"})
+}
+
+// Merge moves the anonymous cart named by X-Cart-ID into the signed-in
+// user's cart. Clients call it right after login.
+func (h *CartHandler) Merge(w http.ResponseWriter, r *http.Request) {
+	const op = "CartHandler.Merge"
+	log := h.log.With(slog.String("op", op))
+
+	principal, _ := authz.FromContext(r.Context())
+
+	anonymousID := r.Header.Get(cartIDHeader)
+	if anonymousID != "" {
+		if _, err := uuid.Parse(anonymousID); err != nil {
+			renderError(w, r, cartErr.ErrCartOwnerRequired, "failed to merge cart")
+			return
+		}
+	}
+
+	cart, err := h.svc.Merge(r.Context(), principal.UserID, anonymousID)
+	if err != nil {
+		log.Error("failed to merge cart", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to merge cart")
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Data: cart})
+}
+
+// Checkout queues an order-creation command for the signed-in user's cart
+// and answers 202 with the command. Retrying with the same Idempotency-Key
+// returns the same command.
+func (h *CartHandler) Checkout(w http.ResponseWriter, r *http.Request) {
+	const op = "CartHandler.Checkout"
+	log := h.log.With(slog.String("op", op))
+
+	principal, _ := authz.FromContext(r.Context())
+
+	cmd, changes, err := h.svc.Checkout(r.Context(), principal.UserID, r.Header.Get(idempotencyKeyHeader))
+	if err != nil {
+		log.Error("failed to checkout", slog.String("error", err.Error()))
+		if errors.Is(err, cartErr.ErrCartChanged) {
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, CheckoutConflictResponse{Error: err.Error(), Changes: changes})
+			return
+		}
+		renderError(w, r, err, "failed to checkout")
+		return
+	}
+
+	render.Status(r, http.StatusAccepted)
+	render.JSON(w, r, SuccessResponse{Data: cmd})
+}
@@ -0,0 +1,27 @@
+package http
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-market/pkg/authz"
+)
+
+func RegisterCartRoutes(r chi.Router, h *CartHandler, verifier *authz.Verifier, denied authz.DenyList) {
+	r.Route("/cart", func(r chi.Router) {
+		r.Group(func(r chi.Router) {
+			r.Use(authz.OptionalAuthenticate(verifier, authz.WithDenyList(denied)))
+
+			r.Get("/", h.Get)
+			r.Delete("/", h.Clear)
+			r.Post("/items", h.AddItem)
+			r.Put("/items/{productID}", h.UpdateItem)
+			r.Delete("/items/{productID}", h.RemoveItem)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(authz.Authenticate(verifier, authz.WithDenyList(denied)))
+
+			r.Post("/merge", h.Merge)
+			r.Post("/checkout", h.Checkout)
+		})
+	})
+}
@@ -0,0 +1,36 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/render"
+	cartErr "github.com/go-market/pkg/errs"
+)
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type SuccessResponse struct {
+	Data    interface{} `json:"data,omitempty"`
+	Message string      `json:"message,omitempty"`
+}
+
+// renderError maps cart errors to status codes. Unknown errors are reported
+// as fallback so internals never reach the client.
+func renderError(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+	status := http.StatusInternalServerError
+	msg := fallback
+
+	switch {
+	case errors.Is(err, cartErr.ErrInvalidCartItem), errors.Is(err, cartErr.ErrCartOwnerRequired),
+		errors.Is(err, cartErr.ErrCartEmpty):
+		status, msg = http.StatusBadRequest, err.Error()
+	case errors.Is(err, cartErr.ErrCartItemNotFound):
+		status, msg = http.StatusNotFound, err.Error()
+	}
+
+	render.Status(r, status)
+	render.JSON(w, r, ErrorResponse{Error: msg})
+}
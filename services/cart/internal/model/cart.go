@@ -0,0 +1,57 @@
+package model
+
+import "time"
+
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// Item is a cart line. SKU, Name and UnitPrice are a snapshot taken from the
+// catalog when the item was added and are refreshed on checkout.
+type Item struct {
+	ProductID string    `json:"product_id"`
+	SKU       string    `json:"sku"`
+	Name      string    `json:"name"`
+	Quantity  int       `json:"quantity"`
+	UnitPrice Money     `json:"unit_price"`
+	AddedAt   time.Time `json:"added_at"`
+}
+
+type Cart struct {
+	Owner Owner  `json:"-"`
+	Items []Item `json:"items"`
+	Total Money  `json:"total"`
+}
+
+// Owner identifies a cart: either a signed-in user or an anonymous cart id
+// handed out to the client.
+type Owner struct {
+	UserID      string
+	AnonymousID string
+}
+
+func (o Owner) Key() string {
+	if o.UserID != "" {
+		return "cart:user:" + o.UserID
+	}
+	return "cart:anon:" + o.AnonymousID
+}
+
+// PriceChange reports an item whose catalog data no longer matches the cart.
+type PriceChange struct {
+	ProductID string `json:"product_id"`
+	OldPrice  Money  `json:"old_price"`
+	NewPrice  *Money `json:"new_price,omitempty"`
+	Removed   bool   `json:"removed"`
+}
+
+// OutboxMessage is a Kafka message queued in Redis together with the cart
+// change that produced it. ID is the stream entry id, set when it is read
+// back for publishing.
+type OutboxMessage struct {
+	ID      string
+	Topic   string
+	Key     string
+	Payload []byte
+}
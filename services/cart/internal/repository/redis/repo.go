@@ -0,0 +1,276 @@
+package redis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	cartErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/services/cart/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	maxRetries = 5
+
+	// outboxKey is the stream checkout commands wait in until they are
+	// published to Kafka.
+	outboxKey = "cart:outbox"
+)
+
+var errConflict = errors.New("cart was modified concurrently")
+
+// RedisRepo stores each cart as a hash keyed by product id, with the JSON
+// encoded line as the value. Every write refreshes the cart TTL.
+type RedisRepo struct {
+	rdb *redis.Client
+	ttl time.Duration
+}
+
+func New(rdb *redis.Client, ttl time.Duration) *RedisRepo {
+	return &RedisRepo{rdb: rdb, ttl: ttl}
+}
+
+func (r *RedisRepo) Get(ctx context.Context, owner model.Owner) ([]model.Item, error) {
+	const op = "repo.Get"
+
+	items, err := readItems(ctx, r.rdb, owner.Key())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return items, nil
+}
+
+func (r *RedisRepo) Update(ctx context.Context, owner model.Owner, productID string, fn func(*model.Item) (*model.Item, error)) error {
+	const op = "repo.Update"
+
+	key := owner.Key()
+	txf := func(tx *redis.Tx) error {
+		var current *model.Item
+		raw, err := tx.HGet(ctx, key, productID).Bytes()
+		switch {
+		case errors.Is(err, redis.Nil):
+		case err != nil:
+			return err
+		default:
+			current = &model.Item{}
+			if err := json.Unmarshal(raw, current); err != nil {
+				return err
+			}
+		}
+
+		next, err := fn(current)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if next == nil {
+				pipe.HDel(ctx, key, productID)
+				return nil
+			}
+
+			data, err := json.Marshal(next)
+			if err != nil {
+				return err
+			}
+			pipe.HSet(ctx, key, productID, data)
+			pipe.Expire(ctx, key, r.ttl)
+			return nil
+		})
+		return err
+	}
+
+	if err := r.watch(ctx, txf, key); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *RedisRepo) Clear(ctx context.Context, owner model.Owner) error {
+	const op = "repo.Clear"
+
+	if err := r.rdb.Del(ctx, owner.Key()).Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *RedisRepo) Merge(ctx context.Context, from, to model.Owner, combine func(existing, incoming model.Item) model.Item) error {
+	const op = "repo.Merge"
+
+	fromKey, toKey := from.Key(), to.Key()
+	txf := func(tx *redis.Tx) error {
+		incoming, err := readItems(ctx, tx, fromKey)
+		if err != nil {
+			return err
+		}
+		if len(incoming) == 0 {
+			return nil
+		}
+
+		existing, err := readItems(ctx, tx, toKey)
+		if err != nil {
+			return err
+		}
+		byProduct := make(map[string]model.Item, len(existing))
+		for _, item := range existing {
+			byProduct[item.ProductID] = item
+		}
+
+		values := make([]interface{}, 0, 2*len(incoming))
+		for _, item := range incoming {
+			if cur, ok := byProduct[item.ProductID]; ok {
+				item = combine(cur, item)
+			}
+			data, err := json.Marshal(item)
+			if err != nil {
+				return err
+			}
+			values = append(values, item.ProductID, data)
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, toKey, values...)
+			pipe.Expire(ctx, toKey, r.ttl)
+			pipe.Del(ctx, fromKey)
+			return nil
+		})
+		return err
+	}
+
+	if err := r.watch(ctx, txf, fromKey, toKey); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *RedisRepo) Checkout(ctx context.Context, owner model.Owner, items []model.Item, checkoutID string, msg model.OutboxMessage) error {
+	const op = "repo.Checkout"
+
+	key := owner.Key()
+	want, err := json.Marshal(items)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	txf := func(tx *redis.Tx) error {
+		current, err := readItems(ctx, tx, key)
+		if err != nil {
+			return err
+		}
+		got, err := json.Marshal(current)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(got, want) {
+			return cartErr.ErrCartChanged
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, key)
+			pipe.XAdd(ctx, &redis.XAddArgs{
+				Stream: outboxKey,
+				Values: map[string]interface{}{"topic": msg.Topic, "key": msg.Key, "payload": msg.Payload},
+			})
+			pipe.Set(ctx, checkoutKey(owner, checkoutID), msg.Payload, r.ttl)
+			return nil
+		})
+		return err
+	}
+
+	if err := r.watch(ctx, txf, key); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *RedisRepo) GetCheckout(ctx context.Context, owner model.Owner, checkoutID string) ([]byte, error) {
+	const op = "repo.GetCheckout"
+
+	payload, err := r.rdb.Get(ctx, checkoutKey(owner, checkoutID)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return payload, nil
+}
+
+func (r *RedisRepo) PendingOutbox(ctx context.Context, limit int) ([]model.OutboxMessage, error) {
+	const op = "repo.PendingOutbox"
+
+	entries, err := r.rdb.XRangeN(ctx, outboxKey, "-", "+", int64(limit)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	msgs := make([]model.OutboxMessage, 0, len(entries))
+	for _, e := range entries {
+		topic, _ := e.Values["topic"].(string)
+		msgKey, _ := e.Values["key"].(string)
+		payload, _ := e.Values["payload"].(string)
+		msgs = append(msgs, model.OutboxMessage{ID: e.ID, Topic: topic, Key: msgKey, Payload: []byte(payload)})
+	}
+
+	return msgs, nil
+}
+
+func (r *RedisRepo) DeleteOutbox(ctx context.Context, ids ...string) error {
+	const op = "repo.DeleteOutbox"
+
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := r.rdb.XDel(ctx, outboxKey, ids...).Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func checkoutKey(owner model.Owner, checkoutID string) string {
+	return owner.Key() + ":checkout:" + checkoutID
+}
+
+// watch runs txf under WATCH and retries when another client modified one of
+// the keys before EXEC.
+func (r *RedisRepo) watch(ctx context.Context, txf func(*redis.Tx) error, keys ...string) error {
+	for i := 0; i < maxRetries; i++ {
+		err := r.rdb.Watch(ctx, txf, keys...)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return errConflict
+}
+
+func readItems(ctx context.Context, c redis.Cmdable, key string) ([]model.Item, error) {
+	raw, err := c.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]model.Item, 0, len(raw))
+	for _, v := range raw {
+		var item model.Item
+		if err := json.Unmarshal([]byte(v), &item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].AddedAt.Before(items[j].AddedAt) })
+
+	return items, nil
+}
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/go-market/services/cart/internal/model"
+)
+
+type Repository interface {
+	Get(ctx context.Context, owner model.Owner) ([]model.Item, error)
+	// Update atomically replaces one line with the result of fn, which gets
+	// the current line (nil if absent). Returning nil removes the line.
+	Update(ctx context.Context, owner model.Owner, productID string, fn func(current *model.Item) (*model.Item, error)) error
+	Clear(ctx context.Context, owner model.Owner) error
+	// Merge moves every line from `from` into `to`, using combine for
+	// products present in both, and deletes `from`.
+	Merge(ctx context.Context, from, to model.Owner, combine func(existing, incoming model.Item) model.Item) error
+	// Checkout clears the cart and queues msg in the outbox in one
+	// transaction, and remembers msg under checkoutID for replays. It fails
+	// with ErrCartChanged if the cart no longer holds exactly items.
+	Checkout(ctx context.Context, owner model.Owner, items []model.Item, checkoutID string, msg model.OutboxMessage) error
+	// GetCheckout returns the payload of an earlier checkout, or nil if
+	// there was none.
+	GetCheckout(ctx context.Context, owner model.Owner, checkoutID string) ([]byte, error)
+	// PendingOutbox returns up to limit queued messages, oldest first.
+	PendingOutbox(ctx context.Context, limit int) ([]model.OutboxMessage, error)
+	DeleteOutbox(ctx context.Context, ids ...string) error
+}
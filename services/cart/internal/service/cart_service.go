@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	domain "github.com/go-market/pkg/domain/model"
+	cartErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/services/cart/internal/client/catalog"
+	"github.com/go-market/services/cart/internal/model"
+	cartRepo "github.com/go-market/services/cart/internal/repository"
+	"github.com/google/uuid"
+)
+
+const (
+	maxQuantity  = 99
+	maxKeyLength = 255
+)
+
+// commandNamespace scopes the name-based UUIDs used as command ids.
+var commandNamespace = uuid.MustParse("6f1c0b7e-3d2a-4f5b-9a8e-2c4d6e8f0a1b")
+
+type Catalog interface {
+	GetProduct(ctx context.Context, id string) (*catalog.Product, error)
+}
+
+type Service struct {
+	repo    cartRepo.Repository
+	catalog Catalog
+}
+
+func New(repo cartRepo.Repository, catalog Catalog) *Service {
+	return &Service{
+		repo:    repo,
+		catalog: catalog,
+	}
+}
+
+// NewAnonymousID hands out an id for a cart that is not bound to a user yet.
+func NewAnonymousID() string {
+	return uuid.NewString()
+}
+
+func (s *Service) Get(ctx context.Context, owner model.Owner) (*model.Cart, error) {
+	items, err := s.repo.Get(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return newCart(owner, items), nil
+}
+
+// AddItem adds quantity of a product, snapshotting its catalog name and price.
+func (s *Service) AddItem(ctx context.Context, owner model.Owner, productID string, quantity int) (*model.Cart, error) {
+	if productID == "" || quantity <= 0 || quantity > maxQuantity {
+		return nil, fmt.Errorf("%w: quantity must be 1..%d", cartErr.ErrInvalidCartItem, maxQuantity)
+	}
+
+	product, err := s.catalog.GetProduct(ctx, productID)
+	if err != nil {
+		if errors.Is(err, catalog.ErrProductNotFound) {
+			return nil, fmt.Errorf("%w: product does not exist", cartErr.ErrInvalidCartItem)
+		}
+		return nil, err
+	}
+
+	items, err := s.repo.Get(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if item.ProductID != productID && item.UnitPrice.Currency != product.Price.Currency {
+			return nil, fmt.Errorf("%w: all items must use the same currency", cartErr.ErrInvalidCartItem)
+		}
+	}
+
+	err = s.repo.Update(ctx, owner, productID, func(current *model.Item) (*model.Item, error) {
+		next := model.Item{
+			ProductID: product.ID,
+			SKU:       product.SKU,
+			Name:      product.Name,
+			Quantity:  quantity,
+			UnitPrice: model.Money{Amount: product.Price.Amount, Currency: product.Price.Currency},
+			AddedAt:   time.Now().UTC(),
+		}
+		if current != nil {
+			next.Quantity += current.Quantity
+			next.AddedAt = current.AddedAt
+		}
+		if next.Quantity > maxQuantity {
+			return nil, fmt.Errorf("%w: quantity must be 1..%d", cartErr.ErrInvalidCartItem, maxQuantity)
+		}
+		return &next, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Get(ctx, owner)
+}
+
+// SetQuantity changes the quantity of a line; zero removes it.
+func (s *Service) SetQuantity(ctx context.Context, owner model.Owner, productID string, quantity int) (*model.Cart, error) {
+	if quantity < 0 || quantity > maxQuantity {
+		return nil, fmt.Errorf("%w: quantity must be 0..%d", cartErr.ErrInvalidCartItem, maxQuantity)
+	}
+
+	err := s.repo.Update(ctx, owner, productID, func(current *model.Item) (*model.Item, error) {
+		if current == nil {
+			return nil, cartErr.ErrCartItemNotFound
+		}
+		if quantity == 0 {
+			return nil, nil
+		}
+		current.Quantity = quantity
+		return current, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Get(ctx, owner)
+}
+
+func (s *Service) RemoveItem(ctx context.Context, owner model.Owner, productID string) (*model.Cart, error) {
+	return s.SetQuantity(ctx, owner, productID, 0)
+}
+
+func (s *Service) Clear(ctx context.Context, owner model.Owner) error {
+	return s.repo.Clear(ctx, owner)
+}
+
+// Merge folds an anonymous cart into the user's cart after login. Quantities
+// of products present in both are added up.
+func (s *Service) Merge(ctx context.Context, userID, anonymousID string) (*model.Cart, error) {
+	user := model.Owner{UserID: userID}
+	if anonymousID == "" {
+		return s.Get(ctx, user)
+	}
+
+	err := s.repo.Merge(ctx, model.Owner{AnonymousID: anonymousID}, user,
+		func(existing, incoming model.Item) model.Item {
+			incoming.Quantity = min(existing.Quantity+incoming.Quantity, maxQuantity)
+			incoming.AddedAt = existing.AddedAt
+			return incoming
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Get(ctx, user)
+}
+
+// Checkout revalidates every line against the catalog and queues a
+// CreateOrderCommand in the outbox in the same transaction that clears the
+// cart. If any price changed or a product disappeared, the cart is updated,
+// nothing is queued, and the changes are returned together with
+// ErrCartChanged so the user can confirm.
+//
+// The command id is derived from idempotencyKey, or from the cart contents
+// when there is none, so a repeated checkout never places a second order:
+// a retry with the same key returns the command queued the first time, and
+// the order service deduplicates commands by id.
+func (s *Service) Checkout(ctx context.Context, userID, idempotencyKey string) (*domain.CreateOrderCommand, []model.PriceChange, error) {
+	owner := model.Owner{UserID: userID}
+
+	idempotencyKey = strings.TrimSpace(idempotencyKey)
+	if len(idempotencyKey) > maxKeyLength {
+		return nil, nil, cartErr.ErrIdempotencyKeyRequired
+	}
+	if idempotencyKey != "" {
+		cmd, err := s.previousCheckout(ctx, owner, commandID(owner, "key", []byte(idempotencyKey)))
+		if err != nil || cmd != nil {
+			return cmd, nil, err
+		}
+	}
+
+	items, err := s.repo.Get(ctx, owner)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil, cartErr.ErrCartEmpty
+	}
+
+	changes, err := s.revalidate(ctx, owner, items)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(changes) > 0 {
+		return nil, changes, cartErr.ErrCartChanged
+	}
+
+	cmd := domain.CreateOrderCommand{
+		UserID:      userID,
+		RequestedAt: time.Now().UTC(),
+	}
+	if idempotencyKey != "" {
+		cmd.CommandID = commandID(owner, "key", []byte(idempotencyKey))
+	} else {
+		contents, err := json.Marshal(items)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encode cart: %w", err)
+		}
+		cmd.CommandID = commandID(owner, "cart", contents)
+	}
+	for _, item := range items {
+		cmd.Items = append(cmd.Items, domain.CreateOrderCommandItem{
+			ProductID:      item.ProductID,
+			SKU:            item.SKU,
+			Name:           item.Name,
+			Quantity:       item.Quantity,
+			UnitPriceMinor: item.UnitPrice.Amount,
+			Currency:       item.UnitPrice.Currency,
+		})
+	}
+
+	payload, err := json.Marshal(cmd)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode order command: %w", err)
+	}
+	err = s.repo.Checkout(ctx, owner, items, cmd.CommandID, model.OutboxMessage{
+		Topic:   domain.TopicCreateOrderCommand,
+		Key:     userID,
+		Payload: payload,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &cmd, nil, nil
+}
+
+func (s *Service) previousCheckout(ctx context.Context, owner model.Owner, id string) (*domain.CreateOrderCommand, error) {
+	payload, err := s.repo.GetCheckout(ctx, owner, id)
+	if err != nil || payload == nil {
+		return nil, err
+	}
+
+	var cmd domain.CreateOrderCommand
+	if err := json.Unmarshal(payload, &cmd); err != nil {
+		return nil, fmt.Errorf("failed to decode order command: %w", err)
+	}
+	return &cmd, nil
+}
+
+// commandID names a checkout of owner by an idempotency key or by the cart
+// contents.
+func commandID(owner model.Owner, kind string, value []byte) string {
+	sum := sha256.Sum256(value)
+	return uuid.NewSHA1(commandNamespace, fmt.Appendf(nil, "%s:%s:%x", owner.Key(), kind, sum)).String()
+}
+
+func (s *Service) revalidate(ctx context.Context, owner model.Owner, items []model.Item) ([]model.PriceChange, error) {
+	var changes []model.PriceChange
+	for _, item := range items {
+		product, err := s.catalog.GetProduct(ctx, item.ProductID)
+		if err != nil && !errors.Is(err, catalog.ErrProductNotFound) {
+			return nil, err
+		}
+
+		change := model.PriceChange{ProductID: item.ProductID, OldPrice: item.UnitPrice}
+		if product == nil {
+			change.Removed = true
+		} else {
+			current := model.Money{Amount: product.Price.Amount, Currency: product.Price.Currency}
+			if current == item.UnitPrice {
+				continue
+			}
+			change.NewPrice = &current
+		}
+		changes = append(changes, change)
+
+		err = s.repo.Update(ctx, owner, item.ProductID, func(cur *model.Item) (*model.Item, error) {
+			if cur == nil || change.Removed {
+				return nil, nil
+			}
+			cur.UnitPrice = *change.NewPrice
+			cur.SKU, cur.Name = product.SKU, product.Name
+			return cur, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return changes, nil
+}
+
+func newCart(owner model.Owner, items []model.Item) *model.Cart {
+	cart := &model.Cart{Owner: owner, Items: items}
+	for _, item := range items {
+		cart.Total.Currency = item.UnitPrice.Currency
+		cart.Total.Amount += int64(item.Quantity) * item.UnitPrice.Amount
+	}
+	return cart
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-market/pkg/kafka"
+	"github.com/go-market/pkg/logger/sl"
+	cartRepo "github.com/go-market/services/cart/internal/repository"
+)
+
+type RelayConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+}
+
+// Relay publishes the commands Checkout queued in the outbox. A command is
+// removed only after Kafka accepted it, so a crash in between publishes it
+// again; the order service deduplicates by command id. Commands go out in
+// the order they were queued and a failure stops the batch.
+type Relay struct {
+	repo cartRepo.Repository
+	pub  kafka.Publisher
+	log  *slog.Logger
+	cfg  RelayConfig
+}
+
+func NewRelay(repo cartRepo.Repository, pub kafka.Publisher, log *slog.Logger, cfg RelayConfig) *Relay {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+
+	return &Relay{
+		repo: repo,
+		pub:  pub,
+		log:  log.With(slog.String("component", "cart.Relay")),
+		cfg:  cfg,
+	}
+}
+
+// Run polls the outbox until ctx is cancelled.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := r.drain(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					r.log.Error("failed to drain outbox", sl.Err(err))
+				}
+				break
+			}
+			if n < r.cfg.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Relay) drain(ctx context.Context) (int, error) {
+	const op = "service.Relay.drain"
+
+	batch, err := r.repo.PendingOutbox(ctx, r.cfg.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	published := make([]string, 0, len(batch))
+	var pubErr error
+	for _, m := range batch {
+		if pubErr = r.pub.Publish(ctx, m.Topic, []byte(m.Key), m.Payload); pubErr != nil {
+			break
+		}
+		published = append(published, m.ID)
+	}
+
+	if err := r.repo.DeleteOutbox(ctx, published...); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if pubErr != nil {
+		return 0, fmt.Errorf("%s: %w", op, pubErr)
+	}
+
+	return len(batch), nil
+}
@@ -0,0 +1,119 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-market/pkg/authz"
+	"github.com/go-market/pkg/migrate"
+	pkgRedis "github.com/go-market/pkg/redis"
+	"github.com/go-market/services/catalog/internal/config"
+	catalogHTTP "github.com/go-market/services/catalog/internal/derivery/http"
+	"github.com/go-market/services/catalog/internal/repository/postgres"
+	"github.com/go-market/services/catalog/internal/service"
+	"github.com/redis/go-redis/v9"
+)
+
+type App struct {
+	server *http.Server
+	repo   *postgres.PostgresRepo
+	rdb    *redis.Client
+	log    *slog.Logger
+}
+
+func NewApp(cfg config.Config, log *slog.Logger) (*App, error) {
+	const op = "app.NewApp"
+
+	logger := log.With(slog.String("op", op))
+
+	repo, err := postgres.New(cfg.DatabaseURL)
+	if err != nil {
+		logger.Error("failed to init postgres", slog.String("op", op), slog.Any("err", err))
+		return nil, err
+	}
+
+	if err := migrateSchema(repo, cfg.MigrationsPath, log); err != nil {
+		logger.Error("failed to migrate database", slog.Any("err", err))
+		repo.Close()
+		return nil, err
+	}
+
+	svc := service.New(repo)
+
+	productHandler := catalogHTTP.NewProductHandler(log, svc)
+	categoryHandler := catalogHTTP.NewCategoryHandler(log, svc)
+
+	rdb := pkgRedis.NewClient(cfg.RedisAddr)
+	if err := rdb.Ping(context.Background()).Err(); err != nil {
+		logger.Warn("redis is unreachable, authenticated requests will be refused", slog.Any("err", err))
+	}
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(middleware.Logger)
+	r.Use(middleware.Recoverer)
+
+	r.Route("/api/v1", func(r chi.Router) {
+		catalogHTTP.RegisterCatalogRoutes(r, productHandler, categoryHandler, authz.NewRemoteVerifier(cfg.Auth), authz.NewRedisDenyList(rdb))
+	})
+
+	server := &http.Server{
+		Addr:         cfg.HTTPAddr.Address,
+		Handler:      r,
+		ReadTimeout:  cfg.HTTPAddr.Timeout,
+		WriteTimeout: cfg.HTTPAddr.Timeout,
+		IdleTimeout:  cfg.HTTPAddr.IdleTimeout,
+	}
+
+	return &App{server: server, repo: repo, rdb: rdb, log: log}, nil
+}
+
+// migrateSchema applies pending migrations and then verifies that the schema
+// matches what the repository queries expect.
+func migrateSchema(repo *postgres.PostgresRepo, path string, log *slog.Logger) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	migrator, err := migrate.New(repo.Pool(), path, log)
+	if err != nil {
+		return err
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		return err
+	}
+
+	return repo.CheckSchema(ctx)
+}
+
+func (a *App) Run() error {
+	go func() {
+		if err := a.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			a.log.Error("listen failed", slog.Any("err", err))
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	a.log.Info("shutting down server")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	defer a.repo.Close()
+	defer func() {
+		if err := a.rdb.Close(); err != nil {
+			a.log.Error("failed to close redis client", slog.Any("err", err))
+		}
+	}()
+
+	return a.server.Shutdown(ctx)
+}
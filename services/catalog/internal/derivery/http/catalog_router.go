@@ -0,0 +1,29 @@
+package http
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-market/pkg/authz"
+)
+
+// RegisterCatalogRoutes exposes reads publicly; writes need catalog:write.
+func RegisterCatalogRoutes(r chi.Router, products *ProductHandler, categories *CategoryHandler, verifier *authz.Verifier, denied authz.DenyList) {
+	write := chi.Chain(authz.Authenticate(verifier, authz.WithDenyList(denied)), authz.RequirePermission(authz.PermCatalogWrite))
+
+	r.Route("/products", func(r chi.Router) {
+		r.Get("/", products.Search)
+		r.Get("/{id}", products.GetByID)
+
+		r.With(write...).Post("/", products.Create)
+		r.With(write...).Put("/{id}", products.Update)
+		r.With(write...).Delete("/{id}", products.Delete)
+	})
+
+	r.Route("/categories", func(r chi.Router) {
+		r.Get("/", categories.Tree)
+		r.Get("/{id}", categories.GetByID)
+
+		r.With(write...).Post("/", categories.Create)
+		r.With(write...).Put("/{id}", categories.Update)
+		r.With(write...).Delete("/{id}", categories.Delete)
+	})
+}
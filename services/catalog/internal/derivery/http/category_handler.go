@@ -0,0 +1,127 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/go-market/services/catalog/internal/model"
+	"github.com/go-market/services/catalog/internal/service"
+)
+
+type CategoryHandler struct {
+	log *slog.Logger
+	svc *service.Service
+}
+
+func NewCategoryHandler(log *slog.Logger, svc *service.Service) *CategoryHandler {
+	return &CategoryHandler{
+		log: log,
+		svc: svc,
+	}
+}
+
+type CategoryRequest struct {
+	ParentID *string `json:"parent_id"`
+	Name     string  `json:"name"`
+	Slug     string  `json:"slug"`
+}
+
+func (h *CategoryHandler) Create(w http.ResponseWriter, r *http.Request) {
+	const op = "CategoryHandler.Create"
+	log := h.log.With(slog.String("op", op))
+
+	var req CategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("failed to decode request", slog.String("error", err.Error()))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	category, err := h.svc.CreateCategory(r.Context(), model.Category{
+		ParentID: req.ParentID,
+		Name:     req.Name,
+		Slug:     req.Slug,
+	})
+	if err != nil {
+		log.Error("failed to create category", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to create category")
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/categories/"+category.ID)
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, SuccessResponse{Data: category})
+}
+
+func (h *CategoryHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	const op = "CategoryHandler.GetByID"
+	log := h.log.With(slog.String("op", op))
+
+	category, err := h.svc.GetCategory(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		log.Error("failed to get category", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to get category")
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Data: category})
+}
+
+// Tree serves the whole category hierarchy as nested nodes.
+func (h *CategoryHandler) Tree(w http.ResponseWriter, r *http.Request) {
+	const op = "CategoryHandler.Tree"
+	log := h.log.With(slog.String("op", op))
+
+	tree, err := h.svc.CategoryTree(r.Context())
+	if err != nil {
+		log.Error("failed to build category tree", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to list categories")
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Data: tree})
+}
+
+func (h *CategoryHandler) Update(w http.ResponseWriter, r *http.Request) {
+	const op = "CategoryHandler.Update"
+	log := h.log.With(slog.String("op", op))
+
+	var req CategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("failed to decode request", slog.String("error", err.Error()))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	category, err := h.svc.UpdateCategory(r.Context(), model.Category{
+		ID:       chi.URLParam(r, "id"),
+		ParentID: req.ParentID,
+		Name:     req.Name,
+		Slug:     req.Slug,
+	})
+	if err != nil {
+		log.Error("failed to update category", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to update category")
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Data: category})
+}
+
+func (h *CategoryHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	const op = "CategoryHandler.Delete"
+	log := h.log.With(slog.String("op", op))
+
+	if err := h.svc.DeleteCategory(r.Context(), chi.URLParam(r, "id")); err != nil {
+		log.Error("failed to delete category", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to delete category")
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Message: "category deleted successfully"})
+}
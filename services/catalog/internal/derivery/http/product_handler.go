@@ -0,0 +1,164 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/go-market/services/catalog/internal/model"
+	"github.com/go-market/services/catalog/internal/service"
+)
+
+type ProductHandler struct {
+	log *slog.Logger
+	svc *service.Service
+}
+
+func NewProductHandler(log *slog.Logger, svc *service.Service) *ProductHandler {
+	return &ProductHandler{
+		log: log,
+		svc: svc,
+	}
+}
+
+type ProductRequest struct {
+	SKU         string                     `json:"sku"`
+	Name        string                     `json:"name"`
+	Description string                     `json:"description"`
+	Price       model.Money                `json:"price"`
+	CategoryID  *string                    `json:"category_id"`
+	Attributes  map[string]model.Attribute `json:"attributes"`
+}
+
+func (req ProductRequest) toModel(id string) model.Product {
+	return model.Product{
+		ID:          id,
+		SKU:         req.SKU,
+		Name:        req.Name,
+		Description: req.Description,
+		Price:       req.Price,
+		CategoryID:  req.CategoryID,
+		Attributes:  req.Attributes,
+	}
+}
+
+func (h *ProductHandler) Create(w http.ResponseWriter, r *http.Request) {
+	const op = "ProductHandler.Create"
+	log := h.log.With(slog.String("op", op))
+
+	var req ProductRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("failed to decode request", slog.String("error", err.Error()))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	product, err := h.svc.CreateProduct(r.Context(), req.toModel(""))
+	if err != nil {
+		log.Error("failed to create product", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to create product")
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/products/"+product.ID)
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, SuccessResponse{Data: product})
+}
+
+func (h *ProductHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	const op = "ProductHandler.GetByID"
+	log := h.log.With(slog.String("op", op))
+
+	product, err := h.svc.GetProduct(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		log.Error("failed to get product", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to get product")
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Data: product})
+}
+
+// Search serves GET /products?q=&category_id=&include_subcategories=&limit=&offset=.
+func (h *ProductHandler) Search(w http.ResponseWriter, r *http.Request) {
+	const op = "ProductHandler.Search"
+	log := h.log.With(slog.String("op", op))
+
+	q := r.URL.Query()
+	filter := model.ProductFilter{
+		Query:                q.Get("q"),
+		CategoryID:           q.Get("category_id"),
+		IncludeSubcategories: q.Get("include_subcategories") != "false",
+	}
+
+	var err error
+	if v := q.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, ErrorResponse{Error: "invalid limit"})
+			return
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if filter.Offset, err = strconv.Atoi(v); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, ErrorResponse{Error: "invalid offset"})
+			return
+		}
+	}
+
+	page, err := h.svc.SearchProducts(r.Context(), filter)
+	if err != nil {
+		log.Error("failed to search products", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to search products")
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{
+		Data: page.Products,
+		Pagination: &Pagination{
+			Limit:   page.Limit,
+			Offset:  page.Offset,
+			HasMore: page.HasMore,
+		},
+	})
+}
+
+func (h *ProductHandler) Update(w http.ResponseWriter, r *http.Request) {
+	const op = "ProductHandler.Update"
+	log := h.log.With(slog.String("op", op))
+
+	var req ProductRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("failed to decode request", slog.String("error", err.Error()))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	product, err := h.svc.UpdateProduct(r.Context(), req.toModel(chi.URLParam(r, "id")))
+	if err != nil {
+		log.Error("failed to update product", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to update product")
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Data: product})
+}
+
+func (h *ProductHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	const op = "ProductHandler.Delete"
+	log := h.log.With(slog.String("op", op))
+
+	if err := h.svc.DeleteProduct(r.Context(), chi.URLParam(r, "id")); err != nil {
+		log.Error("failed to delete product", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to delete product")
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Message: "product deleted successfully"})
+}
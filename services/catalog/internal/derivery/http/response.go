@@ -0,0 +1,45 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/render"
+	catalogErr "github.com/go-market/pkg/errs"
+)
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type SuccessResponse struct {
+	Data       interface{} `json:"data,omitempty"`
+	Message    string      `json:"message,omitempty"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+type Pagination struct {
+	Limit   int  `json:"limit"`
+	Offset  int  `json:"offset"`
+	HasMore bool `json:"has_more"`
+}
+
+// renderError maps catalog errors to status codes. Unknown errors are
+// reported as fallback so internals never reach the client.
+func renderError(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+	status := http.StatusInternalServerError
+	msg := fallback
+
+	switch {
+	case errors.Is(err, catalogErr.ErrInvalidProduct), errors.Is(err, catalogErr.ErrInvalidCategory):
+		status, msg = http.StatusBadRequest, err.Error()
+	case errors.Is(err, catalogErr.ErrProductNotFound), errors.Is(err, catalogErr.ErrCategoryNotFound):
+		status, msg = http.StatusNotFound, err.Error()
+	case errors.Is(err, catalogErr.ErrProductExists), errors.Is(err, catalogErr.ErrCategoryExists),
+		errors.Is(err, catalogErr.ErrCategoryInUse):
+		status, msg = http.StatusConflict, err.Error()
+	}
+
+	render.Status(r, status)
+	render.JSON(w, r, ErrorResponse{Error: msg})
+}
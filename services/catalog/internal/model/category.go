@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+type Category struct {
+	ID        string    `json:"id"`
+	ParentID  *string   `json:"parent_id,omitempty"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CategoryNode is a category together with its descendants.
+type CategoryNode struct {
+	Category
+	Children []*CategoryNode `json:"children"`
+}
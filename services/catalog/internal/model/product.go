@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+// Money is an amount in the currency's minor units (cents for USD), so prices
+// never go through floating point.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+type AttributeType string
+
+const (
+	AttributeString  AttributeType = "string"
+	AttributeNumber  AttributeType = "number"
+	AttributeBoolean AttributeType = "boolean"
+)
+
+// Attribute is a typed product property such as color or weight. Value holds
+// a string, float64 or bool matching Type.
+type Attribute struct {
+	Type  AttributeType `json:"type"`
+	Value interface{}   `json:"value"`
+}
+
+type Product struct {
+	ID          string               `json:"id"`
+	SKU         string               `json:"sku"`
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	Price       Money                `json:"price"`
+	CategoryID  *string              `json:"category_id,omitempty"`
+	Attributes  map[string]Attribute `json:"attributes"`
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+}
+
+type ProductFilter struct {
+	Query                string
+	CategoryID           string
+	IncludeSubcategories bool
+	Limit                int
+	Offset               int
+}
@@ -0,0 +1,166 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	catalogErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/services/catalog/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+const categoryColumns = `id, parent_id, name, slug, created_at, updated_at`
+
+func scanCategory(row pgx.Row) (*model.Category, error) {
+	c := &model.Category{}
+	if err := row.Scan(&c.ID, &c.ParentID, &c.Name, &c.Slug, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func categoryError(op string, err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return catalogErr.ErrCategoryNotFound
+	}
+	switch pgCode(err) {
+	case uniqueViolation:
+		return catalogErr.ErrCategoryExists
+	case foreignKeyViolation:
+		// Either the parent does not exist or the category still has children.
+		return catalogErr.ErrInvalidCategory
+	case invalidTextRepr:
+		return catalogErr.ErrCategoryNotFound
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
+
+func (r *PostgresRepo) CreateCategory(ctx context.Context, category model.Category) (*model.Category, error) {
+	const op = "repo.CreateCategory"
+
+	query := `INSERT INTO categories (parent_id, name, slug) VALUES ($1, $2, $3) RETURNING ` + categoryColumns
+	c, err := scanCategory(r.db.QueryRow(ctx, query, category.ParentID, category.Name, category.Slug))
+	if err != nil {
+		return nil, categoryError(op, err)
+	}
+
+	return c, nil
+}
+
+func (r *PostgresRepo) GetCategory(ctx context.Context, id string) (*model.Category, error) {
+	const op = "repo.GetCategory"
+
+	query := `SELECT ` + categoryColumns + ` FROM categories WHERE id = $1`
+	c, err := scanCategory(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		return nil, categoryError(op, err)
+	}
+
+	return c, nil
+}
+
+func (r *PostgresRepo) ListCategories(ctx context.Context) ([]model.Category, error) {
+	const op = "repo.ListCategories"
+
+	rows, err := r.db.Query(ctx, `SELECT `+categoryColumns+` FROM categories ORDER BY name, id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var categories []model.Category
+	for rows.Next() {
+		c, err := scanCategory(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		categories = append(categories, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return categories, nil
+}
+
+// categoryMoveLock serialises moves, so two of them cannot each pass the
+// cycle check against a tree the other one is about to change.
+const categoryMoveLock = "catalog.category_move"
+
+// UpdateCategory renames or moves a category. A move below the category
+// itself or one of its descendants is rejected with ErrInvalidCategory; the
+// check and the update run in one transaction under categoryMoveLock.
+func (r *PostgresRepo) UpdateCategory(ctx context.Context, category model.Category) (*model.Category, error) {
+	const op = "repo.UpdateCategory"
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if category.ParentID != nil {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`, categoryMoveLock); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		cycle, err := isDescendant(ctx, tx, category.ID, *category.ParentID)
+		if err != nil {
+			return nil, categoryError(op, err)
+		}
+		if cycle {
+			return nil, fmt.Errorf("%w: a category cannot be moved under its own subtree", catalogErr.ErrInvalidCategory)
+		}
+	}
+
+	query := `UPDATE categories SET parent_id = $1, name = $2, slug = $3, updated_at = NOW()
+		WHERE id = $4
+		RETURNING ` + categoryColumns
+	c, err := scanCategory(tx.QueryRow(ctx, query, category.ParentID, category.Name, category.Slug, category.ID))
+	if err != nil {
+		return nil, categoryError(op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return c, nil
+}
+
+func (r *PostgresRepo) DeleteCategory(ctx context.Context, id string) error {
+	const op = "repo.DeleteCategory"
+
+	result, err := r.db.Exec(ctx, `DELETE FROM categories WHERE id = $1`, id)
+	if err != nil {
+		if pgCode(err) == foreignKeyViolation {
+			return catalogErr.ErrCategoryInUse
+		}
+		return categoryError(op, err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return catalogErr.ErrCategoryNotFound
+	}
+
+	return nil
+}
+
+// isDescendant reports whether id lies in the subtree rooted at ancestorID
+// (including ancestorID itself).
+func isDescendant(ctx context.Context, tx pgx.Tx, ancestorID, id string) (bool, error) {
+	query := `WITH RECURSIVE subtree AS (
+			SELECT id FROM categories WHERE id = $1
+			UNION ALL
+			SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+		)
+		SELECT EXISTS (SELECT 1 FROM subtree WHERE id = $2)`
+
+	var found bool
+	if err := tx.QueryRow(ctx, query, ancestorID, id).Scan(&found); err != nil {
+		return false, err
+	}
+
+	return found, nil
+}
@@ -0,0 +1,165 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	catalogErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/services/catalog/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+const productColumns = `id, sku, name, description, price_minor, currency, category_id, attributes, created_at, updated_at`
+
+func scanProduct(row pgx.Row) (*model.Product, error) {
+	p := &model.Product{}
+	err := row.Scan(&p.ID, &p.SKU, &p.Name, &p.Description, &p.Price.Amount, &p.Price.Currency,
+		&p.CategoryID, &p.Attributes, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func productError(op string, err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return catalogErr.ErrProductNotFound
+	}
+	switch pgCode(err) {
+	case uniqueViolation:
+		return catalogErr.ErrProductExists
+	case foreignKeyViolation:
+		return catalogErr.ErrCategoryNotFound
+	case invalidTextRepr:
+		return catalogErr.ErrProductNotFound
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
+
+func (r *PostgresRepo) CreateProduct(ctx context.Context, product model.Product) (*model.Product, error) {
+	const op = "repo.CreateProduct"
+
+	query := `INSERT INTO products (sku, name, description, price_minor, currency, category_id, attributes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING ` + productColumns
+	p, err := scanProduct(r.db.QueryRow(ctx, query, product.SKU, product.Name, product.Description,
+		product.Price.Amount, product.Price.Currency, product.CategoryID, product.Attributes))
+	if err != nil {
+		return nil, productError(op, err)
+	}
+
+	return p, nil
+}
+
+func (r *PostgresRepo) GetProduct(ctx context.Context, id string) (*model.Product, error) {
+	const op = "repo.GetProduct"
+
+	query := `SELECT ` + productColumns + ` FROM products WHERE id = $1`
+	p, err := scanProduct(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		return nil, productError(op, err)
+	}
+
+	return p, nil
+}
+
+func (r *PostgresRepo) UpdateProduct(ctx context.Context, product model.Product) (*model.Product, error) {
+	const op = "repo.UpdateProduct"
+
+	query := `UPDATE products
+		SET sku = $1, name = $2, description = $3, price_minor = $4, currency = $5,
+			category_id = $6, attributes = $7, updated_at = NOW()
+		WHERE id = $8
+		RETURNING ` + productColumns
+	p, err := scanProduct(r.db.QueryRow(ctx, query, product.SKU, product.Name, product.Description,
+		product.Price.Amount, product.Price.Currency, product.CategoryID, product.Attributes, product.ID))
+	if err != nil {
+		return nil, productError(op, err)
+	}
+
+	return p, nil
+}
+
+func (r *PostgresRepo) DeleteProduct(ctx context.Context, id string) error {
+	const op = "repo.DeleteProduct"
+
+	result, err := r.db.Exec(ctx, `DELETE FROM products WHERE id = $1`, id)
+	if err != nil {
+		return productError(op, err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return catalogErr.ErrProductNotFound
+	}
+
+	return nil
+}
+
+// SearchProducts runs a full-text query against the generated `search`
+// column when filter.Query is set and ranks the results; otherwise products
+// are returned newest first.
+func (r *PostgresRepo) SearchProducts(ctx context.Context, filter model.ProductFilter) ([]model.Product, error) {
+	const op = "repo.SearchProducts"
+
+	var (
+		conds []string
+		args  []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	from := "products"
+	order := "created_at DESC, id"
+	if filter.Query != "" {
+		from += ", websearch_to_tsquery('english', " + arg(filter.Query) + ") AS q"
+		conds = append(conds, "search @@ q")
+		order = "ts_rank(search, q) DESC, " + order
+	}
+
+	if filter.CategoryID != "" {
+		if filter.IncludeSubcategories {
+			conds = append(conds, `category_id IN (
+				WITH RECURSIVE subtree AS (
+					SELECT id FROM categories WHERE id = `+arg(filter.CategoryID)+`
+					UNION ALL
+					SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+				)
+				SELECT id FROM subtree)`)
+		} else {
+			conds = append(conds, "category_id = "+arg(filter.CategoryID))
+		}
+	}
+
+	query := `SELECT ` + productColumns + ` FROM ` + from
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY " + order + " LIMIT " + arg(filter.Limit) + " OFFSET " + arg(filter.Offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		if pgCode(err) == invalidTextRepr {
+			return nil, catalogErr.ErrCategoryNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	products := make([]model.Product, 0, filter.Limit)
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		products = append(products, *p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return products, nil
+}
@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-market/pkg/migrate"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+	invalidTextRepr     = "22P02"
+)
+
+// schema lists every column the queries in this package rely on.
+var schema = map[string][]string{
+	"categories": {"id", "parent_id", "name", "slug", "created_at", "updated_at"},
+	"products":   {"id", "sku", "name", "description", "price_minor", "currency", "category_id", "attributes", "search", "created_at", "updated_at"},
+}
+
+type PostgresRepo struct {
+	db *pgxpool.Pool
+}
+
+func New(db_url string) (*PostgresRepo, error) {
+	ctx := context.Background()
+	db, err := pgxpool.New(ctx, db_url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect database %w", err)
+	}
+
+	return &PostgresRepo{db: db}, nil
+}
+
+// Pool exposes the connection pool for components that share the database,
+// such as the migrator.
+func (r *PostgresRepo) Pool() *pgxpool.Pool {
+	return r.db
+}
+
+// CheckSchema fails when the database is missing a column used by the
+// repository queries.
+func (r *PostgresRepo) CheckSchema(ctx context.Context) error {
+	return migrate.VerifyColumns(ctx, r.db, schema)
+}
+
+func (r *PostgresRepo) Close() {
+	r.db.Close()
+}
+
+func pgCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/go-market/services/catalog/internal/model"
+)
+
+type Repository interface {
+	CreateProduct(ctx context.Context, product model.Product) (*model.Product, error)
+	GetProduct(ctx context.Context, id string) (*model.Product, error)
+	UpdateProduct(ctx context.Context, product model.Product) (*model.Product, error)
+	DeleteProduct(ctx context.Context, id string) error
+	SearchProducts(ctx context.Context, filter model.ProductFilter) ([]model.Product, error)
+
+	CreateCategory(ctx context.Context, category model.Category) (*model.Category, error)
+	GetCategory(ctx context.Context, id string) (*model.Category, error)
+	ListCategories(ctx context.Context) ([]model.Category, error)
+	// UpdateCategory rejects moving a category under its own subtree with
+	// ErrInvalidCategory.
+	UpdateCategory(ctx context.Context, category model.Category) (*model.Category, error)
+	DeleteCategory(ctx context.Context, id string) error
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	catalogErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/services/catalog/internal/model"
+)
+
+var (
+	slugRe      = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+	nonSlugRune = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+func (s *Service) CreateCategory(ctx context.Context, c model.Category) (*model.Category, error) {
+	if err := normalizeCategory(&c); err != nil {
+		return nil, err
+	}
+	if c.ParentID != nil {
+		if _, err := s.repo.GetCategory(ctx, *c.ParentID); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.repo.CreateCategory(ctx, c)
+}
+
+func (s *Service) GetCategory(ctx context.Context, id string) (*model.Category, error) {
+	if id == "" {
+		return nil, catalogErr.ErrCategoryNotFound
+	}
+
+	return s.repo.GetCategory(ctx, id)
+}
+
+// UpdateCategory renames or moves a category. Moving a category below itself
+// or one of its descendants is rejected because it would create a cycle.
+func (s *Service) UpdateCategory(ctx context.Context, c model.Category) (*model.Category, error) {
+	if c.ID == "" {
+		return nil, catalogErr.ErrCategoryNotFound
+	}
+	if err := normalizeCategory(&c); err != nil {
+		return nil, err
+	}
+
+	return s.repo.UpdateCategory(ctx, c)
+}
+
+func (s *Service) DeleteCategory(ctx context.Context, id string) error {
+	if id == "" {
+		return catalogErr.ErrCategoryNotFound
+	}
+
+	return s.repo.DeleteCategory(ctx, id)
+}
+
+// CategoryTree returns all root categories with their descendants nested.
+func (s *Service) CategoryTree(ctx context.Context) ([]*model.CategoryNode, error) {
+	categories, err := s.repo.ListCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[string]*model.CategoryNode, len(categories))
+	for _, c := range categories {
+		nodes[c.ID] = &model.CategoryNode{Category: c, Children: []*model.CategoryNode{}}
+	}
+
+	roots := []*model.CategoryNode{}
+	for _, c := range categories {
+		node := nodes[c.ID]
+		if c.ParentID == nil {
+			roots = append(roots, node)
+			continue
+		}
+		if parent, ok := nodes[*c.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+
+	return roots, nil
+}
+
+func normalizeCategory(c *model.Category) error {
+	c.Name = strings.TrimSpace(c.Name)
+	c.Slug = strings.TrimSpace(c.Slug)
+
+	if c.Name == "" || len(c.Name) > 255 {
+		return fmt.Errorf("%w: name must be 1..255 characters", catalogErr.ErrInvalidCategory)
+	}
+	if c.Slug == "" {
+		c.Slug = strings.Trim(nonSlugRune.ReplaceAllString(strings.ToLower(c.Name), "-"), "-")
+	}
+	if !slugRe.MatchString(c.Slug) || len(c.Slug) > 255 {
+		return fmt.Errorf("%w: slug must contain only lowercase letters, digits and dashes", catalogErr.ErrInvalidCategory)
+	}
+	if c.ParentID != nil && *c.ParentID == "" {
+		c.ParentID = nil
+	}
+	if c.ParentID != nil && *c.ParentID == c.ID {
+		return fmt.Errorf("%w: a category cannot be its own parent", catalogErr.ErrInvalidCategory)
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	catalogErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/services/catalog/internal/model"
+	catalogRepo "github.com/go-market/services/catalog/internal/repository"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+var currencyRe = regexp.MustCompile(`^[A-Z]{3}$`)
+
+type Service struct {
+	repo catalogRepo.Repository
+}
+
+func New(repo catalogRepo.Repository) *Service {
+	return &Service{
+		repo: repo,
+	}
+}
+
+type ProductPage struct {
+	Products []model.Product
+	Limit    int
+	Offset   int
+	HasMore  bool
+}
+
+func (s *Service) CreateProduct(ctx context.Context, p model.Product) (*model.Product, error) {
+	if err := normalizeProduct(&p); err != nil {
+		return nil, err
+	}
+
+	return s.repo.CreateProduct(ctx, p)
+}
+
+func (s *Service) GetProduct(ctx context.Context, id string) (*model.Product, error) {
+	if id == "" {
+		return nil, catalogErr.ErrProductNotFound
+	}
+
+	return s.repo.GetProduct(ctx, id)
+}
+
+// UpdateProduct replaces every field of an existing product.
+func (s *Service) UpdateProduct(ctx context.Context, p model.Product) (*model.Product, error) {
+	if p.ID == "" {
+		return nil, catalogErr.ErrProductNotFound
+	}
+	if err := normalizeProduct(&p); err != nil {
+		return nil, err
+	}
+
+	return s.repo.UpdateProduct(ctx, p)
+}
+
+func (s *Service) DeleteProduct(ctx context.Context, id string) error {
+	if id == "" {
+		return catalogErr.ErrProductNotFound
+	}
+
+	return s.repo.DeleteProduct(ctx, id)
+}
+
+func (s *Service) SearchProducts(ctx context.Context, filter model.ProductFilter) (*ProductPage, error) {
+	if filter.Limit == 0 {
+		filter.Limit = defaultPageSize
+	}
+	if filter.Limit < 0 || filter.Limit > maxPageSize || filter.Offset < 0 {
+		return nil, fmt.Errorf("%w: limit must be 1..%d and offset non-negative", catalogErr.ErrInvalidProduct, maxPageSize)
+	}
+	filter.Query = strings.TrimSpace(filter.Query)
+
+	// Fetch one extra row to find out whether another page exists.
+	pageSize := filter.Limit
+	filter.Limit++
+
+	products, err := s.repo.SearchProducts(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ProductPage{Products: products, Limit: pageSize, Offset: filter.Offset}
+	if len(products) > pageSize {
+		page.Products = products[:pageSize]
+		page.HasMore = true
+	}
+
+	return page, nil
+}
+
+func normalizeProduct(p *model.Product) error {
+	p.SKU = strings.TrimSpace(p.SKU)
+	p.Name = strings.TrimSpace(p.Name)
+	p.Price.Currency = strings.ToUpper(strings.TrimSpace(p.Price.Currency))
+
+	if p.SKU == "" || len(p.SKU) > 64 {
+		return fmt.Errorf("%w: sku must be 1..64 characters", catalogErr.ErrInvalidProduct)
+	}
+	if p.Name == "" || len(p.Name) > 255 {
+		return fmt.Errorf("%w: name must be 1..255 characters", catalogErr.ErrInvalidProduct)
+	}
+	if p.Price.Amount < 0 {
+		return fmt.Errorf("%w: price amount must not be negative", catalogErr.ErrInvalidProduct)
+	}
+	if !currencyRe.MatchString(p.Price.Currency) {
+		return fmt.Errorf("%w: currency must be an ISO 4217 code", catalogErr.ErrInvalidProduct)
+	}
+	if p.CategoryID != nil && *p.CategoryID == "" {
+		p.CategoryID = nil
+	}
+
+	if p.Attributes == nil {
+		p.Attributes = map[string]model.Attribute{}
+	}
+	for name, attr := range p.Attributes {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("%w: attribute name must not be empty", catalogErr.ErrInvalidProduct)
+		}
+		if err := validateAttribute(attr); err != nil {
+			return fmt.Errorf("%w: attribute %q: %s", catalogErr.ErrInvalidProduct, name, err)
+		}
+	}
+
+	return nil
+}
+
+func validateAttribute(attr model.Attribute) error {
+	var ok bool
+	switch attr.Type {
+	case model.AttributeString:
+		_, ok = attr.Value.(string)
+	case model.AttributeNumber:
+		_, ok = attr.Value.(float64)
+	case model.AttributeBoolean:
+		_, ok = attr.Value.(bool)
+	default:
+		return fmt.Errorf("unknown type %q", attr.Type)
+	}
+
+	if !ok {
+		return fmt.Errorf("value does not match type %q", attr.Type)
+	}
+	return nil
+}
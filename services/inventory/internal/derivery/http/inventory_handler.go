@@ -0,0 +1,141 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/go-market/services/inventory/internal/model"
+	"github.com/go-market/services/inventory/internal/service"
+)
+
+const idempotentReplayedHeader = "Idempotent-Replayed"
+
+type InventoryHandler struct {
+	log *slog.Logger
+	svc *service.Service
+}
+
+func New(log *slog.Logger, svc *service.Service) *InventoryHandler {
+	return &InventoryHandler{
+		log: log,
+		svc: svc,
+	}
+}
+
+type ReserveRequest struct {
+	OrderID string                `json:"order_id"`
+	Items   []model.RequestedItem `json:"items"`
+}
+
+func (h *InventoryHandler) GetStock(w http.ResponseWriter, r *http.Request) {
+	const op = "InventoryHandler.GetStock"
+	log := h.log.With(slog.String("op", op))
+
+	levels, err := h.svc.GetStock(r.Context(), chi.URLParam(r, "sku"))
+	if err != nil {
+		log.Error("failed to get stock", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to get stock")
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Data: levels})
+}
+
+func (h *InventoryHandler) AdjustStock(w http.ResponseWriter, r *http.Request) {
+	const op = "InventoryHandler.AdjustStock"
+	log := h.log.With(slog.String("op", op))
+
+	var req model.Adjustment
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("failed to decode request", slog.String("error", err.Error()))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	level, err := h.svc.AdjustStock(r.Context(), req)
+	if err != nil {
+		log.Error("failed to adjust stock", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to adjust stock")
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Data: level})
+}
+
+// Reserve holds stock for an order. Retries for the same order_id return
+// the original reservation with the Idempotent-Replayed header set.
+func (h *InventoryHandler) Reserve(w http.ResponseWriter, r *http.Request) {
+	const op = "InventoryHandler.Reserve"
+	log := h.log.With(slog.String("op", op))
+
+	var req ReserveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("failed to decode request", slog.String("error", err.Error()))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	res, replayed, err := h.svc.Reserve(r.Context(), req.OrderID, req.Items)
+	if err != nil {
+		log.Error("failed to reserve stock", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to reserve stock")
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/inventory/reservations/"+res.ID)
+	if replayed {
+		w.Header().Set(idempotentReplayedHeader, "true")
+		render.JSON(w, r, SuccessResponse{Data: res})
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, SuccessResponse{Data: res})
+}
+
+func (h *InventoryHandler) GetReservation(w http.ResponseWriter, r *http.Request) {
+	const op = "InventoryHandler.GetReservation"
+	log := h.log.With(slog.String("op", op))
+
+	res, err := h.svc.GetReservation(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		log.Error("failed to get reservation", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to get reservation")
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Data: res})
+}
+
+func (h *InventoryHandler) Commit(w http.ResponseWriter, r *http.Request) {
+	const op = "InventoryHandler.Commit"
+	log := h.log.With(slog.String("op", op))
+
+	res, err := h.svc.Commit(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		log.Error("failed to commit reservation", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to commit reservation")
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Data: res})
+}
+
+func (h *InventoryHandler) Release(w http.ResponseWriter, r *http.Request) {
+	const op = "InventoryHandler.Release"
+	log := h.log.With(slog.String("op", op))
+
+	res, err := h.svc.Release(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		log.Error("failed to release reservation", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to release reservation")
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Data: res})
+}
@@ -0,0 +1,27 @@
+package http
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-market/pkg/authz"
+)
+
+// RegisterInventoryRoutes exposes stock levels publicly; adjustments and
+// reservations need inventory:manage and, unless the caller is a service, a
+// second factor.
+func RegisterInventoryRoutes(r chi.Router, h *InventoryHandler, verifier *authz.Verifier, denied authz.DenyList) {
+	manage := chi.Chain(authz.Authenticate(verifier, authz.WithDenyList(denied)), authz.RequirePermission(authz.PermInventoryManage), authz.RequireMFA())
+
+	r.Route("/inventory", func(r chi.Router) {
+		r.Get("/stock/{sku}", h.GetStock)
+		r.With(manage...).Post("/stock/adjustments", h.AdjustStock)
+
+		r.Route("/reservations", func(r chi.Router) {
+			r.Use(manage...)
+
+			r.Post("/", h.Reserve)
+			r.Get("/{id}", h.GetReservation)
+			r.Post("/{id}/commit", h.Commit)
+			r.Post("/{id}/release", h.Release)
+		})
+	})
+}
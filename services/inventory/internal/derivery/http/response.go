@@ -0,0 +1,38 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/render"
+	inventoryErr "github.com/go-market/pkg/errs"
+)
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type SuccessResponse struct {
+	Data    interface{} `json:"data,omitempty"`
+	Message string      `json:"message,omitempty"`
+}
+
+// renderError maps inventory errors to status codes. Unknown errors are
+// reported as fallback so internals never reach the client.
+func renderError(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+	status := http.StatusInternalServerError
+	msg := fallback
+
+	switch {
+	case errors.Is(err, inventoryErr.ErrInvalidStockAdjustment), errors.Is(err, inventoryErr.ErrInvalidReservation):
+		status, msg = http.StatusBadRequest, err.Error()
+	case errors.Is(err, inventoryErr.ErrStockNotFound), errors.Is(err, inventoryErr.ErrReservationNotFound):
+		status, msg = http.StatusNotFound, err.Error()
+	case errors.Is(err, inventoryErr.ErrInsufficientStock), errors.Is(err, inventoryErr.ErrReservationNotHeld),
+		errors.Is(err, inventoryErr.ErrReservationExpired):
+		status, msg = http.StatusConflict, err.Error()
+	}
+
+	render.Status(r, status)
+	render.JSON(w, r, ErrorResponse{Error: msg})
+}
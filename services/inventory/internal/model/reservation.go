@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+type ReservationStatus string
+
+const (
+	ReservationHeld      ReservationStatus = "held"
+	ReservationCommitted ReservationStatus = "committed"
+	ReservationReleased  ReservationStatus = "released"
+	ReservationExpired   ReservationStatus = "expired"
+)
+
+// RequestedItem is what a caller asks to reserve. The service decides which
+// warehouses the quantity is taken from.
+type RequestedItem struct {
+	SKU      string `json:"sku"`
+	Quantity int    `json:"quantity"`
+}
+
+type ReservationLine struct {
+	SKU         string `json:"sku"`
+	WarehouseID string `json:"warehouse_id"`
+	Quantity    int    `json:"quantity"`
+}
+
+// Reservation holds stock for one order until it is committed, released or
+// it expires.
+type Reservation struct {
+	ID        string            `json:"id"`
+	OrderID   string            `json:"order_id"`
+	Status    ReservationStatus `json:"status"`
+	Lines     []ReservationLine `json:"lines"`
+	ExpiresAt time.Time         `json:"expires_at"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
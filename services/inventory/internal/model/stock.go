@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+type StockLevel struct {
+	SKU         string    `json:"sku"`
+	WarehouseID string    `json:"warehouse_id"`
+	OnHand      int       `json:"on_hand"`
+	Reserved    int       `json:"reserved"`
+	Available   int       `json:"available"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Adjustment is a manual change of on-hand stock, e.g. goods received or a
+// correction after a stock take.
+type Adjustment struct {
+	SKU         string `json:"sku"`
+	WarehouseID string `json:"warehouse_id"`
+	Delta       int    `json:"delta"`
+	Reason      string `json:"reason"`
+}
+
+// Event is a domain event written to the outbox together with the change
+// that produced it.
+type Event struct {
+	Topic   string
+	Key     string
+	Payload interface{}
+}
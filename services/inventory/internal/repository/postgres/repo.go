@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-market/pkg/migrate"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	checkViolation  = "23514"
+	invalidTextRepr = "22P02"
+)
+
+// schema lists every column the queries in this package rely on.
+var schema = map[string][]string{
+	"stock_levels":      {"sku", "warehouse_id", "on_hand", "reserved", "updated_at"},
+	"reservations":      {"id", "order_id", "status", "expires_at", "created_at", "updated_at"},
+	"reservation_lines": {"reservation_id", "sku", "warehouse_id", "quantity"},
+	"outbox":            {"id", "topic", "message_key", "payload", "attempts", "last_error", "next_attempt_at", "published_at", "locked_until"},
+}
+
+type PostgresRepo struct {
+	db *pgxpool.Pool
+}
+
+func New(db_url string) (*PostgresRepo, error) {
+	ctx := context.Background()
+	db, err := pgxpool.New(ctx, db_url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect database %w", err)
+	}
+
+	return &PostgresRepo{db: db}, nil
+}
+
+// Pool exposes the connection pool for components that share the database,
+// such as the outbox relay.
+func (r *PostgresRepo) Pool() *pgxpool.Pool {
+	return r.db
+}
+
+// CheckSchema fails when the database is missing a column used by the
+// repository queries.
+func (r *PostgresRepo) CheckSchema(ctx context.Context) error {
+	return migrate.VerifyColumns(ctx, r.db, schema)
+}
+
+func (r *PostgresRepo) Close() {
+	r.db.Close()
+}
+
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func pgCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
@@ -0,0 +1,280 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	inventoryErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/pkg/outbox"
+	"github.com/go-market/services/inventory/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+const reservationColumns = `id, order_id, status, expires_at, created_at, updated_at`
+
+// Every transaction that touches stock_levels locks the rows it needs in
+// (sku, warehouse_id) order before changing them, so concurrent checkouts
+// queue up on the same rows instead of deadlocking or overselling.
+
+func (r *PostgresRepo) Reserve(ctx context.Context, orderID string, items []model.RequestedItem, ttl time.Duration, eventFor func(*model.Reservation) model.Event) (*model.Reservation, bool, error) {
+	const op = "repo.Reserve"
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	// A concurrent reserve for the same order blocks on the unique index
+	// until the first one commits, and then returns its reservation.
+	res := &model.Reservation{}
+	err = tx.QueryRow(ctx, `INSERT INTO reservations (order_id, status, expires_at)
+		VALUES ($1, $2, NOW() + make_interval(secs => $3))
+		ON CONFLICT (order_id) DO NOTHING
+		RETURNING `+reservationColumns, orderID, model.ReservationHeld, ttl.Seconds()).
+		Scan(&res.ID, &res.OrderID, &res.Status, &res.ExpiresAt, &res.CreatedAt, &res.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		var id string
+		if err := tx.QueryRow(ctx, `SELECT id FROM reservations WHERE order_id = $1`, orderID).Scan(&id); err != nil {
+			return nil, false, fmt.Errorf("%s: %w", op, err)
+		}
+		existing, err := r.getReservation(ctx, tx, id)
+		if err != nil {
+			return nil, false, err
+		}
+		return existing, true, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	skus := make([]string, 0, len(items))
+	for _, item := range items {
+		skus = append(skus, item.SKU)
+	}
+
+	rows, err := tx.Query(ctx, `SELECT `+stockColumns+` FROM stock_levels
+		WHERE sku = ANY($1) ORDER BY sku, warehouse_id FOR UPDATE`, skus)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+	levels := make(map[string][]model.StockLevel)
+	for rows.Next() {
+		var l model.StockLevel
+		if err := rows.Scan(&l.SKU, &l.WarehouseID, &l.OnHand, &l.Reserved, &l.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, false, fmt.Errorf("%s: %w", op, err)
+		}
+		l.Available = l.OnHand - l.Reserved
+		levels[l.SKU] = append(levels[l.SKU], l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	lines, err := allocate(items, levels)
+	if err != nil {
+		return nil, false, err
+	}
+
+	for _, line := range lines {
+		if _, err := tx.Exec(ctx, `UPDATE stock_levels SET reserved = reserved + $3, updated_at = NOW()
+			WHERE sku = $1 AND warehouse_id = $2`, line.SKU, line.WarehouseID, line.Quantity); err != nil {
+			return nil, false, fmt.Errorf("%s: %w", op, err)
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO reservation_lines (reservation_id, sku, warehouse_id, quantity)
+			VALUES ($1, $2, $3, $4)`, res.ID, line.SKU, line.WarehouseID, line.Quantity); err != nil {
+			return nil, false, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	res.Lines = lines
+
+	event := eventFor(res)
+	if err := outbox.Enqueue(ctx, tx, event.Topic, event.Key, event.Payload); err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res, false, nil
+}
+
+// allocate spreads each requested quantity over the warehouses holding the
+// SKU, taking from the fullest warehouse first to keep shipments few.
+func allocate(items []model.RequestedItem, levels map[string][]model.StockLevel) ([]model.ReservationLine, error) {
+	var lines []model.ReservationLine
+	for _, item := range items {
+		candidates := levels[item.SKU]
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].Available > candidates[j].Available
+		})
+
+		remaining := item.Quantity
+		for _, l := range candidates {
+			if remaining == 0 {
+				break
+			}
+			if l.Available <= 0 {
+				continue
+			}
+			take := min(remaining, l.Available)
+			lines = append(lines, model.ReservationLine{SKU: item.SKU, WarehouseID: l.WarehouseID, Quantity: take})
+			remaining -= take
+		}
+		if remaining > 0 {
+			return nil, fmt.Errorf("%w: %s is short by %d", inventoryErr.ErrInsufficientStock, item.SKU, remaining)
+		}
+	}
+
+	sort.Slice(lines, func(i, j int) bool {
+		if lines[i].SKU != lines[j].SKU {
+			return lines[i].SKU < lines[j].SKU
+		}
+		return lines[i].WarehouseID < lines[j].WarehouseID
+	})
+
+	return lines, nil
+}
+
+func (r *PostgresRepo) GetReservation(ctx context.Context, id string) (*model.Reservation, error) {
+	return r.getReservation(ctx, r.db, id)
+}
+
+func (r *PostgresRepo) getReservation(ctx context.Context, q querier, id string) (*model.Reservation, error) {
+	const op = "repo.GetReservation"
+
+	res := &model.Reservation{}
+	err := q.QueryRow(ctx, `SELECT `+reservationColumns+` FROM reservations WHERE id = $1`, id).
+		Scan(&res.ID, &res.OrderID, &res.Status, &res.ExpiresAt, &res.CreatedAt, &res.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) || pgCode(err) == invalidTextRepr {
+			return nil, inventoryErr.ErrReservationNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := q.Query(ctx, `SELECT sku, warehouse_id, quantity FROM reservation_lines
+		WHERE reservation_id = $1 ORDER BY sku, warehouse_id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	res.Lines = []model.ReservationLine{}
+	for rows.Next() {
+		var line model.ReservationLine
+		if err := rows.Scan(&line.SKU, &line.WarehouseID, &line.Quantity); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		res.Lines = append(res.Lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res, nil
+}
+
+func (r *PostgresRepo) Commit(ctx context.Context, id string, eventFor func(*model.Reservation) model.Event) (*model.Reservation, error) {
+	return r.settle(ctx, "repo.Commit", id, model.ReservationCommitted, `UPDATE stock_levels s
+		SET on_hand = s.on_hand - l.quantity, reserved = s.reserved - l.quantity, updated_at = NOW()
+		FROM reservation_lines l
+		WHERE l.reservation_id = $1 AND s.sku = l.sku AND s.warehouse_id = l.warehouse_id`, eventFor)
+}
+
+func (r *PostgresRepo) Release(ctx context.Context, id string, status model.ReservationStatus, eventFor func(*model.Reservation) model.Event) (*model.Reservation, error) {
+	return r.settle(ctx, "repo.Release", id, status, `UPDATE stock_levels s
+		SET reserved = s.reserved - l.quantity, updated_at = NOW()
+		FROM reservation_lines l
+		WHERE l.reservation_id = $1 AND s.sku = l.sku AND s.warehouse_id = l.warehouse_id`, eventFor)
+}
+
+// settle moves a held reservation to a final status, applying stockUpdate
+// to its stock levels. Settling again into an equivalent status is a no-op
+// so that callers can safely retry. A reservation past its expiry can still
+// be released but no longer committed, even if the sweeper has not got to
+// it yet.
+func (r *PostgresRepo) settle(ctx context.Context, op, id string, to model.ReservationStatus, stockUpdate string, eventFor func(*model.Reservation) model.Event) (*model.Reservation, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		status  model.ReservationStatus
+		expired bool
+	)
+	err = tx.QueryRow(ctx, `SELECT status, expires_at <= NOW() FROM reservations WHERE id = $1 FOR UPDATE`, id).
+		Scan(&status, &expired)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) || pgCode(err) == invalidTextRepr {
+			return nil, inventoryErr.ErrReservationNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if status != model.ReservationHeld {
+		if status == to || (to != model.ReservationCommitted && status != model.ReservationCommitted) {
+			return r.getReservation(ctx, tx, id)
+		}
+		return nil, fmt.Errorf("%w: reservation is %s", inventoryErr.ErrReservationNotHeld, status)
+	}
+	if to == model.ReservationCommitted && expired {
+		return nil, inventoryErr.ErrReservationExpired
+	}
+
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM stock_levels s
+		JOIN reservation_lines l ON s.sku = l.sku AND s.warehouse_id = l.warehouse_id
+		WHERE l.reservation_id = $1
+		ORDER BY s.sku, s.warehouse_id
+		FOR UPDATE OF s`, id); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := tx.Exec(ctx, stockUpdate, id); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE reservations SET status = $2, updated_at = NOW() WHERE id = $1`, id, to); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := r.getReservation(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	event := eventFor(res)
+	if err := outbox.Enqueue(ctx, tx, event.Topic, event.Key, event.Payload); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res, nil
+}
+
+func (r *PostgresRepo) ExpiredReservations(ctx context.Context, limit int) ([]string, error) {
+	const op = "repo.ExpiredReservations"
+
+	rows, err := r.db.Query(ctx, `SELECT id FROM reservations
+		WHERE status = $1 AND expires_at < NOW()
+		ORDER BY expires_at LIMIT $2`, model.ReservationHeld, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ids, nil
+}
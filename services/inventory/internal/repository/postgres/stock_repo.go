@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	inventoryErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/pkg/outbox"
+	"github.com/go-market/services/inventory/internal/model"
+)
+
+const stockColumns = `sku, warehouse_id, on_hand, reserved, updated_at`
+
+func (r *PostgresRepo) GetStock(ctx context.Context, sku string) ([]model.StockLevel, error) {
+	const op = "repo.GetStock"
+
+	rows, err := r.db.Query(ctx, `SELECT `+stockColumns+` FROM stock_levels
+		WHERE sku = $1 ORDER BY warehouse_id`, sku)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var levels []model.StockLevel
+	for rows.Next() {
+		var l model.StockLevel
+		if err := rows.Scan(&l.SKU, &l.WarehouseID, &l.OnHand, &l.Reserved, &l.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		l.Available = l.OnHand - l.Reserved
+		levels = append(levels, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(levels) == 0 {
+		return nil, inventoryErr.ErrStockNotFound
+	}
+
+	return levels, nil
+}
+
+func (r *PostgresRepo) AdjustStock(ctx context.Context, adj model.Adjustment, eventFor func(*model.StockLevel) model.Event) (*model.StockLevel, error) {
+	const op = "repo.AdjustStock"
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	// A single upsert keeps the read-modify-write atomic; the table's CHECK
+	// constraints reject adjustments that would eat into reserved stock.
+	l := &model.StockLevel{}
+	err = tx.QueryRow(ctx, `INSERT INTO stock_levels (sku, warehouse_id, on_hand) VALUES ($1, $2, $3)
+		ON CONFLICT (sku, warehouse_id) DO UPDATE
+		SET on_hand = stock_levels.on_hand + EXCLUDED.on_hand, updated_at = NOW()
+		RETURNING `+stockColumns, adj.SKU, adj.WarehouseID, adj.Delta).
+		Scan(&l.SKU, &l.WarehouseID, &l.OnHand, &l.Reserved, &l.UpdatedAt)
+	if err != nil {
+		if pgCode(err) == checkViolation {
+			return nil, fmt.Errorf("%w: on-hand stock cannot drop below reserved or zero", inventoryErr.ErrInvalidStockAdjustment)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	l.Available = l.OnHand - l.Reserved
+
+	event := eventFor(l)
+	if err := outbox.Enqueue(ctx, tx, event.Topic, event.Key, event.Payload); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return l, nil
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-market/services/inventory/internal/model"
+)
+
+type Repository interface {
+	GetStock(ctx context.Context, sku string) ([]model.StockLevel, error)
+	// AdjustStock applies adj to on-hand stock, creating the stock level if
+	// needed. It fails if on-hand would drop below zero or below reserved.
+	AdjustStock(ctx context.Context, adj model.Adjustment, eventFor func(*model.StockLevel) model.Event) (*model.StockLevel, error)
+
+	// Reserve holds items for orderID for ttl. A second call for the same
+	// order returns the existing reservation and replayed is true.
+	Reserve(ctx context.Context, orderID string, items []model.RequestedItem, ttl time.Duration, eventFor func(*model.Reservation) model.Event) (res *model.Reservation, replayed bool, err error)
+	GetReservation(ctx context.Context, id string) (*model.Reservation, error)
+	// Commit deducts held stock from on-hand. Committing a committed
+	// reservation is a no-op.
+	Commit(ctx context.Context, id string, eventFor func(*model.Reservation) model.Event) (*model.Reservation, error)
+	// Release returns held stock to the available pool and marks the
+	// reservation with status (released or expired). Releasing a reservation
+	// that was already released or expired is a no-op.
+	Release(ctx context.Context, id string, status model.ReservationStatus, eventFor func(*model.Reservation) model.Event) (*model.Reservation, error)
+	// ExpiredReservations returns ids of held reservations past their expiry.
+	ExpiredReservations(ctx context.Context, limit int) ([]string, error)
+}
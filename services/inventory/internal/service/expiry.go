@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/go-market/pkg/logger/sl"
+)
+
+// RunExpiry releases reservations whose TTL has passed every interval until
+// ctx is cancelled. Running it in several instances is safe: each release
+// locks its reservation and skips it if someone else got there first.
+func (s *Service) RunExpiry(ctx context.Context, log *slog.Logger, interval time.Duration, batch int) {
+	log = log.With(slog.String("component", "inventory.Expiry"))
+
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	if batch <= 0 {
+		batch = 100
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := s.ReleaseExpired(ctx, batch)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Error("failed to release expired reservations", sl.Err(err))
+				}
+				break
+			}
+			if n > 0 {
+				log.Info("released expired reservations", slog.Int("count", n))
+			}
+			if n < batch {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
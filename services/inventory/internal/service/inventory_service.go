@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	domain "github.com/go-market/pkg/domain/model"
+	inventoryErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/services/inventory/internal/model"
+	inventoryRepo "github.com/go-market/services/inventory/internal/repository"
+)
+
+const (
+	maxItems       = 100
+	maxQuantity    = 1000
+	maxCodeLength  = 64
+	maxOrderLength = 255
+)
+
+type Service struct {
+	repo inventoryRepo.Repository
+	ttl  time.Duration
+}
+
+// New creates the service; ttl is how long a reservation holds stock before
+// it is released automatically.
+func New(repo inventoryRepo.Repository, ttl time.Duration) *Service {
+	return &Service{
+		repo: repo,
+		ttl:  ttl,
+	}
+}
+
+func (s *Service) GetStock(ctx context.Context, sku string) ([]model.StockLevel, error) {
+	sku = strings.TrimSpace(sku)
+	if sku == "" {
+		return nil, inventoryErr.ErrStockNotFound
+	}
+
+	return s.repo.GetStock(ctx, sku)
+}
+
+func (s *Service) AdjustStock(ctx context.Context, adj model.Adjustment) (*model.StockLevel, error) {
+	adj.SKU = strings.TrimSpace(adj.SKU)
+	adj.WarehouseID = strings.TrimSpace(adj.WarehouseID)
+	adj.Reason = strings.TrimSpace(adj.Reason)
+
+	switch {
+	case adj.SKU == "" || len(adj.SKU) > maxCodeLength:
+		return nil, fmt.Errorf("%w: sku must be 1..%d characters", inventoryErr.ErrInvalidStockAdjustment, maxCodeLength)
+	case adj.WarehouseID == "" || len(adj.WarehouseID) > maxCodeLength:
+		return nil, fmt.Errorf("%w: warehouse_id must be 1..%d characters", inventoryErr.ErrInvalidStockAdjustment, maxCodeLength)
+	case adj.Delta == 0:
+		return nil, fmt.Errorf("%w: delta must not be zero", inventoryErr.ErrInvalidStockAdjustment)
+	}
+
+	return s.repo.AdjustStock(ctx, adj, func(l *model.StockLevel) model.Event {
+		return model.Event{
+			Topic: domain.TopicStockAdjusted,
+			Key:   l.SKU,
+			Payload: domain.StockAdjustedEvent{
+				SKU:         l.SKU,
+				WarehouseID: l.WarehouseID,
+				Delta:       adj.Delta,
+				OnHand:      l.OnHand,
+				Reserved:    l.Reserved,
+				Reason:      adj.Reason,
+				OccurredAt:  time.Now().UTC(),
+			},
+		}
+	})
+}
+
+// Reserve holds stock for an order. Calling it again for the same order
+// returns the original reservation with replayed=true, so callers can retry.
+func (s *Service) Reserve(ctx context.Context, orderID string, items []model.RequestedItem) (*model.Reservation, bool, error) {
+	orderID = strings.TrimSpace(orderID)
+	if orderID == "" || len(orderID) > maxOrderLength {
+		return nil, false, fmt.Errorf("%w: order_id must be 1..%d characters", inventoryErr.ErrInvalidReservation, maxOrderLength)
+	}
+
+	items, err := normalizeItems(items)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return s.repo.Reserve(ctx, orderID, items, s.ttl, func(res *model.Reservation) model.Event {
+		return model.Event{
+			Topic: domain.TopicStockReserved,
+			Key:   res.OrderID,
+			Payload: domain.StockReservedEvent{
+				ReservationID: res.ID,
+				OrderID:       res.OrderID,
+				Lines:         stockLines(res.Lines),
+				ExpiresAt:     res.ExpiresAt,
+				OccurredAt:    time.Now().UTC(),
+			},
+		}
+	})
+}
+
+func (s *Service) GetReservation(ctx context.Context, id string) (*model.Reservation, error) {
+	if id == "" {
+		return nil, inventoryErr.ErrReservationNotFound
+	}
+
+	return s.repo.GetReservation(ctx, id)
+}
+
+// Commit turns a hold into a real deduction once the order is paid.
+func (s *Service) Commit(ctx context.Context, id string) (*model.Reservation, error) {
+	if id == "" {
+		return nil, inventoryErr.ErrReservationNotFound
+	}
+
+	return s.repo.Commit(ctx, id, func(res *model.Reservation) model.Event {
+		return model.Event{
+			Topic: domain.TopicStockCommitted,
+			Key:   res.OrderID,
+			Payload: domain.StockCommittedEvent{
+				ReservationID: res.ID,
+				OrderID:       res.OrderID,
+				Lines:         stockLines(res.Lines),
+				OccurredAt:    time.Now().UTC(),
+			},
+		}
+	})
+}
+
+// Release gives held stock back, e.g. when an order is cancelled.
+func (s *Service) Release(ctx context.Context, id string) (*model.Reservation, error) {
+	if id == "" {
+		return nil, inventoryErr.ErrReservationNotFound
+	}
+
+	return s.repo.Release(ctx, id, model.ReservationReleased, releasedEvent(domain.StockReleaseRequested))
+}
+
+// ReleaseExpired releases up to limit reservations whose TTL has passed and
+// reports how many it released.
+func (s *Service) ReleaseExpired(ctx context.Context, limit int) (int, error) {
+	ids, err := s.repo.ExpiredReservations(ctx, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for _, id := range ids {
+		res, err := s.repo.Release(ctx, id, model.ReservationExpired, releasedEvent(domain.StockReleaseExpired))
+		if err != nil {
+			// Committed between the scan and the release: nothing to do.
+			if errors.Is(err, inventoryErr.ErrReservationNotHeld) {
+				continue
+			}
+			return released, err
+		}
+		if res.Status == model.ReservationExpired {
+			released++
+		}
+	}
+
+	return released, nil
+}
+
+func releasedEvent(reason string) func(*model.Reservation) model.Event {
+	return func(res *model.Reservation) model.Event {
+		return model.Event{
+			Topic: domain.TopicStockReleased,
+			Key:   res.OrderID,
+			Payload: domain.StockReleasedEvent{
+				ReservationID: res.ID,
+				OrderID:       res.OrderID,
+				Lines:         stockLines(res.Lines),
+				Reason:        reason,
+				OccurredAt:    time.Now().UTC(),
+			},
+		}
+	}
+}
+
+// normalizeItems validates items, merges duplicate SKUs and sorts them so
+// that stock rows are always locked in the same order.
+func normalizeItems(items []model.RequestedItem) ([]model.RequestedItem, error) {
+	if len(items) == 0 || len(items) > maxItems {
+		return nil, fmt.Errorf("%w: a reservation needs 1..%d items", inventoryErr.ErrInvalidReservation, maxItems)
+	}
+
+	quantities := make(map[string]int, len(items))
+	for i, item := range items {
+		sku := strings.TrimSpace(item.SKU)
+		switch {
+		case sku == "" || len(sku) > maxCodeLength:
+			return nil, fmt.Errorf("%w: item %d needs a sku", inventoryErr.ErrInvalidReservation, i)
+		case item.Quantity <= 0 || item.Quantity > maxQuantity:
+			return nil, fmt.Errorf("%w: item %d quantity must be 1..%d", inventoryErr.ErrInvalidReservation, i, maxQuantity)
+		}
+		quantities[sku] += item.Quantity
+	}
+
+	normalized := make([]model.RequestedItem, 0, len(quantities))
+	for sku, qty := range quantities {
+		normalized = append(normalized, model.RequestedItem{SKU: sku, Quantity: qty})
+	}
+	sort.Slice(normalized, func(i, j int) bool { return normalized[i].SKU < normalized[j].SKU })
+
+	return normalized, nil
+}
+
+func stockLines(lines []model.ReservationLine) []domain.StockLine {
+	out := make([]domain.StockLine, 0, len(lines))
+	for _, l := range lines {
+		out = append(out, domain.StockLine{SKU: l.SKU, WarehouseID: l.WarehouseID, Quantity: l.Quantity})
+	}
+	return out
+}
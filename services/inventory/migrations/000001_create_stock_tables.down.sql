@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS reservation_lines;
+DROP TABLE IF EXISTS reservations;
+DROP TABLE IF EXISTS stock_levels;
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS stock_levels (
+    sku VARCHAR(64) NOT NULL,
+    warehouse_id VARCHAR(64) NOT NULL,
+    on_hand INT NOT NULL DEFAULT 0,
+    reserved INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (sku, warehouse_id),
+    CHECK (reserved >= 0),
+    CHECK (on_hand >= reserved)
+);
+
+CREATE TABLE IF NOT EXISTS reservations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id VARCHAR(255) NOT NULL UNIQUE,
+    status VARCHAR(16) NOT NULL DEFAULT 'held',
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (status IN ('held', 'committed', 'released', 'expired'))
+);
+
+CREATE INDEX IF NOT EXISTS reservations_held_expires_at_idx ON reservations (expires_at) WHERE status = 'held';
+
+CREATE TABLE IF NOT EXISTS reservation_lines (
+    reservation_id UUID NOT NULL REFERENCES reservations (id) ON DELETE CASCADE,
+    sku VARCHAR(64) NOT NULL,
+    warehouse_id VARCHAR(64) NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (reservation_id, sku, warehouse_id),
+    FOREIGN KEY (sku, warehouse_id) REFERENCES stock_levels (sku, warehouse_id)
+);
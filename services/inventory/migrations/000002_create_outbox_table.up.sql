@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    topic VARCHAR(255) NOT NULL,
+    message_key VARCHAR(255) NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMP,
+    -- The relay leases a batch with locked_until and publishes after the
+    -- claim commits, instead of holding row locks while it talks to the
+    -- broker.
+    locked_until TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS outbox_unpublished_idx ON outbox (next_attempt_at, id) WHERE published_at IS NULL;
+
+-- Serves the check for an earlier unpublished message with the same key.
+CREATE INDEX IF NOT EXISTS outbox_unpublished_key_idx ON outbox (topic, message_key, id) WHERE published_at IS NULL;
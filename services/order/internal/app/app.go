@@ -0,0 +1,204 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-market/pkg/authz"
+	domain "github.com/go-market/pkg/domain/model"
+	"github.com/go-market/pkg/kafka"
+	"github.com/go-market/pkg/migrate"
+	"github.com/go-market/pkg/outbox"
+	pkgRedis "github.com/go-market/pkg/redis"
+	"github.com/go-market/pkg/saga"
+	"github.com/go-market/services/order/internal/client/catalog"
+	"github.com/go-market/services/order/internal/client/inventory"
+	"github.com/go-market/services/order/internal/client/payment"
+	"github.com/go-market/services/order/internal/config"
+	orderHTTP "github.com/go-market/services/order/internal/derivery/http"
+	orderKafka "github.com/go-market/services/order/internal/derivery/kafka"
+	"github.com/go-market/services/order/internal/repository/postgres"
+	"github.com/go-market/services/order/internal/service"
+	"github.com/redis/go-redis/v9"
+)
+
+type App struct {
+	server   *http.Server
+	relay    *outbox.Relay
+	checkout *saga.Orchestrator[service.CheckoutData]
+	consumer *kafka.Consumer[domain.CreateOrderCommand]
+	reader   *kafka.GroupReader
+	producer *kafka.Producer
+	repo     *postgres.PostgresRepo
+	rdb      *redis.Client
+	log      *slog.Logger
+}
+
+func NewApp(cfg config.Config, log *slog.Logger) (*App, error) {
+	const op = "app.NewApp"
+
+	logger := log.With(slog.String("op", op))
+
+	repo, err := postgres.New(cfg.DatabaseURL)
+	if err != nil {
+		logger.Error("failed to init postgres", slog.String("op", op), slog.Any("err", err))
+		return nil, err
+	}
+
+	if err := migrateSchema(repo, cfg.MigrationsPath, log); err != nil {
+		logger.Error("failed to migrate database", slog.Any("err", err))
+		repo.Close()
+		return nil, err
+	}
+
+	svc := service.New(repo, catalog.New(cfg.CatalogURL))
+
+	tokens := authz.NewClientCredentials(cfg.Checkout.TokenURL, cfg.Checkout.ClientID, cfg.Checkout.ClientSecret)
+	checkoutSaga := saga.New(repo.Pool(), service.CheckoutSaga(svc,
+		inventory.New(cfg.Checkout.InventoryURL, tokens),
+		payment.New(cfg.Checkout.PaymentURL, tokens),
+	), log, saga.Config{
+		PollInterval: cfg.Checkout.PollInterval,
+		StepTimeout:  cfg.Checkout.StepTimeout,
+		MaxAttempts:  cfg.Checkout.MaxAttempts,
+	})
+
+	producer := kafka.NewProducer(cfg.Kafka.Brokers)
+	relay := outbox.NewRelay(repo.Pool(), producer, log, outbox.Config{
+		PollInterval: cfg.Outbox.PollInterval,
+		BatchSize:    cfg.Outbox.BatchSize,
+		BaseBackoff:  cfg.Outbox.BaseBackoff,
+		MaxBackoff:   cfg.Outbox.MaxBackoff,
+	})
+
+	reader := kafka.NewGroupReader(cfg.Kafka.Brokers, domain.TopicCreateOrderCommand, cfg.Kafka.Group)
+	createOrders := kafka.NewConsumer(reader, producer, orderKafka.CreateOrderHandler(log, svc), log, kafka.ConsumerConfig{
+		Topic:          domain.TopicCreateOrderCommand,
+		Group:          cfg.Kafka.Group,
+		MaxAttempts:    cfg.Kafka.Consumer.MaxAttempts,
+		BaseBackoff:    cfg.Kafka.Consumer.BaseBackoff,
+		MaxBackoff:     cfg.Kafka.Consumer.MaxBackoff,
+		HandlerTimeout: cfg.Kafka.Consumer.HandlerTimeout,
+	})
+
+	orderHandler := orderHTTP.New(log, svc, service.NewCheckout(svc, checkoutSaga))
+
+	rdb := pkgRedis.NewClient(cfg.RedisAddr)
+	if err := rdb.Ping(context.Background()).Err(); err != nil {
+		logger.Warn("redis is unreachable, authenticated requests will be refused", slog.Any("err", err))
+	}
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(middleware.Logger)
+	r.Use(middleware.Recoverer)
+
+	r.Route("/api/v1", func(r chi.Router) {
+		orderHTTP.RegisterOrderRoutes(r, orderHandler, authz.NewRemoteVerifier(cfg.Auth), authz.NewRedisDenyList(rdb))
+	})
+
+	server := &http.Server{
+		Addr:         cfg.HTTPAddr.Address,
+		Handler:      r,
+		ReadTimeout:  cfg.HTTPAddr.Timeout,
+		WriteTimeout: cfg.HTTPAddr.Timeout,
+		IdleTimeout:  cfg.HTTPAddr.IdleTimeout,
+	}
+
+	return &App{
+		server:   server,
+		relay:    relay,
+		checkout: checkoutSaga,
+		consumer: createOrders,
+		reader:   reader,
+		producer: producer,
+		repo:     repo,
+		rdb:      rdb,
+		log:      log,
+	}, nil
+}
+
+// migrateSchema applies pending migrations and then verifies that the schema
+// matches what the repository queries expect.
+func migrateSchema(repo *postgres.PostgresRepo, path string, log *slog.Logger) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	migrator, err := migrate.New(repo.Pool(), path, log)
+	if err != nil {
+		return err
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		return err
+	}
+
+	return repo.CheckSchema(ctx)
+}
+
+func (a *App) Run() error {
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.relay.Run(bgCtx)
+	}()
+
+	// Resumes checkouts left unfinished by a previous process, then keeps
+	// driving new ones.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.checkout.Run(bgCtx)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.consumer.Run(bgCtx)
+	}()
+
+	go func() {
+		if err := a.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			a.log.Error("listen failed", slog.Any("err", err))
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	a.log.Info("shutting down server")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := a.server.Shutdown(ctx)
+
+	// Background workers finish the message or step in hand before exiting.
+	stopBackground()
+	wg.Wait()
+
+	if err := a.reader.Close(); err != nil {
+		a.log.Error("failed to close kafka reader", slog.Any("err", err))
+	}
+
+	if err := a.producer.Close(); err != nil {
+		a.log.Error("failed to close kafka producer", slog.Any("err", err))
+	}
+	if err := a.rdb.Close(); err != nil {
+		a.log.Error("failed to close redis client", slog.Any("err", err))
+	}
+	a.repo.Close()
+
+	return err
+}
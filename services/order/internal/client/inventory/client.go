@@ -0,0 +1,130 @@
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-market/pkg/authz"
+)
+
+var (
+	ErrInsufficientStock = errors.New("insufficient stock")
+	ErrNotFound          = errors.New("reservation not found")
+	// ErrRejected wraps any other 4xx answer: retrying will not help.
+	ErrRejected = errors.New("inventory rejected the request")
+)
+
+type Item struct {
+	SKU      string `json:"sku"`
+	Quantity int    `json:"quantity"`
+}
+
+type Reservation struct {
+	ID      string `json:"id"`
+	OrderID string `json:"order_id"`
+	Status  string `json:"status"`
+}
+
+// Client calls the inventory service's reservation API with a service token.
+type Client struct {
+	baseURL string
+	tokens  authz.TokenSource
+	http    *http.Client
+}
+
+func New(baseURL string, tokens authz.TokenSource) *Client {
+	return &Client{
+		baseURL: baseURL,
+		tokens:  tokens,
+		http:    &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Reserve holds items for orderID. The inventory service deduplicates by
+// order id, so calling it again returns the same reservation.
+func (c *Client) Reserve(ctx context.Context, orderID string, items []Item) (*Reservation, error) {
+	const op = "inventory.Client.Reserve"
+
+	var res Reservation
+	if err := c.do(ctx, "/api/v1/inventory/reservations", map[string]any{"order_id": orderID, "items": items}, &res); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &res, nil
+}
+
+func (c *Client) Commit(ctx context.Context, id string) error {
+	const op = "inventory.Client.Commit"
+
+	if err := c.do(ctx, "/api/v1/inventory/reservations/"+url.PathEscape(id)+"/commit", nil, nil); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (c *Client) Release(ctx context.Context, id string) error {
+	const op = "inventory.Client.Release"
+
+	if err := c.do(ctx, "/api/v1/inventory/reservations/"+url.PathEscape(id)+"/release", nil, nil); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (c *Client) do(ctx context.Context, path string, in, out any) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Data  json.RawMessage `json:"data"`
+		Error string          `json:"error"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&envelope)
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case resp.StatusCode == http.StatusConflict && path == "/api/v1/inventory/reservations":
+		return fmt.Errorf("%w: %s", ErrInsufficientStock, envelope.Error)
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return fmt.Errorf("%w: %d %s", ErrRejected, resp.StatusCode, envelope.Error)
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	if out != nil && len(envelope.Data) > 0 {
+		if err := json.Unmarshal(envelope.Data, out); err != nil {
+			return fmt.Errorf("decode response: %w", err)
+		}
+	}
+	return nil
+}
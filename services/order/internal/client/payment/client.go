@@ -0,0 +1,137 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-market/pkg/authz"
+)
+
+var (
+	ErrDeclined = errors.New("payment declined")
+	// ErrRejected wraps any other 4xx answer: retrying will not help.
+	ErrRejected = errors.New("payment service rejected the request")
+)
+
+type AuthorizeRequest struct {
+	OrderID string `json:"order_id"`
+	Amount  struct {
+		Amount   int64  `json:"amount"`
+		Currency string `json:"currency"`
+	} `json:"amount"`
+	PaymentMethod string `json:"payment_method"`
+}
+
+type Payment struct {
+	ID      string `json:"id"`
+	OrderID string `json:"order_id"`
+	Status  string `json:"status"`
+}
+
+// Client calls the payment service with a service token. Every call carries
+// an idempotency key, so a retried call never charges twice.
+type Client struct {
+	baseURL string
+	tokens  authz.TokenSource
+	http    *http.Client
+}
+
+func New(baseURL string, tokens authz.TokenSource) *Client {
+	return &Client{
+		baseURL: baseURL,
+		tokens:  tokens,
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *Client) Authorize(ctx context.Context, idempotencyKey string, in AuthorizeRequest) (*Payment, error) {
+	const op = "payment.Client.Authorize"
+
+	var p Payment
+	if err := c.do(ctx, http.MethodPost, idempotencyKey, "/api/v1/payments", in, &p); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &p, nil
+}
+
+func (c *Client) Void(ctx context.Context, idempotencyKey, id string) (*Payment, error) {
+	const op = "payment.Client.Void"
+
+	var p Payment
+	if err := c.do(ctx, http.MethodPost, idempotencyKey, "/api/v1/payments/"+url.PathEscape(id)+"/void", nil, &p); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &p, nil
+}
+
+// ListByOrder returns the payments made for orderID.
+func (c *Client) ListByOrder(ctx context.Context, orderID string) ([]Payment, error) {
+	const op = "payment.Client.ListByOrder"
+
+	var payments []Payment
+	if err := c.do(ctx, http.MethodGet, "", "/api/v1/payments?order_id="+url.QueryEscape(orderID), nil, &payments); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return payments, nil
+}
+
+func (c *Client) do(ctx context.Context, method, idempotencyKey, path string, in, out any) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Data  json.RawMessage `json:"data"`
+		Error string          `json:"error"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&envelope)
+
+	switch {
+	case resp.StatusCode == http.StatusPaymentRequired:
+		return fmt.Errorf("%w: %s", ErrDeclined, envelope.Error)
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests ||
+		resp.Header.Get("Retry-After") != "":
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return fmt.Errorf("%w: %d %s", ErrRejected, resp.StatusCode, envelope.Error)
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	if out != nil && len(envelope.Data) > 0 {
+		if err := json.Unmarshal(envelope.Data, out); err != nil {
+			return fmt.Errorf("decode response: %w", err)
+		}
+	}
+	return nil
+}
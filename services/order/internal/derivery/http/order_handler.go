@@ -0,0 +1,224 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/go-market/pkg/authz"
+	"github.com/go-market/services/order/internal/model"
+	"github.com/go-market/services/order/internal/service"
+)
+
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+type OrderHandler struct {
+	log      *slog.Logger
+	svc      *service.Service
+	checkout *service.Checkout
+}
+
+func New(log *slog.Logger, svc *service.Service, checkout *service.Checkout) *OrderHandler {
+	return &OrderHandler{
+		log:      log,
+		svc:      svc,
+		checkout: checkout,
+	}
+}
+
+// CreateOrderRequest names the products and quantities to order. Prices are
+// looked up in the catalog, so any the client sends are ignored.
+type CreateOrderRequest struct {
+	Items []CreateOrderItem `json:"items"`
+}
+
+type CreateOrderItem struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+}
+
+type CancelOrderRequest struct {
+	Reason string `json:"reason"`
+}
+
+type CheckoutRequest struct {
+	PaymentMethod string `json:"payment_method"`
+}
+
+type TransitionRequest struct {
+	Status model.Status `json:"status"`
+	Reason string       `json:"reason"`
+}
+
+func (h *OrderHandler) Create(w http.ResponseWriter, r *http.Request) {
+	const op = "OrderHandler.Create"
+	log := h.log.With(slog.String("op", op))
+
+	principal, _ := authz.FromContext(r.Context())
+
+	var req CreateOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("failed to decode request", slog.String("error", err.Error()))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	items := make([]model.Item, 0, len(req.Items))
+	for _, item := range req.Items {
+		items = append(items, model.Item{ProductID: item.ProductID, Quantity: item.Quantity})
+	}
+
+	order, replayed, err := h.svc.Create(r.Context(), principal.UserID, r.Header.Get(idempotencyKeyHeader), items)
+	if err != nil {
+		log.Error("failed to create order", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to create order")
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/orders/"+order.ID)
+	if replayed {
+		w.Header().Set(idempotentReplayedHeader, "true")
+		render.JSON(w, r, SuccessResponse{Data: order})
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, SuccessResponse{Data: order})
+}
+
+func (h *OrderHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	const op = "OrderHandler.GetByID"
+	log := h.log.With(slog.String("op", op))
+
+	principal, _ := authz.FromContext(r.Context())
+
+	order, err := h.svc.Get(r.Context(), principal, chi.URLParam(r, "id"))
+	if err != nil {
+		log.Error("failed to get order", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to get order")
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Data: order})
+}
+
+func (h *OrderHandler) List(w http.ResponseWriter, r *http.Request) {
+	const op = "OrderHandler.List"
+	log := h.log.With(slog.String("op", op))
+
+	principal, _ := authz.FromContext(r.Context())
+
+	orders, err := h.svc.ListForUser(r.Context(), principal.UserID)
+	if err != nil {
+		log.Error("failed to list orders", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to list orders")
+		return
+	}
+
+	if orders == nil {
+		orders = []model.Order{}
+	}
+	render.JSON(w, r, SuccessResponse{Data: orders})
+}
+
+func (h *OrderHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	const op = "OrderHandler.Cancel"
+	log := h.log.With(slog.String("op", op))
+
+	principal, _ := authz.FromContext(r.Context())
+
+	var req CancelOrderRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Error("failed to decode request", slog.String("error", err.Error()))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, ErrorResponse{Error: "invalid request body"})
+			return
+		}
+	}
+
+	order, err := h.svc.Cancel(r.Context(), principal, chi.URLParam(r, "id"), req.Reason)
+	if err != nil {
+		log.Error("failed to cancel order", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to cancel order")
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Data: order})
+}
+
+// Transition lets staff drive an order through the state machine, e.g. to
+// mark it shipped or delivered.
+func (h *OrderHandler) Transition(w http.ResponseWriter, r *http.Request) {
+	const op = "OrderHandler.Transition"
+	log := h.log.With(slog.String("op", op))
+
+	var req TransitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("failed to decode request", slog.String("error", err.Error()))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	order, err := h.svc.Transition(r.Context(), chi.URLParam(r, "id"), req.Status, req.Reason)
+	if err != nil {
+		log.Error("failed to transition order", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to update order status")
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Data: order})
+}
+
+// StartCheckout starts the checkout saga for a pending order and answers 202;
+// clients follow its progress with GET /orders/{id}/checkout.
+func (h *OrderHandler) StartCheckout(w http.ResponseWriter, r *http.Request) {
+	const op = "OrderHandler.StartCheckout"
+	log := h.log.With(slog.String("op", op))
+
+	principal, _ := authz.FromContext(r.Context())
+
+	var req CheckoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("failed to decode request", slog.String("error", err.Error()))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	checkout, created, err := h.checkout.Start(r.Context(), principal, chi.URLParam(r, "id"), req.PaymentMethod)
+	if err != nil {
+		log.Error("failed to start checkout", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to start checkout")
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/orders/"+checkout.ID+"/checkout")
+	if created {
+		render.Status(r, http.StatusAccepted)
+	}
+	render.JSON(w, r, SuccessResponse{Data: checkout})
+}
+
+func (h *OrderHandler) CheckoutStatus(w http.ResponseWriter, r *http.Request) {
+	const op = "OrderHandler.CheckoutStatus"
+	log := h.log.With(slog.String("op", op))
+
+	principal, _ := authz.FromContext(r.Context())
+
+	checkout, err := h.checkout.Status(r.Context(), principal, chi.URLParam(r, "id"))
+	if err != nil {
+		log.Error("failed to get checkout", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to get checkout")
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Data: checkout})
+}
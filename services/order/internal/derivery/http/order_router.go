@@ -0,0 +1,21 @@
+package http
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-market/pkg/authz"
+)
+
+func RegisterOrderRoutes(r chi.Router, h *OrderHandler, verifier *authz.Verifier, denied authz.DenyList) {
+	r.Route("/orders", func(r chi.Router) {
+		r.Use(authz.Authenticate(verifier, authz.WithDenyList(denied)))
+
+		r.Post("/", h.Create)
+		r.Get("/", h.List)
+		r.Get("/{id}", h.GetByID)
+		r.Post("/{id}/cancel", h.Cancel)
+		r.Post("/{id}/checkout", h.StartCheckout)
+		r.Get("/{id}/checkout", h.CheckoutStatus)
+
+		r.With(authz.RequirePermission(authz.PermOrdersManage)).Post("/{id}/transitions", h.Transition)
+	})
+}
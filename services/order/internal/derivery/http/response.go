@@ -0,0 +1,39 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/render"
+	orderErr "github.com/go-market/pkg/errs"
+)
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type SuccessResponse struct {
+	Data    interface{} `json:"data,omitempty"`
+	Message string      `json:"message,omitempty"`
+}
+
+// renderError maps order errors to status codes. Unknown errors are
+// reported as fallback so internals never reach the client.
+func renderError(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+	status := http.StatusInternalServerError
+	msg := fallback
+
+	switch {
+	case errors.Is(err, orderErr.ErrInvalidOrder), errors.Is(err, orderErr.ErrIdempotencyKeyRequired):
+		status, msg = http.StatusBadRequest, err.Error()
+	case errors.Is(err, orderErr.ErrOrderNotFound), errors.Is(err, orderErr.ErrCheckoutNotFound):
+		status, msg = http.StatusNotFound, err.Error()
+	case errors.Is(err, orderErr.ErrInvalidTransition):
+		status, msg = http.StatusConflict, err.Error()
+	case errors.Is(err, orderErr.ErrIdempotencyKeyReused):
+		status, msg = http.StatusUnprocessableEntity, err.Error()
+	}
+
+	render.Status(r, status)
+	render.JSON(w, r, ErrorResponse{Error: msg})
+}
@@ -0,0 +1,42 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	domain "github.com/go-market/pkg/domain/model"
+	orderErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/pkg/kafka"
+	"github.com/go-market/services/order/internal/model"
+	"github.com/go-market/services/order/internal/service"
+)
+
+// CreateOrderHandler places orders requested by the cart's checkout. The
+// command id doubles as the idempotency key, so a redelivered command
+// returns the order created the first time. Prices in the command are only
+// what the cart showed; the order is priced from the catalog like any other.
+func CreateOrderHandler(log *slog.Logger, svc *service.Service) kafka.Handler[domain.CreateOrderCommand] {
+	return func(ctx context.Context, _ kafka.Message, cmd domain.CreateOrderCommand) error {
+		const op = "kafka.CreateOrderHandler"
+		log := log.With(slog.String("op", op), slog.String("command_id", cmd.CommandID))
+
+		items := make([]model.Item, 0, len(cmd.Items))
+		for _, item := range cmd.Items {
+			items = append(items, model.Item{ProductID: item.ProductID, Quantity: item.Quantity})
+		}
+
+		order, replayed, err := svc.Create(ctx, cmd.UserID, cmd.CommandID, items)
+		if err != nil {
+			if errors.Is(err, orderErr.ErrInvalidOrder) ||
+				errors.Is(err, orderErr.ErrIdempotencyKeyRequired) ||
+				errors.Is(err, orderErr.ErrIdempotencyKeyReused) {
+				return kafka.Permanent(err)
+			}
+			return err
+		}
+
+		log.Info("order created from command", slog.String("order_id", order.ID), slog.Bool("replayed", replayed))
+		return nil
+	}
+}
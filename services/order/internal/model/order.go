@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+type Item struct {
+	ProductID string `json:"product_id"`
+	SKU       string `json:"sku"`
+	Name      string `json:"name"`
+	Quantity  int    `json:"quantity"`
+	UnitPrice Money  `json:"unit_price"`
+}
+
+type Order struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Status    Status    `json:"status"`
+	Total     Money     `json:"total"`
+	Items     []Item    `json:"items"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IdempotencyKey identifies one logical create request from a user. Replays
+// with the same key and body return the order created the first time.
+type IdempotencyKey struct {
+	Key         string
+	RequestHash string
+}
+
+// Event is a domain event written to the outbox together with the change
+// that produced it.
+type Event struct {
+	Topic   string
+	Key     string
+	Payload interface{}
+}
@@ -0,0 +1,41 @@
+package model
+
+type Status string
+
+const (
+	StatusPending         Status = "pending"
+	StatusAwaitingPayment Status = "awaiting_payment"
+	StatusPaid            Status = "paid"
+	StatusShipped         Status = "shipped"
+	StatusDelivered       Status = "delivered"
+	StatusCancelled       Status = "cancelled"
+	StatusRefunded        Status = "refunded"
+)
+
+// transitions is the order state machine. Anything not listed is illegal;
+// cancelled and refunded are terminal.
+var transitions = map[Status][]Status{
+	StatusPending:         {StatusAwaitingPayment, StatusCancelled},
+	StatusAwaitingPayment: {StatusPaid, StatusCancelled},
+	StatusPaid:            {StatusShipped, StatusRefunded},
+	StatusShipped:         {StatusDelivered},
+	StatusDelivered:       {StatusRefunded},
+}
+
+func (s Status) Valid() bool {
+	switch s {
+	case StatusPending, StatusAwaitingPayment, StatusPaid, StatusShipped,
+		StatusDelivered, StatusCancelled, StatusRefunded:
+		return true
+	}
+	return false
+}
+
+func (s Status) CanTransitionTo(next Status) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,278 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	orderErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/pkg/migrate"
+	"github.com/go-market/pkg/outbox"
+	"github.com/go-market/services/order/internal/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const invalidTextRepr = "22P02"
+
+// schema lists every column the queries below rely on.
+var schema = map[string][]string{
+	"orders":           {"id", "user_id", "status", "total_minor", "currency", "created_at", "updated_at"},
+	"order_items":      {"id", "order_id", "product_id", "sku", "name", "quantity", "unit_price_minor"},
+	"idempotency_keys": {"user_id", "key", "request_hash", "order_id", "created_at"},
+	"outbox":           {"id", "topic", "message_key", "payload", "attempts", "last_error", "next_attempt_at", "published_at", "locked_until"},
+	"sagas":            {"id", "name", "status", "step", "data", "attempts", "last_error", "next_attempt_at", "lease_owner", "locked_until", "updated_at"},
+}
+
+const orderColumns = `id, user_id, status, total_minor, currency, created_at, updated_at`
+
+type PostgresRepo struct {
+	db *pgxpool.Pool
+}
+
+func New(db_url string) (*PostgresRepo, error) {
+	ctx := context.Background()
+	db, err := pgxpool.New(ctx, db_url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect database %w", err)
+	}
+
+	return &PostgresRepo{db: db}, nil
+}
+
+// Pool exposes the connection pool for components that share the database,
+// such as the outbox relay.
+func (r *PostgresRepo) Pool() *pgxpool.Pool {
+	return r.db
+}
+
+func (r *PostgresRepo) Create(ctx context.Context, order model.Order, key model.IdempotencyKey, eventFor func(*model.Order) model.Event) (*model.Order, bool, error) {
+	const op = "repo.Create"
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	// A concurrent request with the same key blocks on this insert until the
+	// first one commits, and then falls through to the replay branch.
+	tag, err := tx.Exec(ctx, `INSERT INTO idempotency_keys (user_id, key, request_hash)
+		VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`, order.UserID, key.Key, key.RequestHash)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		var (
+			hash    string
+			orderID *string
+		)
+		err := tx.QueryRow(ctx, `SELECT request_hash, order_id FROM idempotency_keys
+			WHERE user_id = $1 AND key = $2`, order.UserID, key.Key).Scan(&hash, &orderID)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %w", op, err)
+		}
+		if hash != key.RequestHash || orderID == nil {
+			return nil, false, orderErr.ErrIdempotencyKeyReused
+		}
+
+		existing, err := r.getByID(ctx, tx, *orderID)
+		if err != nil {
+			return nil, false, err
+		}
+		return existing, true, nil
+	}
+
+	o := &model.Order{}
+	err = tx.QueryRow(ctx, `INSERT INTO orders (user_id, status, total_minor, currency)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+orderColumns, order.UserID, order.Status, order.Total.Amount, order.Total.Currency).
+		Scan(&o.ID, &o.UserID, &o.Status, &o.Total.Amount, &o.Total.Currency, &o.CreatedAt, &o.UpdatedAt)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, item := range order.Items {
+		_, err := tx.Exec(ctx, `INSERT INTO order_items (order_id, product_id, sku, name, quantity, unit_price_minor)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			o.ID, item.ProductID, item.SKU, item.Name, item.Quantity, item.UnitPrice.Amount)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == invalidTextRepr {
+				return nil, false, fmt.Errorf("%w: invalid product id", orderErr.ErrInvalidOrder)
+			}
+			return nil, false, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	o.Items = order.Items
+
+	if _, err := tx.Exec(ctx, `UPDATE idempotency_keys SET order_id = $1 WHERE user_id = $2 AND key = $3`,
+		o.ID, order.UserID, key.Key); err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	event := eventFor(o)
+	if err := outbox.Enqueue(ctx, tx, event.Topic, event.Key, event.Payload); err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return o, false, nil
+}
+
+func (r *PostgresRepo) GetByIdempotencyKey(ctx context.Context, userID string, key model.IdempotencyKey) (*model.Order, error) {
+	const op = "repo.GetByIdempotencyKey"
+
+	var (
+		hash    string
+		orderID *string
+	)
+	err := r.db.QueryRow(ctx, `SELECT request_hash, order_id FROM idempotency_keys
+		WHERE user_id = $1 AND key = $2`, userID, key.Key).Scan(&hash, &orderID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, orderErr.ErrOrderNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if hash != key.RequestHash {
+		return nil, orderErr.ErrIdempotencyKeyReused
+	}
+	if orderID == nil {
+		return nil, orderErr.ErrOrderNotFound
+	}
+
+	return r.getByID(ctx, r.db, *orderID)
+}
+
+func (r *PostgresRepo) GetByID(ctx context.Context, id string) (*model.Order, error) {
+	return r.getByID(ctx, r.db, id)
+}
+
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func (r *PostgresRepo) getByID(ctx context.Context, q querier, id string) (*model.Order, error) {
+	const op = "repo.GetByID"
+
+	o := &model.Order{}
+	err := q.QueryRow(ctx, `SELECT `+orderColumns+` FROM orders WHERE id = $1`, id).
+		Scan(&o.ID, &o.UserID, &o.Status, &o.Total.Amount, &o.Total.Currency, &o.CreatedAt, &o.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.Is(err, pgx.ErrNoRows) || (errors.As(err, &pgErr) && pgErr.Code == invalidTextRepr) {
+			return nil, orderErr.ErrOrderNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if o.Items, err = r.items(ctx, q, o); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return o, nil
+}
+
+func (r *PostgresRepo) ListByUser(ctx context.Context, userID string, limit int) ([]model.Order, error) {
+	const op = "repo.ListByUser"
+
+	rows, err := r.db.Query(ctx, `SELECT `+orderColumns+` FROM orders
+		WHERE user_id = $1 ORDER BY created_at DESC, id LIMIT $2`, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var orders []model.Order
+	for rows.Next() {
+		var o model.Order
+		if err := rows.Scan(&o.ID, &o.UserID, &o.Status, &o.Total.Amount, &o.Total.Currency, &o.CreatedAt, &o.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		orders = append(orders, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i := range orders {
+		if orders[i].Items, err = r.items(ctx, r.db, &orders[i]); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return orders, nil
+}
+
+func (r *PostgresRepo) items(ctx context.Context, q querier, o *model.Order) ([]model.Item, error) {
+	rows, err := q.Query(ctx, `SELECT product_id, sku, name, quantity, unit_price_minor
+		FROM order_items WHERE order_id = $1 ORDER BY id`, o.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []model.Item{}
+	for rows.Next() {
+		item := model.Item{UnitPrice: model.Money{Currency: o.Total.Currency}}
+		if err := rows.Scan(&item.ProductID, &item.SKU, &item.Name, &item.Quantity, &item.UnitPrice.Amount); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+func (r *PostgresRepo) UpdateStatus(ctx context.Context, id string, from, to model.Status, event model.Event) (*model.Order, error) {
+	const op = "repo.UpdateStatus"
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	// The status guard makes this a compare-and-set: if someone else moved
+	// the order in the meantime, nothing is updated.
+	tag, err := tx.Exec(ctx, `UPDATE orders SET status = $1, updated_at = NOW()
+		WHERE id = $2 AND status = $3`, to, id, from)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, orderErr.ErrInvalidTransition
+	}
+
+	if err := outbox.Enqueue(ctx, tx, event.Topic, event.Key, event.Payload); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	o, err := r.getByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return o, nil
+}
+
+// CheckSchema fails when the database is missing a column used by the
+// repository queries.
+func (r *PostgresRepo) CheckSchema(ctx context.Context) error {
+	return migrate.VerifyColumns(ctx, r.db, schema)
+}
+
+func (r *PostgresRepo) Close() {
+	r.db.Close()
+}
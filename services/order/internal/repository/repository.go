@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/go-market/services/order/internal/model"
+)
+
+type Repository interface {
+	// Create stores the order and the event built by eventFor unless key was
+	// seen before for the same user, in which case the original order is
+	// returned and replayed is true.
+	Create(ctx context.Context, order model.Order, key model.IdempotencyKey, eventFor func(*model.Order) model.Event) (created *model.Order, replayed bool, err error)
+	// GetByIdempotencyKey returns the order userID created with key. It
+	// returns ErrOrderNotFound if the key has not been used and
+	// ErrIdempotencyKeyReused if it was used for a different request.
+	GetByIdempotencyKey(ctx context.Context, userID string, key model.IdempotencyKey) (*model.Order, error)
+	GetByID(ctx context.Context, id string) (*model.Order, error)
+	ListByUser(ctx context.Context, userID string, limit int) ([]model.Order, error)
+	// UpdateStatus moves the order from `from` to `to` only if it is still in
+	// `from`, and records event in the same transaction.
+	UpdateStatus(ctx context.Context, id string, from, to model.Status, event model.Event) (*model.Order, error)
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-market/pkg/authz"
+	orderErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/pkg/saga"
+	"github.com/go-market/services/order/internal/client/inventory"
+	"github.com/go-market/services/order/internal/client/payment"
+	"github.com/go-market/services/order/internal/model"
+)
+
+const CheckoutSagaName = "checkout"
+
+// CheckoutData is the persisted state of one checkout saga. Ids returned by
+// other services are recorded so compensations know what to undo.
+type CheckoutData struct {
+	OrderID       string `json:"order_id"`
+	PaymentMethod string `json:"payment_method"`
+	ReservationID string `json:"reservation_id,omitempty"`
+	PaymentID     string `json:"payment_id,omitempty"`
+}
+
+type Inventory interface {
+	Reserve(ctx context.Context, orderID string, items []inventory.Item) (*inventory.Reservation, error)
+	Commit(ctx context.Context, id string) error
+	Release(ctx context.Context, id string) error
+}
+
+type Payments interface {
+	Authorize(ctx context.Context, idempotencyKey string, in payment.AuthorizeRequest) (*payment.Payment, error)
+	Void(ctx context.Context, idempotencyKey, id string) (*payment.Payment, error)
+	ListByOrder(ctx context.Context, orderID string) ([]payment.Payment, error)
+}
+
+// CheckoutSaga takes a pending order through stock reservation, payment
+// authorization and confirmation:
+//
+//	accept_order       check the order is pending   / cancel_order
+//	reserve_stock      hold stock for the order     / release_stock
+//	authorize_payment  authorize the order total    / void_payment
+//	confirm_order      awaiting_payment, commit stock
+//
+// Remote calls are keyed by the order id, so a step repeated after a crash
+// never reserves or charges twice.
+func CheckoutSaga(s *Service, inv Inventory, pay Payments) saga.Definition[CheckoutData] {
+	c := checkoutSteps{orders: s, inventory: inv, payments: pay}
+
+	return saga.Definition[CheckoutData]{
+		Name: CheckoutSagaName,
+		Steps: []saga.Step[CheckoutData]{
+			{Name: "accept_order", Action: c.acceptOrder, Compensate: c.cancelOrder},
+			{Name: "reserve_stock", Action: c.reserveStock, Compensate: c.releaseStock},
+			{Name: "authorize_payment", Action: c.authorizePayment, Compensate: c.voidPayment},
+			{Name: "confirm_order", Action: c.confirmOrder},
+		},
+	}
+}
+
+type checkoutSteps struct {
+	orders    *Service
+	inventory Inventory
+	payments  Payments
+}
+
+func (c checkoutSteps) order(ctx context.Context, id string) (*model.Order, error) {
+	order, err := c.orders.repo.GetByID(ctx, id)
+	if errors.Is(err, orderErr.ErrOrderNotFound) {
+		return nil, saga.Permanent(err)
+	}
+	return order, err
+}
+
+func (c checkoutSteps) acceptOrder(ctx context.Context, d *CheckoutData) error {
+	order, err := c.order(ctx, d.OrderID)
+	if err != nil {
+		return err
+	}
+	if order.Status != model.StatusPending {
+		return saga.Permanent(fmt.Errorf("%w: order is %s", orderErr.ErrInvalidTransition, order.Status))
+	}
+	return nil
+}
+
+func (c checkoutSteps) cancelOrder(ctx context.Context, d *CheckoutData) error {
+	order, err := c.order(ctx, d.OrderID)
+	if err != nil {
+		return err
+	}
+	if !order.Status.CanTransitionTo(model.StatusCancelled) {
+		return nil
+	}
+
+	_, err = c.orders.transition(ctx, order, model.StatusCancelled, "checkout failed")
+	return err
+}
+
+func (c checkoutSteps) reserveStock(ctx context.Context, d *CheckoutData) error {
+	order, err := c.order(ctx, d.OrderID)
+	if err != nil {
+		return err
+	}
+
+	items := make([]inventory.Item, 0, len(order.Items))
+	for _, item := range order.Items {
+		items = append(items, inventory.Item{SKU: item.SKU, Quantity: item.Quantity})
+	}
+
+	res, err := c.inventory.Reserve(ctx, order.ID, items)
+	if err != nil {
+		if errors.Is(err, inventory.ErrInsufficientStock) || errors.Is(err, inventory.ErrRejected) {
+			return saga.Permanent(err)
+		}
+		return err
+	}
+
+	d.ReservationID = res.ID
+	return nil
+}
+
+// releaseStock has nothing to release if the reservation id never came back;
+// a reservation made in that case runs out on its own TTL.
+func (c checkoutSteps) releaseStock(ctx context.Context, d *CheckoutData) error {
+	if d.ReservationID == "" {
+		return nil
+	}
+
+	err := c.inventory.Release(ctx, d.ReservationID)
+	if errors.Is(err, inventory.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (c checkoutSteps) authorizePayment(ctx context.Context, d *CheckoutData) error {
+	order, err := c.order(ctx, d.OrderID)
+	if err != nil {
+		return err
+	}
+
+	p, err := c.authorize(ctx, order, d.PaymentMethod)
+	if err != nil {
+		if errors.Is(err, payment.ErrDeclined) || errors.Is(err, payment.ErrRejected) {
+			return saga.Permanent(err)
+		}
+		return err
+	}
+
+	d.PaymentID = p.ID
+	return nil
+}
+
+func (c checkoutSteps) authorize(ctx context.Context, order *model.Order, method string) (*payment.Payment, error) {
+	req := payment.AuthorizeRequest{OrderID: order.ID, PaymentMethod: method}
+	req.Amount.Amount = order.Total.Amount
+	req.Amount.Currency = order.Total.Currency
+
+	return c.payments.Authorize(ctx, "checkout:"+order.ID+":authorize", req)
+}
+
+func (c checkoutSteps) voidPayment(ctx context.Context, d *CheckoutData) error {
+	if d.PaymentID == "" {
+		// The authorize reply may have been lost. Look the payment up by
+		// order rather than replaying the authorization, which would charge
+		// the card if the first attempt never reached the payment service.
+		payments, err := c.payments.ListByOrder(ctx, d.OrderID)
+		if err != nil {
+			return err
+		}
+		for _, p := range payments {
+			if p.Status == "pending" || p.Status == "authorized" {
+				d.PaymentID = p.ID
+			}
+		}
+		if d.PaymentID == "" {
+			return nil
+		}
+	}
+
+	_, err := c.payments.Void(ctx, "checkout:"+d.OrderID+":void", d.PaymentID)
+	if errors.Is(err, payment.ErrRejected) {
+		return nil
+	}
+	return err
+}
+
+func (c checkoutSteps) confirmOrder(ctx context.Context, d *CheckoutData) error {
+	order, err := c.order(ctx, d.OrderID)
+	if err != nil {
+		return err
+	}
+
+	switch order.Status {
+	case model.StatusPending:
+		if _, err := c.orders.transition(ctx, order, model.StatusAwaitingPayment, "checkout confirmed"); err != nil {
+			if errors.Is(err, orderErr.ErrInvalidTransition) {
+				return saga.Permanent(err)
+			}
+			return err
+		}
+	case model.StatusAwaitingPayment:
+	default:
+		return saga.Permanent(fmt.Errorf("%w: order is %s", orderErr.ErrInvalidTransition, order.Status))
+	}
+
+	if err := c.inventory.Commit(ctx, d.ReservationID); err != nil {
+		if errors.Is(err, inventory.ErrRejected) || errors.Is(err, inventory.ErrNotFound) {
+			return saga.Permanent(err)
+		}
+		return err
+	}
+	return nil
+}
+
+// Checkout starts and reports checkout sagas on behalf of API callers.
+type Checkout struct {
+	orders *Service
+	sagas  *saga.Orchestrator[CheckoutData]
+}
+
+func NewCheckout(orders *Service, sagas *saga.Orchestrator[CheckoutData]) *Checkout {
+	return &Checkout{
+		orders: orders,
+		sagas:  sagas,
+	}
+}
+
+// Start begins checkout of a pending order. An order is checked out at most
+// once; calling Start again returns the existing saga with created=false.
+func (c *Checkout) Start(ctx context.Context, p authz.Principal, orderID, paymentMethod string) (*saga.Instance[CheckoutData], bool, error) {
+	order, err := c.orders.Get(ctx, p, orderID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if existing, err := c.sagas.Get(ctx, order.ID); err == nil {
+		return existing, false, nil
+	} else if !errors.Is(err, saga.ErrNotFound) {
+		return nil, false, err
+	}
+
+	paymentMethod = strings.TrimSpace(paymentMethod)
+	if paymentMethod == "" {
+		return nil, false, fmt.Errorf("%w: payment_method is required", orderErr.ErrInvalidOrder)
+	}
+	if order.Status != model.StatusPending {
+		return nil, false, fmt.Errorf("%w: order is %s", orderErr.ErrInvalidTransition, order.Status)
+	}
+
+	return c.sagas.Start(ctx, order.ID, CheckoutData{OrderID: order.ID, PaymentMethod: paymentMethod})
+}
+
+func (c *Checkout) Status(ctx context.Context, p authz.Principal, orderID string) (*saga.Instance[CheckoutData], error) {
+	order, err := c.orders.Get(ctx, p, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	inst, err := c.sagas.Get(ctx, order.ID)
+	if errors.Is(err, saga.ErrNotFound) {
+		return nil, orderErr.ErrCheckoutNotFound
+	}
+	return inst, err
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/go-market/services/order/internal/client/payment"
+)
+
+// fakePayments records the calls made to the payment service.
+type fakePayments struct {
+	byOrder []payment.Payment
+	calls   []string
+}
+
+func (f *fakePayments) Authorize(_ context.Context, key string, _ payment.AuthorizeRequest) (*payment.Payment, error) {
+	f.calls = append(f.calls, "authorize "+key)
+	return &payment.Payment{ID: "new"}, nil
+}
+
+func (f *fakePayments) Void(_ context.Context, _, id string) (*payment.Payment, error) {
+	f.calls = append(f.calls, "void "+id)
+	return &payment.Payment{ID: id, Status: "voided"}, nil
+}
+
+func (f *fakePayments) ListByOrder(_ context.Context, orderID string) ([]payment.Payment, error) {
+	f.calls = append(f.calls, "list "+orderID)
+	return f.byOrder, nil
+}
+
+func TestVoidPayment(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      CheckoutData
+		byOrder   []payment.Payment
+		wantCalls []string
+	}{
+		{
+			name:      "known payment is voided",
+			data:      CheckoutData{OrderID: "o1", PaymentID: "p1"},
+			wantCalls: []string{"void p1"},
+		},
+		{
+			name:      "lost authorize reply is found by order",
+			data:      CheckoutData{OrderID: "o1"},
+			byOrder:   []payment.Payment{{ID: "p1", Status: "declined"}, {ID: "p2", Status: "authorized"}},
+			wantCalls: []string{"list o1", "void p2"},
+		},
+		{
+			name:      "nothing to void when no payment was made",
+			data:      CheckoutData{OrderID: "o1"},
+			wantCalls: []string{"list o1"},
+		},
+		{
+			name:      "settled payments are left alone",
+			data:      CheckoutData{OrderID: "o1"},
+			byOrder:   []payment.Payment{{ID: "p1", Status: "voided"}},
+			wantCalls: []string{"list o1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pay := &fakePayments{byOrder: tt.byOrder}
+			c := checkoutSteps{payments: pay}
+
+			d := tt.data
+			if err := c.voidPayment(context.Background(), &d); err != nil {
+				t.Fatalf("voidPayment() error = %v", err)
+			}
+			if !slices.Equal(pay.calls, tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", pay.calls, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-market/pkg/authz"
+	domain "github.com/go-market/pkg/domain/model"
+	orderErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/services/order/internal/client/catalog"
+	"github.com/go-market/services/order/internal/model"
+	orderRepo "github.com/go-market/services/order/internal/repository"
+)
+
+const (
+	maxItems        = 100
+	maxQuantity     = 1000
+	maxKeyLength    = 255
+	defaultPageSize = 50
+)
+
+var currencyRe = regexp.MustCompile(`^[A-Z]{3}$`)
+
+type Catalog interface {
+	GetProduct(ctx context.Context, id string) (*catalog.Product, error)
+}
+
+type Service struct {
+	repo    orderRepo.Repository
+	catalog Catalog
+}
+
+func New(repo orderRepo.Repository, catalog Catalog) *Service {
+	return &Service{
+		repo:    repo,
+		catalog: catalog,
+	}
+}
+
+// Create places a new pending order. Only the product id and quantity of
+// each item are taken from the caller; sku, name and price come from the
+// catalog. Retrying with the same idempotency key and the same items returns
+// the original order and replayed=true without consulting the catalog;
+// reusing the key for different items is rejected.
+func (s *Service) Create(ctx context.Context, userID, idempotencyKey string, items []model.Item) (*model.Order, bool, error) {
+	idempotencyKey = strings.TrimSpace(idempotencyKey)
+	if idempotencyKey == "" || len(idempotencyKey) > maxKeyLength {
+		return nil, false, orderErr.ErrIdempotencyKeyRequired
+	}
+
+	lines, err := orderLines(items)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// The hash covers what was asked for rather than what it cost, so a
+	// retry still matches after a price change in the catalog.
+	hash, err := requestHash(lines)
+	if err != nil {
+		return nil, false, err
+	}
+
+	key := model.IdempotencyKey{Key: idempotencyKey, RequestHash: hash}
+
+	// A retry is answered from the stored order, so it succeeds even when
+	// the catalog is down or a product has been delisted since.
+	existing, err := s.repo.GetByIdempotencyKey(ctx, userID, key)
+	switch {
+	case err == nil:
+		return existing, true, nil
+	case !errors.Is(err, orderErr.ErrOrderNotFound):
+		return nil, false, err
+	}
+
+	priced, err := s.price(ctx, lines)
+	if err != nil {
+		return nil, false, err
+	}
+
+	order, err := newOrder(userID, priced)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return s.repo.Create(ctx, order, key,
+		func(o *model.Order) model.Event {
+			event := domain.OrderCreatedEvent{
+				OrderID:    o.ID,
+				UserID:     o.UserID,
+				TotalMinor: o.Total.Amount,
+				Currency:   o.Total.Currency,
+				CreatedAt:  o.CreatedAt,
+			}
+			for _, item := range o.Items {
+				event.Items = append(event.Items, domain.OrderItem{
+					ProductID:      item.ProductID,
+					SKU:            item.SKU,
+					Quantity:       item.Quantity,
+					UnitPriceMinor: item.UnitPrice.Amount,
+				})
+			}
+			return model.Event{Topic: domain.TopicOrderCreated, Key: o.ID, Payload: event}
+		})
+}
+
+// Get returns the order if p owns it or may manage orders. Other callers get
+// ErrOrderNotFound so order ids cannot be probed.
+func (s *Service) Get(ctx context.Context, p authz.Principal, id string) (*model.Order, error) {
+	if id == "" {
+		return nil, orderErr.ErrOrderNotFound
+	}
+
+	order, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !authz.OwnerOr(p, order.UserID, authz.PermOrdersManage) {
+		return nil, orderErr.ErrOrderNotFound
+	}
+
+	return order, nil
+}
+
+func (s *Service) ListForUser(ctx context.Context, userID string) ([]model.Order, error) {
+	return s.repo.ListByUser(ctx, userID, defaultPageSize)
+}
+
+// Cancel lets the owner (or staff) cancel an order that has not been paid.
+func (s *Service) Cancel(ctx context.Context, p authz.Principal, id, reason string) (*model.Order, error) {
+	order, err := s.Get(ctx, p, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.transition(ctx, order, model.StatusCancelled, reason)
+}
+
+// Transition moves an order to the given status if the state machine allows it.
+func (s *Service) Transition(ctx context.Context, id string, to model.Status, reason string) (*model.Order, error) {
+	if !to.Valid() {
+		return nil, fmt.Errorf("%w: unknown status %q", orderErr.ErrInvalidTransition, to)
+	}
+
+	order, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.transition(ctx, order, to, reason)
+}
+
+func (s *Service) transition(ctx context.Context, order *model.Order, to model.Status, reason string) (*model.Order, error) {
+	if !order.Status.CanTransitionTo(to) {
+		return nil, fmt.Errorf("%w: %s -> %s", orderErr.ErrInvalidTransition, order.Status, to)
+	}
+
+	return s.repo.UpdateStatus(ctx, order.ID, order.Status, to, transitionEvent(order, to, reason))
+}
+
+func transitionEvent(order *model.Order, to model.Status, reason string) model.Event {
+	t := domain.OrderTransition{
+		OrderID:    order.ID,
+		UserID:     order.UserID,
+		From:       string(order.Status),
+		To:         string(to),
+		Reason:     reason,
+		OccurredAt: time.Now().UTC(),
+	}
+
+	event := model.Event{Key: order.ID}
+	switch to {
+	case model.StatusAwaitingPayment:
+		event.Topic, event.Payload = domain.TopicOrderAwaitingPayment, domain.OrderAwaitingPaymentEvent{OrderTransition: t}
+	case model.StatusPaid:
+		event.Topic, event.Payload = domain.TopicOrderPaid, domain.OrderPaidEvent{OrderTransition: t}
+	case model.StatusShipped:
+		event.Topic, event.Payload = domain.TopicOrderShipped, domain.OrderShippedEvent{OrderTransition: t}
+	case model.StatusDelivered:
+		event.Topic, event.Payload = domain.TopicOrderDelivered, domain.OrderDeliveredEvent{OrderTransition: t}
+	case model.StatusCancelled:
+		event.Topic, event.Payload = domain.TopicOrderCancelled, domain.OrderCancelledEvent{OrderTransition: t}
+	case model.StatusRefunded:
+		event.Topic, event.Payload = domain.TopicOrderRefunded, domain.OrderRefundedEvent{OrderTransition: t}
+	}
+
+	return event
+}
+
+// orderLines keeps the product id and quantity of each requested item and
+// checks them.
+func orderLines(items []model.Item) ([]model.Item, error) {
+	if len(items) == 0 || len(items) > maxItems {
+		return nil, fmt.Errorf("%w: an order needs 1..%d items", orderErr.ErrInvalidOrder, maxItems)
+	}
+
+	lines := make([]model.Item, 0, len(items))
+	for i, item := range items {
+		line := model.Item{ProductID: strings.TrimSpace(item.ProductID), Quantity: item.Quantity}
+
+		switch {
+		case line.ProductID == "":
+			return nil, fmt.Errorf("%w: item %d needs product_id", orderErr.ErrInvalidOrder, i)
+		case line.Quantity <= 0 || line.Quantity > maxQuantity:
+			return nil, fmt.Errorf("%w: item %d quantity must be 1..%d", orderErr.ErrInvalidOrder, i, maxQuantity)
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
+
+// price fills in sku, name and unit price of each line from the catalog.
+func (s *Service) price(ctx context.Context, lines []model.Item) ([]model.Item, error) {
+	priced := make([]model.Item, 0, len(lines))
+	for i, line := range lines {
+		product, err := s.catalog.GetProduct(ctx, line.ProductID)
+		if err != nil {
+			if errors.Is(err, catalog.ErrProductNotFound) {
+				return nil, fmt.Errorf("%w: item %d: product %s does not exist", orderErr.ErrInvalidOrder, i, line.ProductID)
+			}
+			return nil, err
+		}
+
+		line.SKU = product.SKU
+		line.Name = product.Name
+		line.UnitPrice = model.Money{Amount: product.Price.Amount, Currency: product.Price.Currency}
+		priced = append(priced, line)
+	}
+
+	return priced, nil
+}
+
+func newOrder(userID string, items []model.Item) (model.Order, error) {
+	if userID == "" {
+		return model.Order{}, fmt.Errorf("%w: missing user", orderErr.ErrInvalidOrder)
+	}
+
+	order := model.Order{UserID: userID, Status: model.StatusPending}
+	for i, item := range items {
+		item.SKU = strings.TrimSpace(item.SKU)
+		item.UnitPrice.Currency = strings.ToUpper(strings.TrimSpace(item.UnitPrice.Currency))
+
+		switch {
+		case item.SKU == "":
+			return model.Order{}, fmt.Errorf("%w: item %d has no sku in the catalog", orderErr.ErrInvalidOrder, i)
+		case item.UnitPrice.Amount < 0:
+			return model.Order{}, fmt.Errorf("%w: item %d price must not be negative", orderErr.ErrInvalidOrder, i)
+		case !currencyRe.MatchString(item.UnitPrice.Currency):
+			return model.Order{}, fmt.Errorf("%w: item %d currency must be an ISO 4217 code", orderErr.ErrInvalidOrder, i)
+		}
+
+		if order.Total.Currency == "" {
+			order.Total.Currency = item.UnitPrice.Currency
+		} else if order.Total.Currency != item.UnitPrice.Currency {
+			return model.Order{}, fmt.Errorf("%w: all items must use the same currency", orderErr.ErrInvalidOrder)
+		}
+
+		if item.UnitPrice.Amount > 0 && int64(item.Quantity) > (math.MaxInt64-order.Total.Amount)/item.UnitPrice.Amount {
+			return model.Order{}, fmt.Errorf("%w: order total is too large", orderErr.ErrInvalidOrder)
+		}
+		order.Total.Amount += int64(item.Quantity) * item.UnitPrice.Amount
+		order.Items = append(order.Items, item)
+	}
+
+	return order, nil
+}
+
+func requestHash(items []model.Item) (string, error) {
+	b, err := json.Marshal(items)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash request: %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	orderErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/services/order/internal/client/catalog"
+	"github.com/go-market/services/order/internal/model"
+	orderRepo "github.com/go-market/services/order/internal/repository"
+)
+
+// fakeOrders stores at most one order per idempotency key.
+type fakeOrders struct {
+	orderRepo.Repository
+	keys    map[string]model.IdempotencyKey
+	orders  map[string]*model.Order
+	created int
+}
+
+func (f *fakeOrders) GetByIdempotencyKey(_ context.Context, userID string, key model.IdempotencyKey) (*model.Order, error) {
+	stored, ok := f.keys[userID+"/"+key.Key]
+	switch {
+	case !ok:
+		return nil, orderErr.ErrOrderNotFound
+	case stored.RequestHash != key.RequestHash:
+		return nil, orderErr.ErrIdempotencyKeyReused
+	}
+	return f.orders[userID+"/"+key.Key], nil
+}
+
+func (f *fakeOrders) Create(_ context.Context, order model.Order, key model.IdempotencyKey, eventFor func(*model.Order) model.Event) (*model.Order, bool, error) {
+	f.created++
+	order.ID = "o1"
+	eventFor(&order)
+	f.keys[order.UserID+"/"+key.Key] = key
+	f.orders[order.UserID+"/"+key.Key] = &order
+	return &order, false, nil
+}
+
+// fakeCatalog prices every product at 250 EUR cents unless it is down.
+type fakeCatalog struct {
+	down bool
+}
+
+func (f *fakeCatalog) GetProduct(_ context.Context, id string) (*catalog.Product, error) {
+	if f.down {
+		return nil, errors.New("catalog unavailable")
+	}
+	p := &catalog.Product{ID: id, SKU: "SKU-" + id, Name: "product " + id}
+	p.Price.Amount, p.Price.Currency = 250, "EUR"
+	return p, nil
+}
+
+func TestCreateReplay(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeOrders{keys: map[string]model.IdempotencyKey{}, orders: map[string]*model.Order{}}
+	cat := &fakeCatalog{}
+	s := New(repo, cat)
+
+	items := []model.Item{{ProductID: "p1", Quantity: 2}}
+	order, replayed, err := s.Create(ctx, "u1", "k1", items)
+	if err != nil || replayed {
+		t.Fatalf("Create() = %v, %v, want a new order", replayed, err)
+	}
+	if order.Total.Amount != 500 {
+		t.Errorf("total = %d, want 500", order.Total.Amount)
+	}
+
+	cat.down = true
+
+	tests := []struct {
+		name         string
+		key          string
+		items        []model.Item
+		wantReplayed bool
+		wantErr      bool
+	}{
+		{name: "retry is replayed without the catalog", key: "k1", items: items, wantReplayed: true},
+		{name: "key reused for other items", key: "k1", items: []model.Item{{ProductID: "p2", Quantity: 1}}, wantErr: true},
+		{name: "new key needs the catalog", key: "k2", items: items, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, replayed, err := s.Create(ctx, "u1", tt.key, tt.items)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Create() error = nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			if replayed != tt.wantReplayed || got.ID != order.ID {
+				t.Errorf("Create() = %s, replayed %v, want %s replayed", got.ID, replayed, order.ID)
+			}
+		})
+	}
+
+	if repo.created != 1 {
+		t.Errorf("orders created = %d, want 1", repo.created)
+	}
+}
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS sagas (
+    id VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(64) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    step INT NOT NULL DEFAULT 0,
+    data JSONB NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    lease_owner VARCHAR(32),
+    locked_until TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (status IN ('running', 'compensating', 'completed', 'compensated', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS sagas_due_idx ON sagas (name, next_attempt_at) WHERE status IN ('running', 'compensating');
@@ -0,0 +1,176 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-market/pkg/authz"
+	"github.com/go-market/pkg/kafka"
+	"github.com/go-market/pkg/migrate"
+	"github.com/go-market/pkg/outbox"
+	pkgRedis "github.com/go-market/pkg/redis"
+	"github.com/go-market/services/payment/internal/config"
+	paymentHTTP "github.com/go-market/services/payment/internal/derivery/http"
+	"github.com/go-market/services/payment/internal/provider"
+	"github.com/go-market/services/payment/internal/provider/fake"
+	"github.com/go-market/services/payment/internal/repository/postgres"
+	"github.com/go-market/services/payment/internal/service"
+	"github.com/redis/go-redis/v9"
+)
+
+type App struct {
+	server   *http.Server
+	relay    *outbox.Relay
+	producer *kafka.Producer
+	repo     *postgres.PostgresRepo
+	rdb      *redis.Client
+	log      *slog.Logger
+}
+
+func NewApp(cfg config.Config, log *slog.Logger) (*App, error) {
+	const op = "app.NewApp"
+
+	logger := log.With(slog.String("op", op))
+
+	repo, err := postgres.New(cfg.DatabaseURL)
+	if err != nil {
+		logger.Error("failed to init postgres", slog.String("op", op), slog.Any("err", err))
+		return nil, err
+	}
+
+	if err := migrateSchema(repo, cfg.MigrationsPath, log); err != nil {
+		logger.Error("failed to migrate database", slog.Any("err", err))
+		repo.Close()
+		return nil, err
+	}
+
+	pp, err := newProvider(cfg.Provider.Name)
+	if err != nil {
+		logger.Error("failed to init payment provider", slog.Any("err", err))
+		repo.Close()
+		return nil, err
+	}
+
+	svc := service.New(repo, pp, service.WebhookConfig{
+		Secret:    cfg.Provider.WebhookSecret,
+		Tolerance: cfg.Provider.WebhookTolerance,
+	})
+
+	producer := kafka.NewProducer(cfg.Kafka.Brokers)
+	relay := outbox.NewRelay(repo.Pool(), producer, log, outbox.Config{
+		PollInterval: cfg.Outbox.PollInterval,
+		BatchSize:    cfg.Outbox.BatchSize,
+		BaseBackoff:  cfg.Outbox.BaseBackoff,
+		MaxBackoff:   cfg.Outbox.MaxBackoff,
+	})
+
+	paymentHandler := paymentHTTP.New(log, svc)
+
+	rdb := pkgRedis.NewClient(cfg.RedisAddr)
+	if err := rdb.Ping(context.Background()).Err(); err != nil {
+		logger.Warn("redis is unreachable, authenticated requests will be refused", slog.Any("err", err))
+	}
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(middleware.Logger)
+	r.Use(middleware.Recoverer)
+
+	r.Route("/api/v1", func(r chi.Router) {
+		paymentHTTP.RegisterPaymentRoutes(r, paymentHandler, authz.NewRemoteVerifier(cfg.Auth), authz.NewRedisDenyList(rdb))
+	})
+
+	server := &http.Server{
+		Addr:         cfg.HTTPAddr.Address,
+		Handler:      r,
+		ReadTimeout:  cfg.HTTPAddr.Timeout,
+		WriteTimeout: cfg.HTTPAddr.Timeout,
+		IdleTimeout:  cfg.HTTPAddr.IdleTimeout,
+	}
+
+	return &App{
+		server:   server,
+		relay:    relay,
+		producer: producer,
+		repo:     repo,
+		rdb:      rdb,
+		log:      log,
+	}, nil
+}
+
+// newProvider picks the payment provider by name. Only the local fake is
+// built in so far; real providers plug in here.
+func newProvider(name string) (provider.Provider, error) {
+	switch name {
+	case "", "fake":
+		return fake.New(), nil
+	}
+	return nil, fmt.Errorf("unknown payment provider %q", name)
+}
+
+// migrateSchema applies pending migrations and then verifies that the schema
+// matches what the repository queries expect.
+func migrateSchema(repo *postgres.PostgresRepo, path string, log *slog.Logger) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	migrator, err := migrate.New(repo.Pool(), path, log)
+	if err != nil {
+		return err
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		return err
+	}
+
+	return repo.CheckSchema(ctx)
+}
+
+func (a *App) Run() error {
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.relay.Run(bgCtx)
+	}()
+
+	go func() {
+		if err := a.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			a.log.Error("listen failed", slog.Any("err", err))
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	a.log.Info("shutting down server")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := a.server.Shutdown(ctx)
+
+	stopBackground()
+	wg.Wait()
+
+	if err := a.producer.Close(); err != nil {
+		a.log.Error("failed to close kafka producer", slog.Any("err", err))
+	}
+	if err := a.rdb.Close(); err != nil {
+		a.log.Error("failed to close redis client", slog.Any("err", err))
+	}
+	a.repo.Close()
+
+	return err
+}
@@ -0,0 +1,178 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/go-market/services/payment/internal/model"
+	"github.com/go-market/services/payment/internal/service"
+	"github.com/go-market/services/payment/internal/webhook"
+)
+
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxWebhookBody = 1 << 20
+)
+
+type PaymentHandler struct {
+	log *slog.Logger
+	svc *service.Service
+}
+
+func New(log *slog.Logger, svc *service.Service) *PaymentHandler {
+	return &PaymentHandler{
+		log: log,
+		svc: svc,
+	}
+}
+
+type AmountRequest struct {
+	Amount int64 `json:"amount"`
+}
+
+// Authorize creates a payment. Every mutating endpoint requires an
+// Idempotency-Key; retries with the same key return the stored result with
+// the Idempotent-Replayed header instead of calling the provider again.
+func (h *PaymentHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+	const op = "PaymentHandler.Authorize"
+	log := h.log.With(slog.String("op", op))
+
+	var req service.AuthorizeInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("failed to decode request", slog.String("error", err.Error()))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	payment, replayed, err := h.svc.Authorize(r.Context(), r.Header.Get(idempotencyKeyHeader), req)
+	h.respond(w, r, log, payment, replayed, err, "failed to authorize payment", http.StatusCreated)
+}
+
+func (h *PaymentHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	const op = "PaymentHandler.GetByID"
+	log := h.log.With(slog.String("op", op))
+
+	payment, err := h.svc.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		log.Error("failed to get payment", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to get payment")
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Data: payment})
+}
+
+// List serves GET /payments?order_id=, the payments made for one order.
+func (h *PaymentHandler) List(w http.ResponseWriter, r *http.Request) {
+	const op = "PaymentHandler.List"
+	log := h.log.With(slog.String("op", op))
+
+	payments, err := h.svc.ListByOrder(r.Context(), r.URL.Query().Get("order_id"))
+	if err != nil {
+		log.Error("failed to list payments", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to list payments")
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Data: payments})
+}
+
+func (h *PaymentHandler) Capture(w http.ResponseWriter, r *http.Request) {
+	const op = "PaymentHandler.Capture"
+	log := h.log.With(slog.String("op", op))
+
+	req, ok := h.decodeAmount(w, r, log)
+	if !ok {
+		return
+	}
+
+	payment, replayed, err := h.svc.Capture(r.Context(), r.Header.Get(idempotencyKeyHeader), chi.URLParam(r, "id"), req.Amount)
+	h.respond(w, r, log, payment, replayed, err, "failed to capture payment", http.StatusOK)
+}
+
+func (h *PaymentHandler) Refund(w http.ResponseWriter, r *http.Request) {
+	const op = "PaymentHandler.Refund"
+	log := h.log.With(slog.String("op", op))
+
+	req, ok := h.decodeAmount(w, r, log)
+	if !ok {
+		return
+	}
+
+	payment, replayed, err := h.svc.Refund(r.Context(), r.Header.Get(idempotencyKeyHeader), chi.URLParam(r, "id"), req.Amount)
+	h.respond(w, r, log, payment, replayed, err, "failed to refund payment", http.StatusOK)
+}
+
+func (h *PaymentHandler) Void(w http.ResponseWriter, r *http.Request) {
+	const op = "PaymentHandler.Void"
+	log := h.log.With(slog.String("op", op))
+
+	payment, replayed, err := h.svc.Void(r.Context(), r.Header.Get(idempotencyKeyHeader), chi.URLParam(r, "id"))
+	h.respond(w, r, log, payment, replayed, err, "failed to void payment", http.StatusOK)
+}
+
+// Webhook ingests provider callbacks. It is unauthenticated; the signature
+// header is what proves the request came from the provider.
+func (h *PaymentHandler) Webhook(w http.ResponseWriter, r *http.Request) {
+	const op = "PaymentHandler.Webhook"
+	log := h.log.With(slog.String("op", op))
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		log.Error("failed to read webhook", slog.String("error", err.Error()))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	applied, err := h.svc.HandleWebhook(r.Context(), r.Header.Get(webhook.SignatureHeader), body)
+	if err != nil {
+		log.Error("failed to handle webhook", slog.String("error", err.Error()))
+		renderError(w, r, err, "failed to handle webhook")
+		return
+	}
+
+	if !applied {
+		render.JSON(w, r, SuccessResponse{Message: "duplicate event ignored"})
+		return
+	}
+	render.JSON(w, r, SuccessResponse{Message: "event processed"})
+}
+
+func (h *PaymentHandler) decodeAmount(w http.ResponseWriter, r *http.Request, log *slog.Logger) (AmountRequest, bool) {
+	var req AmountRequest
+	if r.ContentLength == 0 {
+		return req, true
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("failed to decode request", slog.String("error", err.Error()))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, ErrorResponse{Error: "invalid request body"})
+		return req, false
+	}
+	return req, true
+}
+
+func (h *PaymentHandler) respond(w http.ResponseWriter, r *http.Request, log *slog.Logger, payment *model.Payment, replayed bool, err error, fallback string, created int) {
+	if replayed {
+		w.Header().Set(idempotentReplayedHeader, "true")
+	}
+	if err != nil {
+		log.Error(fallback, slog.String("error", err.Error()))
+		renderError(w, r, err, fallback)
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/payments/"+payment.ID)
+	if !replayed {
+		render.Status(r, created)
+	}
+	render.JSON(w, r, SuccessResponse{Data: payment})
+}
@@ -0,0 +1,28 @@
+package http
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-market/pkg/authz"
+)
+
+// RegisterPaymentRoutes exposes the payment API to callers holding
+// payments:manage, who also need a second factor unless they are a service.
+// Webhooks are authenticated by signature instead.
+func RegisterPaymentRoutes(r chi.Router, h *PaymentHandler, verifier *authz.Verifier, denied authz.DenyList) {
+	r.Route("/payments", func(r chi.Router) {
+		r.Post("/webhooks", h.Webhook)
+
+		r.Group(func(r chi.Router) {
+			r.Use(authz.Authenticate(verifier, authz.WithDenyList(denied)))
+			r.Use(authz.RequirePermission(authz.PermPaymentsManage))
+			r.Use(authz.RequireMFA())
+
+			r.Get("/", h.List)
+			r.Post("/", h.Authorize)
+			r.Get("/{id}", h.GetByID)
+			r.Post("/{id}/capture", h.Capture)
+			r.Post("/{id}/refund", h.Refund)
+			r.Post("/{id}/void", h.Void)
+		})
+	})
+}
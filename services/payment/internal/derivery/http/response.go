@@ -0,0 +1,49 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/render"
+	paymentErr "github.com/go-market/pkg/errs"
+)
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type SuccessResponse struct {
+	Data    interface{} `json:"data,omitempty"`
+	Message string      `json:"message,omitempty"`
+}
+
+// renderError maps payment errors to status codes. Unknown errors are
+// reported as fallback so internals never reach the client.
+func renderError(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+	status := http.StatusInternalServerError
+	msg := fallback
+
+	switch {
+	case errors.Is(err, paymentErr.ErrInvalidPayment), errors.Is(err, paymentErr.ErrIdempotencyKeyRequired):
+		status, msg = http.StatusBadRequest, err.Error()
+	case errors.Is(err, paymentErr.ErrInvalidSignature):
+		status, msg = http.StatusUnauthorized, paymentErr.ErrInvalidSignature.Error()
+	case errors.Is(err, paymentErr.ErrPaymentDeclined):
+		status, msg = http.StatusPaymentRequired, err.Error()
+	case errors.Is(err, paymentErr.ErrPaymentNotFound):
+		status, msg = http.StatusNotFound, err.Error()
+	case errors.Is(err, paymentErr.ErrPaymentBusy):
+		// Unlike other conflicts this one clears up by itself.
+		w.Header().Set("Retry-After", "1")
+		status, msg = http.StatusConflict, err.Error()
+	case errors.Is(err, paymentErr.ErrInvalidPaymentState), errors.Is(err, paymentErr.ErrPaymentExists):
+		status, msg = http.StatusConflict, err.Error()
+	case errors.Is(err, paymentErr.ErrIdempotencyKeyReused):
+		status, msg = http.StatusUnprocessableEntity, err.Error()
+	case errors.Is(err, paymentErr.ErrProviderUnavailable):
+		status, msg = http.StatusBadGateway, paymentErr.ErrProviderUnavailable.Error()
+	}
+
+	render.Status(r, status)
+	render.JSON(w, r, ErrorResponse{Error: msg})
+}
@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+type OperationKind string
+
+const (
+	OperationAuthorize OperationKind = "authorize"
+	OperationCapture   OperationKind = "capture"
+	OperationRefund    OperationKind = "refund"
+	OperationVoid      OperationKind = "void"
+)
+
+type OperationStatus string
+
+const (
+	OperationPending   OperationStatus = "pending"
+	OperationSucceeded OperationStatus = "succeeded"
+	OperationFailed    OperationStatus = "failed"
+)
+
+// Operation is one mutating call against a payment, keyed by the caller's
+// idempotency key. It is recorded as pending before the provider is called,
+// so a retry after a crash resumes the same provider call instead of
+// starting a new one.
+type Operation struct {
+	ID             string
+	PaymentID      string
+	Kind           OperationKind
+	Amount         int64
+	Status         OperationStatus
+	IdempotencyKey string
+	RequestHash    string
+	FailureReason  string
+	CreatedAt      time.Time
+}
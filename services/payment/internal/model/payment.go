@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+type Status string
+
+const (
+	StatusPending           Status = "pending"
+	StatusAuthorized        Status = "authorized"
+	StatusDeclined          Status = "declined"
+	StatusCaptured          Status = "captured"
+	StatusPartiallyRefunded Status = "partially_refunded"
+	StatusRefunded          Status = "refunded"
+	StatusVoided            Status = "voided"
+)
+
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+type Payment struct {
+	ID            string    `json:"id"`
+	OrderID       string    `json:"order_id"`
+	Status        Status    `json:"status"`
+	Amount        Money     `json:"amount"`
+	Captured      int64     `json:"captured_minor"`
+	Refunded      int64     `json:"refunded_minor"`
+	PaymentMethod string    `json:"-"`
+	Provider      string    `json:"provider"`
+	ProviderRef   string    `json:"provider_ref,omitempty"`
+	DeclineCode   string    `json:"decline_code,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Event is a domain event written to the outbox together with the change
+// that produced it.
+type Event struct {
+	Topic   string
+	Key     string
+	Payload interface{}
+}
@@ -0,0 +1,147 @@
+// Package fake is a deterministic in-process payment provider for local
+// development and tests. The outcome depends only on the payment method
+// token, so the same request always gets the same answer:
+//
+//	tok_declined            authorization declined (card_declined)
+//	tok_insufficient_funds  authorization declined (insufficient_funds)
+//	tok_provider_error      every call fails as if the provider were down
+//	anything else           approved
+package fake
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+
+	"github.com/go-market/services/payment/internal/provider"
+)
+
+const (
+	TokenDeclined          = "tok_declined"
+	TokenInsufficientFunds = "tok_insufficient_funds"
+	TokenProviderError     = "tok_provider_error"
+)
+
+var ErrUnavailable = errors.New("fake provider: simulated outage")
+
+type charge struct {
+	method     string
+	authorized int64
+	captured   int64
+	refunded   int64
+	voided     bool
+}
+
+type Provider struct {
+	mu      sync.Mutex
+	charges map[string]*charge
+	results map[string]provider.Result
+}
+
+func New() *Provider {
+	return &Provider{
+		charges: make(map[string]*charge),
+		results: make(map[string]provider.Result),
+	}
+}
+
+func (p *Provider) Name() string {
+	return "fake"
+}
+
+func (p *Provider) Authorize(_ context.Context, idempotencyKey string, req provider.AuthorizeRequest) (provider.Result, error) {
+	if req.PaymentMethod == TokenProviderError {
+		return provider.Result{}, ErrUnavailable
+	}
+
+	return p.once(idempotencyKey, func() provider.Result {
+		ref := Ref(req.Reference)
+		switch req.PaymentMethod {
+		case TokenDeclined:
+			return provider.Result{ProviderRef: ref, DeclineCode: "card_declined"}
+		case TokenInsufficientFunds:
+			return provider.Result{ProviderRef: ref, DeclineCode: "insufficient_funds"}
+		}
+
+		p.charges[ref] = &charge{method: req.PaymentMethod, authorized: req.Amount}
+		return provider.Result{ProviderRef: ref, Approved: true}
+	}), nil
+}
+
+func (p *Provider) Capture(_ context.Context, idempotencyKey, providerRef string, amount int64) (provider.Result, error) {
+	return p.mutate(idempotencyKey, providerRef, func(c *charge) string {
+		switch {
+		case c.voided:
+			return "authorization_voided"
+		case c.captured > 0:
+			return "already_captured"
+		case amount > c.authorized:
+			return "amount_too_large"
+		}
+		c.captured = amount
+		return ""
+	})
+}
+
+func (p *Provider) Refund(_ context.Context, idempotencyKey, providerRef string, amount int64) (provider.Result, error) {
+	return p.mutate(idempotencyKey, providerRef, func(c *charge) string {
+		if amount > c.captured-c.refunded {
+			return "amount_too_large"
+		}
+		c.refunded += amount
+		return ""
+	})
+}
+
+func (p *Provider) Void(_ context.Context, idempotencyKey, providerRef string) (provider.Result, error) {
+	return p.mutate(idempotencyKey, providerRef, func(c *charge) string {
+		if c.captured > 0 {
+			return "already_captured"
+		}
+		c.voided = true
+		return ""
+	})
+}
+
+// Ref is the provider reference the fake assigns to a payment, exposed so
+// that tests can build webhooks for it.
+func Ref(reference string) string {
+	sum := sha256.Sum256([]byte(reference))
+	return "fake_" + hex.EncodeToString(sum[:12])
+}
+
+func (p *Provider) mutate(idempotencyKey, providerRef string, apply func(*charge) string) (provider.Result, error) {
+	p.mu.Lock()
+	c, ok := p.charges[providerRef]
+	p.mu.Unlock()
+	if ok && c.method == TokenProviderError {
+		return provider.Result{}, ErrUnavailable
+	}
+
+	return p.once(idempotencyKey, func() provider.Result {
+		c, ok := p.charges[providerRef]
+		if !ok {
+			return provider.Result{ProviderRef: providerRef, DeclineCode: "unknown_charge"}
+		}
+		if code := apply(c); code != "" {
+			return provider.Result{ProviderRef: providerRef, DeclineCode: code}
+		}
+		return provider.Result{ProviderRef: providerRef, Approved: true}
+	}), nil
+}
+
+// once runs fn the first time idempotencyKey is seen and replays its result
+// afterwards.
+func (p *Provider) once(idempotencyKey string, fn func() provider.Result) provider.Result {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if res, ok := p.results[idempotencyKey]; ok {
+		return res
+	}
+	res := fn()
+	p.results[idempotencyKey] = res
+	return res
+}
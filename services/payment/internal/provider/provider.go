@@ -0,0 +1,30 @@
+// Package provider abstracts the payment service provider (PSP). Every call
+// carries an idempotency key that the provider must honour, so repeating a
+// call after a timeout never moves money twice.
+package provider
+
+import "context"
+
+type AuthorizeRequest struct {
+	// Reference is our payment id, stored by the provider for reconciliation.
+	Reference     string
+	Amount        int64
+	Currency      string
+	PaymentMethod string
+}
+
+// Result is the provider's answer. A declined operation is not an error;
+// errors mean the outcome is unknown and the call should be retried.
+type Result struct {
+	ProviderRef string
+	Approved    bool
+	DeclineCode string
+}
+
+type Provider interface {
+	Name() string
+	Authorize(ctx context.Context, idempotencyKey string, req AuthorizeRequest) (Result, error)
+	Capture(ctx context.Context, idempotencyKey, providerRef string, amount int64) (Result, error)
+	Refund(ctx context.Context, idempotencyKey, providerRef string, amount int64) (Result, error)
+	Void(ctx context.Context, idempotencyKey, providerRef string) (Result, error)
+}
@@ -0,0 +1,313 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	paymentErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/pkg/outbox"
+	"github.com/go-market/services/payment/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+const paymentColumns = `id, order_id, status, amount_minor, currency, captured_minor, refunded_minor,
+	payment_method, provider, COALESCE(provider_ref, ''), COALESCE(decline_code, ''), created_at, updated_at`
+
+const operationColumns = `id, payment_id, kind, amount_minor, status, idempotency_key, request_hash,
+	COALESCE(failure_reason, ''), created_at`
+
+func scanPayment(row pgx.Row) (*model.Payment, error) {
+	p := &model.Payment{}
+	err := row.Scan(&p.ID, &p.OrderID, &p.Status, &p.Amount.Amount, &p.Amount.Currency, &p.Captured, &p.Refunded,
+		&p.PaymentMethod, &p.Provider, &p.ProviderRef, &p.DeclineCode, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) || pgCode(err) == invalidTextRepr {
+			return nil, paymentErr.ErrPaymentNotFound
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+func scanOperation(row pgx.Row) (*model.Operation, error) {
+	o := &model.Operation{}
+	err := row.Scan(&o.ID, &o.PaymentID, &o.Kind, &o.Amount, &o.Status, &o.IdempotencyKey, &o.RequestHash,
+		&o.FailureReason, &o.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (r *PostgresRepo) GetByID(ctx context.Context, id string) (*model.Payment, error) {
+	const op = "repo.GetByID"
+
+	p, err := scanPayment(r.db.QueryRow(ctx, `SELECT `+paymentColumns+` FROM payments WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, paymentErr.ErrPaymentNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return p, nil
+}
+
+func (r *PostgresRepo) ListByOrder(ctx context.Context, orderID string) ([]model.Payment, error) {
+	const op = "repo.ListByOrder"
+
+	rows, err := r.db.Query(ctx, `SELECT `+paymentColumns+` FROM payments WHERE order_id = $1 ORDER BY created_at`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	payments := []model.Payment{}
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		payments = append(payments, *p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return payments, nil
+}
+
+func (r *PostgresRepo) StartAuthorize(ctx context.Context, payment model.Payment, o model.Operation) (*model.Payment, *model.Operation, bool, error) {
+	const op = "repo.StartAuthorize"
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if p, existing, err := r.replay(ctx, tx, o); err != nil || existing != nil {
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("%s: %w", op, err)
+		}
+		return p, existing, true, nil
+	}
+
+	p, err := scanPayment(tx.QueryRow(ctx, `INSERT INTO payments (order_id, status, amount_minor, currency, payment_method, provider)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+paymentColumns,
+		payment.OrderID, model.StatusPending, payment.Amount.Amount, payment.Amount.Currency, payment.PaymentMethod, payment.Provider))
+	if err != nil {
+		if pgCode(err) == uniqueViolation {
+			return nil, nil, false, paymentErr.ErrPaymentExists
+		}
+		return nil, nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	o.PaymentID = p.ID
+	created, err := insertOperation(ctx, tx, o)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return p, created, false, nil
+}
+
+func (r *PostgresRepo) StartOperation(ctx context.Context, o model.Operation, check func(*model.Payment, *model.Operation) error) (*model.Payment, *model.Operation, bool, error) {
+	const op = "repo.StartOperation"
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if p, existing, err := r.replay(ctx, tx, o); err != nil || existing != nil {
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("%s: %w", op, err)
+		}
+		return p, existing, true, nil
+	}
+
+	p, err := scanPayment(tx.QueryRow(ctx, `SELECT `+paymentColumns+` FROM payments WHERE id = $1 FOR UPDATE`, o.PaymentID))
+	if err != nil {
+		if errors.Is(err, paymentErr.ErrPaymentNotFound) {
+			return nil, nil, false, err
+		}
+		return nil, nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// One provider call per payment at a time keeps our amounts and the
+	// provider's in step.
+	var busy bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM payment_operations
+		WHERE payment_id = $1 AND status = $2)`, p.ID, model.OperationPending).Scan(&busy); err != nil {
+		return nil, nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+	if busy {
+		return nil, nil, false, paymentErr.ErrPaymentBusy
+	}
+
+	if err := check(p, &o); err != nil {
+		return nil, nil, false, err
+	}
+
+	created, err := insertOperation(ctx, tx, o)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return p, created, false, nil
+}
+
+// replay serialises requests sharing an idempotency key and returns the
+// operation recorded for it, if any. Reusing a key for a different request
+// is an error.
+func (r *PostgresRepo) replay(ctx context.Context, tx pgx.Tx, o model.Operation) (*model.Payment, *model.Operation, error) {
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`, o.IdempotencyKey); err != nil {
+		return nil, nil, err
+	}
+
+	existing, err := scanOperation(tx.QueryRow(ctx, `SELECT `+operationColumns+` FROM payment_operations
+		WHERE idempotency_key = $1`, o.IdempotencyKey))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if existing.RequestHash != o.RequestHash {
+		return nil, nil, paymentErr.ErrIdempotencyKeyReused
+	}
+
+	p, err := scanPayment(tx.QueryRow(ctx, `SELECT `+paymentColumns+` FROM payments WHERE id = $1`, existing.PaymentID))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return p, existing, nil
+}
+
+func insertOperation(ctx context.Context, tx pgx.Tx, o model.Operation) (*model.Operation, error) {
+	return scanOperation(tx.QueryRow(ctx, `INSERT INTO payment_operations
+		(payment_id, kind, amount_minor, status, idempotency_key, request_hash)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+operationColumns,
+		o.PaymentID, o.Kind, o.Amount, model.OperationPending, o.IdempotencyKey, o.RequestHash))
+}
+
+func (r *PostgresRepo) FinishOperation(ctx context.Context, opID string, apply func(*model.Payment, *model.Operation) []model.Event) (*model.Payment, *model.Operation, error) {
+	const op = "repo.FinishOperation"
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	o, err := scanOperation(tx.QueryRow(ctx, `SELECT `+operationColumns+` FROM payment_operations
+		WHERE id = $1 FOR UPDATE`, opID))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	p, err := scanPayment(tx.QueryRow(ctx, `SELECT `+paymentColumns+` FROM payments WHERE id = $1 FOR UPDATE`, o.PaymentID))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// A concurrent retry already settled it.
+	if o.Status != model.OperationPending {
+		return p, o, nil
+	}
+
+	events := apply(p, o)
+
+	if err := savePayment(ctx, tx, p); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE payment_operations
+		SET status = $2, failure_reason = NULLIF($3, ''), updated_at = NOW()
+		WHERE id = $1`, o.ID, o.Status, o.FailureReason); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := enqueue(ctx, tx, events); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return p, o, nil
+}
+
+func (r *PostgresRepo) ApplyWebhook(ctx context.Context, provider, eventID, eventType, providerRef string, apply func(*model.Payment) []model.Event) (*model.Payment, bool, error) {
+	const op = "repo.ApplyWebhook"
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `INSERT INTO webhook_events (provider, event_id, type)
+		VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`, provider, eventID, eventType)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, false, nil
+	}
+
+	p, err := scanPayment(tx.QueryRow(ctx, `SELECT `+paymentColumns+` FROM payments
+		WHERE provider = $1 AND provider_ref = $2 FOR UPDATE`, provider, providerRef))
+	if err != nil {
+		if errors.Is(err, paymentErr.ErrPaymentNotFound) {
+			return nil, false, err
+		}
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	events := apply(p)
+
+	if err := savePayment(ctx, tx, p); err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := enqueue(ctx, tx, events); err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return p, true, nil
+}
+
+func savePayment(ctx context.Context, tx pgx.Tx, p *model.Payment) error {
+	return tx.QueryRow(ctx, `UPDATE payments
+		SET status = $2, captured_minor = $3, refunded_minor = $4,
+			provider_ref = NULLIF($5, ''), decline_code = NULLIF($6, ''), updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at`,
+		p.ID, p.Status, p.Captured, p.Refunded, p.ProviderRef, p.DeclineCode).Scan(&p.UpdatedAt)
+}
+
+func enqueue(ctx context.Context, tx pgx.Tx, events []model.Event) error {
+	for _, event := range events {
+		if err := outbox.Enqueue(ctx, tx, event.Topic, event.Key, event.Payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-market/pkg/migrate"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	invalidTextRepr = "22P02"
+	uniqueViolation = "23505"
+)
+
+// schema lists every column the queries in this package rely on.
+var schema = map[string][]string{
+	"payments": {"id", "order_id", "status", "amount_minor", "currency", "captured_minor", "refunded_minor",
+		"payment_method", "provider", "provider_ref", "decline_code", "created_at", "updated_at"},
+	"payment_operations": {"id", "payment_id", "kind", "amount_minor", "status", "idempotency_key", "request_hash",
+		"failure_reason", "created_at", "updated_at"},
+	"webhook_events": {"provider", "event_id", "type", "received_at"},
+	"outbox":         {"id", "topic", "message_key", "payload", "attempts", "last_error", "next_attempt_at", "published_at", "locked_until"},
+}
+
+type PostgresRepo struct {
+	db *pgxpool.Pool
+}
+
+func New(db_url string) (*PostgresRepo, error) {
+	ctx := context.Background()
+	db, err := pgxpool.New(ctx, db_url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect database %w", err)
+	}
+
+	return &PostgresRepo{db: db}, nil
+}
+
+// Pool exposes the connection pool for components that share the database,
+// such as the outbox relay.
+func (r *PostgresRepo) Pool() *pgxpool.Pool {
+	return r.db
+}
+
+// CheckSchema fails when the database is missing a column used by the
+// repository queries.
+func (r *PostgresRepo) CheckSchema(ctx context.Context) error {
+	return migrate.VerifyColumns(ctx, r.db, schema)
+}
+
+func (r *PostgresRepo) Close() {
+	r.db.Close()
+}
+
+func pgCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
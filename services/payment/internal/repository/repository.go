@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/go-market/services/payment/internal/model"
+)
+
+// Mutating calls are split in two: Start records the intent under the
+// caller's idempotency key before the provider is contacted, Finish stores
+// the provider's answer. A retry with the same key finds the recorded
+// operation and either replays the result or resumes the provider call.
+type Repository interface {
+	// StartAuthorize stores a new pending payment with its authorize
+	// operation. If op.IdempotencyKey was used before, the original payment
+	// and operation are returned and replayed is true.
+	StartAuthorize(ctx context.Context, payment model.Payment, op model.Operation) (p *model.Payment, o *model.Operation, replayed bool, err error)
+	// StartOperation records a pending operation on op.PaymentID once check
+	// has accepted it against the locked payment. check may fill in defaults
+	// such as the amount.
+	StartOperation(ctx context.Context, op model.Operation, check func(*model.Payment, *model.Operation) error) (p *model.Payment, o *model.Operation, replayed bool, err error)
+	// FinishOperation settles a pending operation. apply updates the locked
+	// payment and operation in place and returns the events to publish.
+	// Operations that are already settled are returned unchanged.
+	FinishOperation(ctx context.Context, opID string, apply func(*model.Payment, *model.Operation) []model.Event) (*model.Payment, *model.Operation, error)
+	GetByID(ctx context.Context, id string) (*model.Payment, error)
+	ListByOrder(ctx context.Context, orderID string) ([]model.Payment, error)
+	// ApplyWebhook records a provider event and lets apply update the payment
+	// it refers to. Events seen before are ignored and applied is false.
+	ApplyWebhook(ctx context.Context, provider, eventID, eventType, providerRef string, apply func(*model.Payment) []model.Event) (p *model.Payment, applied bool, err error)
+}
@@ -0,0 +1,328 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	domain "github.com/go-market/pkg/domain/model"
+	paymentErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/services/payment/internal/model"
+	"github.com/go-market/services/payment/internal/provider"
+	paymentRepo "github.com/go-market/services/payment/internal/repository"
+)
+
+const (
+	maxKeyLength   = 255
+	maxFieldLength = 255
+)
+
+var currencyRe = regexp.MustCompile(`^[A-Z]{3}$`)
+
+type Service struct {
+	repo     paymentRepo.Repository
+	provider provider.Provider
+	webhooks WebhookConfig
+}
+
+type WebhookConfig struct {
+	Secret    string
+	Tolerance time.Duration
+}
+
+func New(repo paymentRepo.Repository, p provider.Provider, webhooks WebhookConfig) *Service {
+	return &Service{
+		repo:     repo,
+		provider: p,
+		webhooks: webhooks,
+	}
+}
+
+type AuthorizeInput struct {
+	OrderID       string      `json:"order_id"`
+	Amount        model.Money `json:"amount"`
+	PaymentMethod string      `json:"payment_method"`
+}
+
+// Authorize creates a payment and reserves the amount with the provider.
+// Retrying with the same idempotency key returns the original payment with
+// replayed=true and never authorizes twice.
+func (s *Service) Authorize(ctx context.Context, idempotencyKey string, in AuthorizeInput) (*model.Payment, bool, error) {
+	idempotencyKey, err := checkKey(idempotencyKey)
+	if err != nil {
+		return nil, false, err
+	}
+
+	in.OrderID = strings.TrimSpace(in.OrderID)
+	in.PaymentMethod = strings.TrimSpace(in.PaymentMethod)
+	in.Amount.Currency = strings.ToUpper(strings.TrimSpace(in.Amount.Currency))
+
+	switch {
+	case in.OrderID == "" || len(in.OrderID) > maxFieldLength:
+		return nil, false, fmt.Errorf("%w: order_id must be 1..%d characters", paymentErr.ErrInvalidPayment, maxFieldLength)
+	case in.Amount.Amount <= 0:
+		return nil, false, fmt.Errorf("%w: amount must be positive", paymentErr.ErrInvalidPayment)
+	case !currencyRe.MatchString(in.Amount.Currency):
+		return nil, false, fmt.Errorf("%w: currency must be an ISO 4217 code", paymentErr.ErrInvalidPayment)
+	case in.PaymentMethod == "" || len(in.PaymentMethod) > maxFieldLength:
+		return nil, false, fmt.Errorf("%w: payment_method is required", paymentErr.ErrInvalidPayment)
+	}
+
+	hash, err := requestHash(model.OperationAuthorize, in)
+	if err != nil {
+		return nil, false, err
+	}
+
+	p, op, replayed, err := s.repo.StartAuthorize(ctx, model.Payment{
+		OrderID:       in.OrderID,
+		Amount:        in.Amount,
+		PaymentMethod: in.PaymentMethod,
+		Provider:      s.provider.Name(),
+	}, model.Operation{
+		Kind:           model.OperationAuthorize,
+		Amount:         in.Amount.Amount,
+		IdempotencyKey: idempotencyKey,
+		RequestHash:    hash,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	p, err = s.execute(ctx, p, op)
+	return p, replayed, err
+}
+
+func (s *Service) Get(ctx context.Context, id string) (*model.Payment, error) {
+	if id == "" {
+		return nil, paymentErr.ErrPaymentNotFound
+	}
+
+	return s.repo.GetByID(ctx, id)
+}
+
+// ListByOrder returns every payment made for orderID, oldest first.
+func (s *Service) ListByOrder(ctx context.Context, orderID string) ([]model.Payment, error) {
+	if orderID == "" {
+		return nil, fmt.Errorf("%w: order_id is required", paymentErr.ErrInvalidPayment)
+	}
+
+	return s.repo.ListByOrder(ctx, orderID)
+}
+
+// Capture takes amount of an authorized payment; zero captures it in full.
+func (s *Service) Capture(ctx context.Context, idempotencyKey, id string, amount int64) (*model.Payment, bool, error) {
+	return s.operate(ctx, idempotencyKey, id, model.OperationCapture, amount, func(p *model.Payment, op *model.Operation) error {
+		if p.Status != model.StatusAuthorized {
+			return fmt.Errorf("%w: cannot capture a %s payment", paymentErr.ErrInvalidPaymentState, p.Status)
+		}
+		if op.Amount == 0 {
+			op.Amount = p.Amount.Amount
+		}
+		if op.Amount > p.Amount.Amount {
+			return fmt.Errorf("%w: capture exceeds the authorized amount", paymentErr.ErrInvalidPayment)
+		}
+		return nil
+	})
+}
+
+// Refund returns amount of a captured payment; zero refunds the remainder.
+func (s *Service) Refund(ctx context.Context, idempotencyKey, id string, amount int64) (*model.Payment, bool, error) {
+	return s.operate(ctx, idempotencyKey, id, model.OperationRefund, amount, func(p *model.Payment, op *model.Operation) error {
+		if p.Status != model.StatusCaptured && p.Status != model.StatusPartiallyRefunded {
+			return fmt.Errorf("%w: cannot refund a %s payment", paymentErr.ErrInvalidPaymentState, p.Status)
+		}
+		remaining := p.Captured - p.Refunded
+		if op.Amount == 0 {
+			op.Amount = remaining
+		}
+		if op.Amount > remaining {
+			return fmt.Errorf("%w: refund exceeds the captured amount", paymentErr.ErrInvalidPayment)
+		}
+		return nil
+	})
+}
+
+// Void cancels an authorization that has not been captured.
+func (s *Service) Void(ctx context.Context, idempotencyKey, id string) (*model.Payment, bool, error) {
+	return s.operate(ctx, idempotencyKey, id, model.OperationVoid, 0, func(p *model.Payment, _ *model.Operation) error {
+		if p.Status != model.StatusAuthorized {
+			return fmt.Errorf("%w: cannot void a %s payment", paymentErr.ErrInvalidPaymentState, p.Status)
+		}
+		return nil
+	})
+}
+
+func (s *Service) operate(ctx context.Context, idempotencyKey, id string, kind model.OperationKind, amount int64, check func(*model.Payment, *model.Operation) error) (*model.Payment, bool, error) {
+	idempotencyKey, err := checkKey(idempotencyKey)
+	if err != nil {
+		return nil, false, err
+	}
+	if id == "" {
+		return nil, false, paymentErr.ErrPaymentNotFound
+	}
+	if amount < 0 {
+		return nil, false, fmt.Errorf("%w: amount must not be negative", paymentErr.ErrInvalidPayment)
+	}
+
+	hash, err := requestHash(kind, struct {
+		PaymentID string `json:"payment_id"`
+		Amount    int64  `json:"amount"`
+	}{id, amount})
+	if err != nil {
+		return nil, false, err
+	}
+
+	p, op, replayed, err := s.repo.StartOperation(ctx, model.Operation{
+		PaymentID:      id,
+		Kind:           kind,
+		Amount:         amount,
+		IdempotencyKey: idempotencyKey,
+		RequestHash:    hash,
+	}, check)
+	if err != nil {
+		return nil, false, err
+	}
+
+	p, err = s.execute(ctx, p, op)
+	return p, replayed, err
+}
+
+// execute calls the provider for a pending operation and stores the answer.
+// If the provider cannot be reached the operation stays pending, and a retry
+// with the same idempotency key repeats the same provider call.
+func (s *Service) execute(ctx context.Context, p *model.Payment, op *model.Operation) (*model.Payment, error) {
+	if op.Status == model.OperationPending {
+		result, err := s.call(ctx, p, op)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", paymentErr.ErrProviderUnavailable, err)
+		}
+
+		p, op, err = s.repo.FinishOperation(ctx, op.ID, func(p *model.Payment, op *model.Operation) []model.Event {
+			return settle(p, op, result)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if op.Status == model.OperationFailed {
+		return p, fmt.Errorf("%w: %s", paymentErr.ErrPaymentDeclined, op.FailureReason)
+	}
+
+	return p, nil
+}
+
+// call uses the operation id as the provider idempotency key, so repeated
+// calls for one operation are deduplicated by the provider.
+func (s *Service) call(ctx context.Context, p *model.Payment, op *model.Operation) (provider.Result, error) {
+	switch op.Kind {
+	case model.OperationAuthorize:
+		return s.provider.Authorize(ctx, op.ID, provider.AuthorizeRequest{
+			Reference:     p.ID,
+			Amount:        p.Amount.Amount,
+			Currency:      p.Amount.Currency,
+			PaymentMethod: p.PaymentMethod,
+		})
+	case model.OperationCapture:
+		return s.provider.Capture(ctx, op.ID, p.ProviderRef, op.Amount)
+	case model.OperationRefund:
+		return s.provider.Refund(ctx, op.ID, p.ProviderRef, op.Amount)
+	case model.OperationVoid:
+		return s.provider.Void(ctx, op.ID, p.ProviderRef)
+	}
+	return provider.Result{}, fmt.Errorf("unknown operation %q", op.Kind)
+}
+
+// settle applies a provider result to the payment and returns the events it
+// produced.
+func settle(p *model.Payment, op *model.Operation, result provider.Result) []model.Event {
+	if result.ProviderRef != "" {
+		p.ProviderRef = result.ProviderRef
+	}
+
+	if !result.Approved {
+		op.Status = model.OperationFailed
+		op.FailureReason = result.DeclineCode
+		if op.Kind != model.OperationAuthorize {
+			return nil
+		}
+		p.Status = model.StatusDeclined
+		p.DeclineCode = result.DeclineCode
+		return []model.Event{paymentEvent(p, domain.TopicPaymentDeclined, p.Amount.Amount, result.DeclineCode)}
+	}
+
+	op.Status = model.OperationSucceeded
+	switch op.Kind {
+	case model.OperationAuthorize:
+		p.Status = model.StatusAuthorized
+		return []model.Event{paymentEvent(p, domain.TopicPaymentAuthorized, p.Amount.Amount, "")}
+	case model.OperationCapture:
+		p.Status = model.StatusCaptured
+		p.Captured = op.Amount
+		return []model.Event{paymentEvent(p, domain.TopicPaymentCaptured, op.Amount, "")}
+	case model.OperationRefund:
+		p.Refunded += op.Amount
+		p.Status = refundStatus(p)
+		return []model.Event{paymentEvent(p, domain.TopicPaymentRefunded, op.Amount, "")}
+	case model.OperationVoid:
+		p.Status = model.StatusVoided
+		return []model.Event{paymentEvent(p, domain.TopicPaymentVoided, p.Amount.Amount, "")}
+	}
+	return nil
+}
+
+func refundStatus(p *model.Payment) model.Status {
+	if p.Refunded >= p.Captured {
+		return model.StatusRefunded
+	}
+	return model.StatusPartiallyRefunded
+}
+
+func paymentEvent(p *model.Payment, topic string, amount int64, reason string) model.Event {
+	change := domain.PaymentChange{
+		PaymentID:   p.ID,
+		OrderID:     p.OrderID,
+		Status:      string(p.Status),
+		AmountMinor: amount,
+		Currency:    p.Amount.Currency,
+		Reason:      reason,
+		OccurredAt:  time.Now().UTC(),
+	}
+
+	event := model.Event{Topic: topic, Key: p.OrderID}
+	switch topic {
+	case domain.TopicPaymentAuthorized:
+		event.Payload = domain.PaymentAuthorizedEvent{PaymentChange: change}
+	case domain.TopicPaymentDeclined:
+		event.Payload = domain.PaymentDeclinedEvent{PaymentChange: change}
+	case domain.TopicPaymentCaptured:
+		event.Payload = domain.PaymentCapturedEvent{PaymentChange: change}
+	case domain.TopicPaymentRefunded:
+		event.Payload = domain.PaymentRefundedEvent{PaymentChange: change}
+	case domain.TopicPaymentVoided:
+		event.Payload = domain.PaymentVoidedEvent{PaymentChange: change}
+	}
+	return event
+}
+
+func checkKey(key string) (string, error) {
+	key = strings.TrimSpace(key)
+	if key == "" || len(key) > maxKeyLength {
+		return "", paymentErr.ErrIdempotencyKeyRequired
+	}
+	return key, nil
+}
+
+func requestHash(kind model.OperationKind, req any) (string, error) {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash request: %w", err)
+	}
+	sum := sha256.Sum256(append([]byte(kind+":"), b...))
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	domain "github.com/go-market/pkg/domain/model"
+	"github.com/go-market/services/payment/internal/model"
+	"github.com/go-market/services/payment/internal/webhook"
+)
+
+// HandleWebhook verifies a provider callback and reconciles the payment it
+// refers to. Webhook amounts are the provider's running totals, so applying
+// a late or repeated event never double-counts. Duplicate events are
+// ignored and reported with applied=false.
+func (s *Service) HandleWebhook(ctx context.Context, signature string, body []byte) (applied bool, err error) {
+	event, err := webhook.Parse(s.webhooks.Secret, signature, body, time.Now(), s.webhooks.Tolerance)
+	if err != nil {
+		return false, err
+	}
+
+	_, applied, err = s.repo.ApplyWebhook(ctx, s.provider.Name(), event.ID, event.Type, event.ProviderRef,
+		func(p *model.Payment) []model.Event {
+			return reconcile(p, event)
+		})
+	return applied, err
+}
+
+func reconcile(p *model.Payment, event *webhook.Event) []model.Event {
+	switch event.Type {
+	case webhook.EventCaptured:
+		if p.Status != model.StatusAuthorized {
+			return nil
+		}
+		captured := event.Amount
+		if captured <= 0 || captured > p.Amount.Amount {
+			captured = p.Amount.Amount
+		}
+		p.Status = model.StatusCaptured
+		p.Captured = captured
+		return []model.Event{paymentEvent(p, domain.TopicPaymentCaptured, captured, "provider webhook")}
+
+	case webhook.EventRefunded:
+		if p.Status != model.StatusCaptured && p.Status != model.StatusPartiallyRefunded {
+			return nil
+		}
+		if event.Amount <= p.Refunded || event.Amount > p.Captured {
+			return nil
+		}
+		delta := event.Amount - p.Refunded
+		p.Refunded = event.Amount
+		p.Status = refundStatus(p)
+		return []model.Event{paymentEvent(p, domain.TopicPaymentRefunded, delta, "provider webhook")}
+
+	case webhook.EventVoided:
+		if p.Status != model.StatusAuthorized {
+			return nil
+		}
+		p.Status = model.StatusVoided
+		return []model.Event{paymentEvent(p, domain.TopicPaymentVoided, p.Amount.Amount, "provider webhook")}
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+// Package webhook verifies provider callbacks. Payloads are signed with
+// HMAC-SHA256 over "<unix timestamp>.<body>" and the signature is sent as
+//
+//	X-Webhook-Signature: t=<unix timestamp>,v1=<hex digest>
+//
+// The timestamp is part of the signed data so captured requests cannot be
+// replayed outside the tolerance window.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	paymentErr "github.com/go-market/pkg/errs"
+)
+
+const SignatureHeader = "X-Webhook-Signature"
+
+// Event types understood by the payment service.
+const (
+	EventCaptured = "payment.captured"
+	EventRefunded = "payment.refunded"
+	EventVoided   = "payment.voided"
+)
+
+// Event is a provider notification. Amount is the provider's running total
+// for the event type (e.g. everything refunded so far), not a delta.
+type Event struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	ProviderRef string    `json:"provider_ref"`
+	Amount      int64     `json:"amount"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Sign returns the signature header value for body at time ts.
+func Sign(secret string, ts time.Time, body []byte) string {
+	unix := strconv.FormatInt(ts.Unix(), 10)
+	return "t=" + unix + ",v1=" + digest(secret, unix, body)
+}
+
+// Parse verifies signature against body and returns the decoded event.
+func Parse(secret, signature string, body []byte, now time.Time, tolerance time.Duration) (*Event, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("%w: webhook secret is not configured", paymentErr.ErrInvalidSignature)
+	}
+
+	var unix, sig string
+	for _, part := range strings.Split(signature, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			unix = v
+		case "v1":
+			sig = v
+		}
+	}
+	if unix == "" || sig == "" {
+		return nil, paymentErr.ErrInvalidSignature
+	}
+
+	ts, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil {
+		return nil, paymentErr.ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > tolerance || age < -tolerance {
+		return nil, fmt.Errorf("%w: timestamp outside tolerance", paymentErr.ErrInvalidSignature)
+	}
+
+	expected, err := hex.DecodeString(sig)
+	if err != nil {
+		return nil, paymentErr.ErrInvalidSignature
+	}
+	actual, _ := hex.DecodeString(digest(secret, unix, body))
+	if !hmac.Equal(expected, actual) {
+		return nil, paymentErr.ErrInvalidSignature
+	}
+
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("%w: malformed payload", paymentErr.ErrInvalidPayment)
+	}
+	if event.ID == "" || event.Type == "" || event.ProviderRef == "" {
+		return nil, fmt.Errorf("%w: webhook needs id, type and provider_ref", paymentErr.ErrInvalidPayment)
+	}
+
+	return &event, nil
+}
+
+func digest(secret, unix string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
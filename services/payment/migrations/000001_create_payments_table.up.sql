@@ -0,0 +1,50 @@
+CREATE TABLE IF NOT EXISTS payments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id VARCHAR(255) NOT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT 'pending',
+    amount_minor BIGINT NOT NULL CHECK (amount_minor > 0),
+    currency CHAR(3) NOT NULL,
+    captured_minor BIGINT NOT NULL DEFAULT 0,
+    refunded_minor BIGINT NOT NULL DEFAULT 0,
+    payment_method VARCHAR(255) NOT NULL,
+    provider VARCHAR(32) NOT NULL,
+    provider_ref VARCHAR(255),
+    decline_code VARCHAR(64),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (status IN ('pending', 'authorized', 'declined', 'captured', 'partially_refunded', 'refunded', 'voided')),
+    CHECK (captured_minor >= 0 AND captured_minor <= amount_minor),
+    CHECK (refunded_minor >= 0 AND refunded_minor <= captured_minor)
+);
+
+CREATE INDEX IF NOT EXISTS payments_order_id_idx ON payments (order_id);
+-- An order has at most one live payment. Declined and voided payments do not
+-- count, so an order can be paid again after a card was refused.
+CREATE UNIQUE INDEX IF NOT EXISTS payments_order_id_active_idx ON payments (order_id)
+    WHERE status NOT IN ('declined', 'voided');
+CREATE UNIQUE INDEX IF NOT EXISTS payments_provider_ref_idx ON payments (provider, provider_ref);
+
+CREATE TABLE IF NOT EXISTS payment_operations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    payment_id UUID NOT NULL REFERENCES payments (id) ON DELETE CASCADE,
+    kind VARCHAR(16) NOT NULL,
+    amount_minor BIGINT NOT NULL DEFAULT 0,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    idempotency_key VARCHAR(255) NOT NULL UNIQUE,
+    request_hash TEXT NOT NULL,
+    failure_reason VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (kind IN ('authorize', 'capture', 'refund', 'void')),
+    CHECK (status IN ('pending', 'succeeded', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS payment_operations_pending_idx ON payment_operations (payment_id) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS webhook_events (
+    provider VARCHAR(32) NOT NULL,
+    event_id VARCHAR(255) NOT NULL,
+    type VARCHAR(64) NOT NULL,
+    received_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (provider, event_id)
+);
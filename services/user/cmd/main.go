@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"os"
 
 	"github.com/go-market/pkg/logger/handlers/slogpretty"
+	"github.com/go-market/pkg/migrate"
 	"github.com/go-market/services/user/internal/app"
 	"github.com/go-market/services/user/internal/config"
+	"github.com/go-market/services/user/internal/repository/postgres"
 )
 
 const (
@@ -22,6 +25,14 @@ func main() {
 	log.Info("info level starting up", slog.String("env", cfg.Env))
 	log.Debug("debug level starting up")
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(*cfg, log, os.Args[2:]); err != nil {
+			log.Error("migrate failed", slog.Any("err", err))
+			os.Exit(1)
+		}
+		return
+	}
+
 	application, err := app.NewApp(*cfg, log)
 	if err != nil {
 		log.Error("failed to initialize app", slog.Any("err", err))
@@ -34,6 +45,22 @@ func main() {
 	}
 }
 
+// runMigrate handles `<binary> migrate up|down|status`.
+func runMigrate(cfg config.Config, log *slog.Logger, args []string) error {
+	repo, err := postgres.New(cfg.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	migrator, err := migrate.New(repo.Pool(), cfg.MigrationsPath, log)
+	if err != nil {
+		return err
+	}
+
+	return migrate.Command(context.Background(), migrator, args, os.Stdout)
+}
+
 func setupLogger(env string) *slog.Logger {
 	var log *slog.Logger
 
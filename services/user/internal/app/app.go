@@ -2,24 +2,47 @@ package app
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-market/pkg/authz"
+	domain "github.com/go-market/pkg/domain/model"
+	"github.com/go-market/pkg/kafka"
+	"github.com/go-market/pkg/mailer"
+	"github.com/go-market/pkg/migrate"
+	"github.com/go-market/pkg/outbox"
+	pkgRedis "github.com/go-market/pkg/redis"
+	"github.com/go-market/services/user/internal/blob"
+	"github.com/go-market/services/user/internal/blob/local"
+	"github.com/go-market/services/user/internal/blob/s3"
 	"github.com/go-market/services/user/internal/config"
 	userHTTP "github.com/go-market/services/user/internal/derivery/http"
+	userKafka "github.com/go-market/services/user/internal/derivery/kafka"
+	"github.com/go-market/services/user/internal/repository/cache"
 	"github.com/go-market/services/user/internal/repository/postgres"
+	userRedis "github.com/go-market/services/user/internal/repository/redis"
 	"github.com/go-market/services/user/internal/service"
+	"github.com/redis/go-redis/v9"
 )
 
 type App struct {
-	server *http.Server
-	log    *slog.Logger
+	server   *http.Server
+	relay    *outbox.Relay
+	consumer *kafka.Consumer[domain.UserRegisteredEvent]
+	reader   *kafka.GroupReader
+	producer *kafka.Producer
+	repo     *postgres.PostgresRepo
+	rdb      *redis.Client
+	log      *slog.Logger
 }
 
 func NewApp(cfg config.Config, log *slog.Logger) (*App, error) {
@@ -27,23 +50,92 @@ func NewApp(cfg config.Config, log *slog.Logger) (*App, error) {
 
 	logger := log.With(slog.String("op", op))
 
+	// An empty secret would make every verification token forgeable.
+	if strings.TrimSpace(cfg.Verification.Secret) == "" {
+		return nil, fmt.Errorf("%s: verification secret is not set", op)
+	}
+
 	repo, err := postgres.New(cfg.DatabaseURL)
 	if err != nil {
 		logger.Error("failed to init postgres", slog.String("op", op), slog.Any("err", err))
 		return nil, err
 	}
-	svc := service.New(repo)
 
-	userHandler := userHTTP.New(log, svc)
+	if err := migrateSchema(repo, cfg.MigrationsPath, log); err != nil {
+		logger.Error("failed to migrate database", slog.Any("err", err))
+		repo.Close()
+		return nil, err
+	}
+
+	rdb := pkgRedis.NewClient(cfg.RedisAddr)
+	if err := rdb.Ping(context.Background()).Err(); err != nil {
+		logger.Warn("redis is unreachable, user lookups will hit postgres and authenticated requests will be refused", slog.Any("err", err))
+	}
+
+	cachedRepo := cache.New(repo, rdb, cfg.CacheTTL, log)
+	svc := service.New(cachedRepo)
+
+	store, err := newAvatarStore(cfg.Avatar)
+	if err != nil {
+		logger.Error("failed to init avatar store", slog.Any("err", err))
+		repo.Close()
+		return nil, err
+	}
+	avatars := service.NewAvatars(cachedRepo, store, service.AvatarOptions{
+		MaxBytes:     cfg.Avatar.MaxBytes,
+		MinDimension: cfg.Avatar.MinDimension,
+		MaxDimension: cfg.Avatar.MaxDimension,
+		Sizes:        cfg.Avatar.Sizes,
+	})
+
+	mail, err := mailer.New(cfg.Mailer, log)
+	if err != nil {
+		logger.Error("failed to init mailer", slog.Any("err", err))
+		repo.Close()
+		return nil, err
+	}
+	verification := service.NewVerification(cachedRepo, userRedis.NewVerificationStore(rdb), mail, service.VerificationOptions{
+		Secret:       cfg.Verification.Secret,
+		TokenTTL:     cfg.Verification.TokenTTL,
+		LinkURL:      cfg.Verification.LinkURL,
+		ResendLimit:  cfg.Verification.ResendLimit,
+		ResendWindow: cfg.Verification.ResendWindow,
+	})
+
+	producer := kafka.NewProducer(cfg.Kafka.Brokers)
+	relay := outbox.NewRelay(repo.Pool(), producer, log, outbox.Config{
+		PollInterval: cfg.Outbox.PollInterval,
+		BatchSize:    cfg.Outbox.BatchSize,
+		BaseBackoff:  cfg.Outbox.BaseBackoff,
+		MaxBackoff:   cfg.Outbox.MaxBackoff,
+	})
+
+	reader := kafka.NewGroupReader(cfg.Kafka.Brokers, domain.TopicUserRegistered, cfg.Kafka.Group)
+	registrations := kafka.NewConsumer(reader, producer, userKafka.UserRegisteredHandler(log, verification), log, kafka.ConsumerConfig{
+		Topic:          domain.TopicUserRegistered,
+		Group:          cfg.Kafka.Group,
+		MaxAttempts:    cfg.Kafka.Consumer.MaxAttempts,
+		BaseBackoff:    cfg.Kafka.Consumer.BaseBackoff,
+		MaxBackoff:     cfg.Kafka.Consumer.MaxBackoff,
+		HandlerTimeout: cfg.Kafka.Consumer.HandlerTimeout,
+	})
+
+	userHandler := userHTTP.New(log, svc, avatars, verification)
 
 	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
 	r.Route("/api/v1", func(r chi.Router) {
-		userHTTP.RegisterUserRoutes(r, userHandler, cfg.SecretKey)
+		userHTTP.RegisterUserRoutes(r, userHandler, authz.NewRemoteVerifier(cfg.Auth), authz.NewRedisDenyList(rdb))
 	})
 
+	if fs, ok := store.(*local.Store); ok {
+		route := strings.TrimSuffix(cfg.Avatar.Local.Route, "/")
+		r.Handle(route+"/*", http.StripPrefix(route, fs.Handler()))
+	}
+
 	server := &http.Server{
 		Addr:         cfg.HTTPAddr.Address,
 		Handler:      r,
@@ -52,10 +144,73 @@ func NewApp(cfg config.Config, log *slog.Logger) (*App, error) {
 		IdleTimeout:  60 * time.Second,
 	}
 
-	return &App{server: server, log: log}, nil
+	return &App{
+		server:   server,
+		relay:    relay,
+		consumer: registrations,
+		reader:   reader,
+		producer: producer,
+		repo:     repo,
+		rdb:      rdb,
+		log:      log,
+	}, nil
+}
+
+func newAvatarStore(cfg config.Avatar) (blob.Store, error) {
+	switch cfg.Store {
+	case "local":
+		return local.New(cfg.Local.Dir, cfg.Local.PublicURL)
+	case "s3":
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		return s3.New(ctx, s3.Config{
+			Endpoint:  cfg.S3.Endpoint,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey,
+			Bucket:    cfg.S3.Bucket,
+			Region:    cfg.S3.Region,
+			UseSSL:    cfg.S3.UseSSL,
+			PublicURL: cfg.S3.PublicURL,
+		})
+	default:
+		return nil, fmt.Errorf("unknown avatar store %q", cfg.Store)
+	}
+}
+
+// migrateSchema applies pending migrations and then verifies that the schema
+// matches what the repository queries expect.
+func migrateSchema(repo *postgres.PostgresRepo, path string, log *slog.Logger) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	migrator, err := migrate.New(repo.Pool(), path, log)
+	if err != nil {
+		return err
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		return err
+	}
+
+	return repo.CheckSchema(ctx)
 }
 
 func (a *App) Run() error {
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.relay.Run(bgCtx)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.consumer.Run(bgCtx)
+	}()
+
 	go func() {
 		if err := a.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			a.log.Error("listen failed", slog.Any("err", err))
@@ -75,5 +230,19 @@ func (a *App) Run() error {
 		panic(err)
 	}
 
+	stopBackground()
+	wg.Wait()
+
+	if err := a.reader.Close(); err != nil {
+		a.log.Error("failed to close kafka reader", slog.Any("err", err))
+	}
+	if err := a.producer.Close(); err != nil {
+		a.log.Error("failed to close kafka producer", slog.Any("err", err))
+	}
+	if err := a.rdb.Close(); err != nil {
+		a.log.Error("failed to close redis client", slog.Any("err", err))
+	}
+	a.repo.Close()
+
 	return nil
 }
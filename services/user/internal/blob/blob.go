@@ -0,0 +1,20 @@
+// Package blob abstracts where uploaded files live. Keys are slash-separated
+// paths such as "<user id>/<upload id>/256.png"; a store turns a key into
+// the public URL clients fetch it from.
+package blob
+
+import (
+	"context"
+	"io"
+)
+
+type Store interface {
+	// Put stores size bytes from r under key, replacing any existing object,
+	// and returns its public URL.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (string, error)
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// DeletePrefix removes every key under prefix, which must end in "/".
+	// Nothing under prefix is not an error.
+	DeletePrefix(ctx context.Context, prefix string) error
+}
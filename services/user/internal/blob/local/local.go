@@ -0,0 +1,128 @@
+// Package local stores blobs on the local filesystem. The directory is
+// served by the user service itself, which makes it suitable for
+// development and single-node deployments.
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type Store struct {
+	dir     string
+	baseURL string
+}
+
+// New stores files under dir and builds URLs as baseURL + "/" + key.
+func New(dir, baseURL string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("local.New: %w", err)
+	}
+	return &Store{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+// Handler serves the stored files. Directories are reported as missing,
+// so one user's uploads cannot be listed by browsing their parent.
+func (s *Store) Handler() http.Handler {
+	return http.FileServer(filesOnly{http.Dir(s.dir)})
+}
+
+func (s *Store) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (string, error) {
+	const op = "local.Put"
+
+	name, err := s.path(key)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Write to a temporary file first so readers never see a partial object.
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, io.LimitReader(r, size)); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s.baseURL + "/" + key, nil
+}
+
+func (s *Store) Delete(ctx context.Context, key string) error {
+	const op = "local.Delete"
+
+	name, err := s.path(key)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *Store) DeletePrefix(ctx context.Context, prefix string) error {
+	const op = "local.DeletePrefix"
+
+	if !strings.HasSuffix(prefix, "/") {
+		return fmt.Errorf("%s: invalid prefix %q", op, prefix)
+	}
+	name, err := s.path(strings.TrimSuffix(prefix, "/"))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := os.RemoveAll(name); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// path maps key into the store directory, rejecting keys that would escape it.
+func (s *Store) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if clean == "/" || clean != "/"+key {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(clean)), nil
+}
+
+// filesOnly hides directories from http.FileServer, which would otherwise
+// render them as listings.
+type filesOnly struct {
+	fs http.FileSystem
+}
+
+func (f filesOnly) Open(name string) (http.File, error) {
+	file, err := f.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		file.Close()
+		return nil, os.ErrNotExist
+	}
+	return file, nil
+}
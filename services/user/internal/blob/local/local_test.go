@@ -0,0 +1,84 @@
+package local
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func put(t *testing.T, s *Store, key string) {
+	t.Helper()
+
+	if _, err := s.Put(context.Background(), key, strings.NewReader("img"), 3, "image/png"); err != nil {
+		t.Fatalf("Put(%q) error = %v", key, err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	s, err := New(t.TempDir(), "http://localhost/avatars")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	put(t, s, "u1/a/256.png")
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{path: "/u1/a/256.png", want: http.StatusOK},
+		{path: "/u1/a/64.png", want: http.StatusNotFound},
+		{path: "/u1/a/", want: http.StatusNotFound},
+		{path: "/u1/", want: http.StatusNotFound},
+		{path: "/", want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != tt.want {
+			t.Errorf("GET %s = %d, want %d", tt.path, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestDeletePrefix(t *testing.T) {
+	ctx := context.Background()
+	s, err := New(t.TempDir(), "http://localhost/avatars")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	put(t, s, "u1/a/256.png")
+	put(t, s, "u1/b/256.png")
+	put(t, s, "u2/c/256.png")
+
+	if err := s.DeletePrefix(ctx, "u1/a/"); err != nil {
+		t.Fatalf("DeletePrefix() error = %v", err)
+	}
+	if err := s.DeletePrefix(ctx, "u1/missing/"); err != nil {
+		t.Fatalf("DeletePrefix() of nothing error = %v", err)
+	}
+	for _, prefix := range []string{"u1", "", "/", "../"} {
+		if err := s.DeletePrefix(ctx, prefix); err == nil {
+			t.Errorf("DeletePrefix(%q) error = nil", prefix)
+		}
+	}
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{path: "/u1/a/256.png", want: http.StatusNotFound},
+		{path: "/u1/b/256.png", want: http.StatusOK},
+		{path: "/u2/c/256.png", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != tt.want {
+			t.Errorf("GET %s = %d, want %d", tt.path, rec.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	userErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/pkg/problem"
+)
+
+const avatarFormField = "avatar"
+
+// multipartOverhead leaves room for boundaries and part headers on top of
+// the image itself.
+const multipartOverhead = 64 << 10
+
+type ThumbnailResponse struct {
+	Size int    `json:"size"`
+	URL  string `json:"url"`
+}
+
+type AvatarResponse struct {
+	Avatar     string              `json:"avatar"`
+	Thumbnails []ThumbnailResponse `json:"thumbnails"`
+}
+
+// UploadAvatar serves POST /users/{id}/avatar. The body is multipart/form-data
+// with the image in the "avatar" part; the part is streamed, never buffered
+// to disk. If-Match is optional here; without it the upload applies to the
+// version current when it started.
+func (h *UserHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
+	const op = "UserHandler.UploadAvatar"
+	log := h.log.With(slog.String("op", op))
+
+	id := chi.URLParam(r, "id")
+
+	var version int64
+	if r.Header.Get("If-Match") != "" {
+		v, err := ifMatchVersion(r)
+		if err != nil {
+			log.Error("invalid If-Match", slog.String("error", err.Error()))
+			problem.Write(w, r, err)
+			return
+		}
+		version = v
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.avatars.MaxBytes()+multipartOverhead)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		log.Error("failed to read multipart body", slog.String("error", err.Error()))
+		problem.Write(w, r, userErr.Wrap(userErr.ErrUnsupportedMediaType, err))
+		return
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			log.Error("failed to find avatar part", slog.String("error", err.Error()))
+			var tooLarge *http.MaxBytesError
+			switch {
+			case errors.As(err, &tooLarge):
+				problem.Write(w, r, userErr.Wrap(userErr.ErrAvatarTooLarge, err))
+			case errors.Is(err, io.EOF):
+				problem.Write(w, r, userErr.ErrAvatarRequired)
+			default:
+				problem.Write(w, r, userErr.Wrap(userErr.ErrInvalidRequestBody, err))
+			}
+			return
+		}
+		if part.FormName() != avatarFormField {
+			part.Close()
+			continue
+		}
+
+		upload, err := h.avatars.Upload(r.Context(), id, version, part)
+		part.Close()
+		if err != nil {
+			log.Error("failed to upload avatar", slog.String("error", err.Error()))
+			problem.Write(w, r, err)
+			return
+		}
+
+		response := AvatarResponse{
+			Avatar:     upload.User.Avatar,
+			Thumbnails: make([]ThumbnailResponse, 0, len(upload.Thumbnails)),
+		}
+		for _, t := range upload.Thumbnails {
+			response.Thumbnails = append(response.Thumbnails, ThumbnailResponse{Size: t.Size, URL: t.URL})
+		}
+
+		w.Header().Set("ETag", etag(upload.User.Version))
+		render.JSON(w, r, SuccessResponse{Data: response, Message: "avatar updated successfully"})
+		return
+	}
+}
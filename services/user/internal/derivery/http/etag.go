@@ -0,0 +1,38 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	userErr "github.com/go-market/pkg/errs"
+)
+
+// etag renders a user version as a strong entity tag.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ifMatchVersion returns the version a write is conditional on. "*" matches
+// any current version and yields 0. A tag this service never issued, weak
+// ones included, can never match and fails with ErrVersionConflict.
+func ifMatchVersion(r *http.Request) (int64, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		return 0, userErr.ErrIfMatchRequired
+	}
+	if header == "*" {
+		return 0, nil
+	}
+
+	tag, err := strconv.Unquote(header)
+	if err != nil {
+		return 0, userErr.ErrVersionConflict
+	}
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, userErr.ErrVersionConflict
+	}
+
+	return version, nil
+}
@@ -1,80 +0,0 @@
-package middleware
-
-import (
-	"context"
-	"net/http"
-	"strings"
-
-	"github.com/go-chi/render"
-	"github.com/golang-jwt/jwt/v5"
-)
-
-type ErrorResponse struct {
-	Error string `json:"error"`
-}
-
-type SuccessResponse struct {
-	Data    interface{} `json:"data,omitempty"`
-	Message string      `json:"message,omitempty"`
-}
-
-type contextKey string
-
-const UserIDKey contextKey = "user_id"
-
-func AuthMiddleware(secret string) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" {
-				render.Status(r, http.StatusUnauthorized)
-				render.JSON(w, r, ErrorResponse{Error: "missing authorization header"})
-				return
-			}
-
-			parts := strings.Split(authHeader, " ")
-			if len(parts) != 2 || parts[0] != "Bearer" {
-				render.Status(r, http.StatusUnauthorized)
-				render.JSON(w, r, ErrorResponse{Error: "invalid authorization header format"})
-				return
-			}
-
-			tokenStr := parts[1]
-			token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-				return []byte(secret), nil
-			})
-			if err != nil || !token.Valid {
-				render.Status(r, http.StatusUnauthorized)
-				render.JSON(w, r, ErrorResponse{Error: "invalid token"})
-				return
-			}
-
-			claims, ok := token.Claims.(jwt.MapClaims)
-			if !ok {
-				render.Status(r, http.StatusUnauthorized)
-				render.JSON(w, r, ErrorResponse{Error: "invalid token claims"})
-				return
-			}
-
-			userID, ok := claims["sub"].(string)
-			if !ok {
-				render.Status(r, http.StatusUnauthorized)
-				render.JSON(w, r, ErrorResponse{Error: "invalid subject"})
-				return
-			}
-
-			role, ok := claims["role"].(string)
-			if !ok {
-				render.Status(r, http.StatusUnauthorized)
-				render.JSON(w, r, ErrorResponse{Error: "invalid role"})
-				return
-			}
-
-			ctx := context.WithValue(r.Context(), UserIDKey, userID)
-			ctx = context.WithValue(ctx, RoleKey, role)
-
-			next.ServeHTTP(w, r.WithContext(ctx))
-
-		})
-	}
-}
@@ -2,59 +2,129 @@ package http
 
 import (
 	"encoding/json"
-	"errors"
 	"log/slog"
+	"mime"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
+	"github.com/go-market/pkg/authz"
 	userErr "github.com/go-market/pkg/errs"
-	"github.com/go-market/services/user/internal/derivery/http/middleware"
+	"github.com/go-market/pkg/problem"
+	"github.com/go-market/pkg/validation"
 	"github.com/go-market/services/user/internal/model"
 	"github.com/go-market/services/user/internal/service"
 )
 
 type UserHandler struct {
-	log *slog.Logger
-	svc *service.Service
+	log          *slog.Logger
+	svc          *service.Service
+	avatars      *service.Avatars
+	verification *service.Verification
 }
 
-func New(log *slog.Logger, svc *service.Service) *UserHandler {
+func New(log *slog.Logger, svc *service.Service, avatars *service.Avatars, verification *service.Verification) *UserHandler {
 	return &UserHandler{
-		log: log,
-		svc: svc,
+		log:          log,
+		svc:          svc,
+		avatars:      avatars,
+		verification: verification,
 	}
 }
 
 type CreateUserRequest struct {
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Avatar   string `json:"avatar" validate:"omitempty"`
+	Username string `json:"username" validate:"required,username"`
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Avatar   string `json:"avatar" validate:"omitempty,http_url,max=2048"`
 }
 
+// UpdateUserRequest is the full replacement sent with PUT. A merge patch is
+// checked against the same rules, restricted to the members it contains.
 type UpdateUserRequest struct {
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Avatar   string `json:"avatar" validate:"omitempty"`
+	Username string `json:"username" validate:"required,username"`
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Avatar   string `json:"avatar" validate:"omitempty,http_url,max=2048"`
 }
 
 type UserResponse struct {
-	ID        string    `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	Avatar    string    `json:"avatar"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID            string    `json:"id"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email"`
+	Avatar        string    `json:"avatar"`
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
-type ErrorResponse struct {
-	Error string `json:"error"`
+func toUserResponse(u *model.User) UserResponse {
+	return UserResponse{
+		ID:            u.ID,
+		Username:      u.Username,
+		Email:         u.Email,
+		Avatar:        u.Avatar,
+		EmailVerified: u.EmailVerified,
+		CreatedAt:     u.CreatedAt,
+		UpdatedAt:     u.UpdatedAt,
+	}
 }
 
 type SuccessResponse struct {
-	Data    interface{} `json:"data,omitempty"`
-	Message string      `json:"message,omitempty"`
+	Data       interface{} `json:"data,omitempty"`
+	Message    string      `json:"message,omitempty"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+type Pagination struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+func (h *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
+	const op = "UserHandler.Create"
+	log := h.log.With(slog.String("op", op))
+
+	var req CreateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("failed to decode request", slog.String("error", err.Error()))
+		problem.Write(w, r, userErr.Wrap(userErr.ErrInvalidRequestBody, err))
+		return
+	}
+	if err := validation.Struct(req); err != nil {
+		log.Error("invalid request", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	principal, ok := authz.FromContext(r.Context())
+	if !ok {
+		log.Error("failed to extract user id from context")
+		problem.Write(w, r, userErr.ErrUnauthenticated)
+		return
+	}
+
+	// A profile is created for the authenticated account, so its id matches
+	// the token subject and GET /users/me resolves to it.
+	user, err := h.svc.Create(r.Context(), model.User{
+		ID:       principal.UserID,
+		Username: req.Username,
+		Email:    req.Email,
+		Avatar:   req.Avatar,
+	}, principal.Roles)
+	if err != nil {
+		log.Error("failed to create user", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	response := toUserResponse(user)
+
+	w.Header().Set("Location", "/api/v1/users/"+user.ID)
+	w.Header().Set("ETag", etag(user.Version))
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, SuccessResponse{Data: response})
 }
 
 func (h *UserHandler) GetByID(w http.ResponseWriter, r *http.Request) {
@@ -66,30 +136,13 @@ func (h *UserHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	user, err := h.svc.GetByID(r.Context(), id)
 	if err != nil {
 		log.Error("failed to get user by id", slog.String("error", err.Error()))
-		if errors.Is(err, userErr.ErrInvalidID) {
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, ErrorResponse{Error: err.Error()})
-			return
-		}
-		if errors.Is(err, userErr.ErrUserNotFound) {
-			render.Status(r, http.StatusNotFound)
-			render.JSON(w, r, ErrorResponse{Error: err.Error()})
-			return
-		}
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, ErrorResponse{Error: "failed to get user"})
+		problem.Write(w, r, err)
 		return
 	}
 
-	response := UserResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		Avatar:    user.Avatar,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
-	}
+	response := toUserResponse(user)
 
+	w.Header().Set("ETag", etag(user.Version))
 	render.JSON(w, r, SuccessResponse{Data: response})
 }
 
@@ -97,41 +150,23 @@ func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) {
 	const op = "UserHandler.GetMe"
 	log := h.log.With(slog.String("op", op))
 
-	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
-	if !ok || userID == "" {
+	principal, ok := authz.FromContext(r.Context())
+	if !ok {
 		log.Error("failed to extract user id from context")
-		render.Status(r, http.StatusUnauthorized)
-		render.JSON(w, r, ErrorResponse{Error: "invalid user context"})
+		problem.Write(w, r, userErr.ErrUnauthenticated)
 		return
 	}
 
-	user, err := h.svc.GetByID(r.Context(), userID)
+	user, err := h.svc.GetByID(r.Context(), principal.UserID)
 	if err != nil {
 		log.Error("failed to get user", slog.String("error", err.Error()))
-		if errors.Is(err, userErr.ErrInvalidID) {
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, ErrorResponse{Error: err.Error()})
-			return
-		}
-		if errors.Is(err, userErr.ErrUserNotFound) {
-			render.Status(r, http.StatusNotFound)
-			render.JSON(w, r, ErrorResponse{Error: err.Error()})
-			return
-		}
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, ErrorResponse{Error: "failed to get user"})
+		problem.Write(w, r, err)
 		return
 	}
 
-	response := UserResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		Avatar:    user.Avatar,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
-	}
+	response := toUserResponse(user)
 
+	w.Header().Set("ETag", etag(user.Version))
 	render.JSON(w, r, SuccessResponse{Data: response})
 }
 
@@ -144,31 +179,99 @@ func (h *UserHandler) GetByEmail(w http.ResponseWriter, r *http.Request) {
 	user, err := h.svc.GetByEmail(r.Context(), email)
 	if err != nil {
 		log.Error("failed to get user by email", slog.String("error", err.Error()))
-		if errors.Is(err, userErr.ErrInvalidEmail) {
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, ErrorResponse{Error: err.Error()})
-			return
+		problem.Write(w, r, err)
+		return
+	}
+
+	response := toUserResponse(user)
+
+	render.JSON(w, r, SuccessResponse{Data: response})
+}
+
+// List serves GET /users?limit=&cursor=&username_prefix=&email_domain=
+// &created_after=&created_before=&sort=created_at|-created_at.
+func (h *UserHandler) List(w http.ResponseWriter, r *http.Request) {
+	const op = "UserHandler.List"
+	log := h.log.With(slog.String("op", op))
+
+	filter, err := parseListFilter(r)
+	if err != nil {
+		log.Error("failed to parse list filter", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	page, err := h.svc.List(r.Context(), filter)
+	if err != nil {
+		log.Error("failed to list users", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	response := make([]UserResponse, 0, len(page.Users))
+	for _, user := range page.Users {
+		response = append(response, toUserResponse(&user))
+	}
+
+	render.JSON(w, r, SuccessResponse{
+		Data: response,
+		Pagination: &Pagination{
+			Limit:      page.Limit,
+			NextCursor: page.NextCursor,
+			HasMore:    page.HasMore,
+		},
+	})
+}
+
+func parseListFilter(r *http.Request) (model.ListFilter, error) {
+	q := r.URL.Query()
+
+	filter := model.ListFilter{
+		UsernamePrefix: q.Get("username_prefix"),
+		EmailDomain:    q.Get("email_domain"),
+		SortDesc:       true,
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return filter, userErr.ErrInvalidFilter
 		}
-		if errors.Is(err, userErr.ErrUserNotFound) {
-			render.Status(r, http.StatusNotFound)
-			render.JSON(w, r, ErrorResponse{Error: err.Error()})
-			return
+		filter.Limit = limit
+	}
+
+	switch q.Get("sort") {
+	case "", "-created_at":
+	case "created_at":
+		filter.SortDesc = false
+	default:
+		return filter, userErr.ErrInvalidFilter
+	}
+
+	if v := q.Get("created_after"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, userErr.ErrInvalidFilter
 		}
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, ErrorResponse{Error: "failed to get user"})
-		return
+		filter.CreatedAfter = &t
+	}
+	if v := q.Get("created_before"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, userErr.ErrInvalidFilter
+		}
+		filter.CreatedBefore = &t
 	}
 
-	response := UserResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		Avatar:    user.Avatar,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+	if v := q.Get("cursor"); v != "" {
+		cursor, err := service.DecodeCursor(v)
+		if err != nil {
+			return filter, err
+		}
+		filter.Cursor = cursor
 	}
 
-	render.JSON(w, r, SuccessResponse{Data: response})
+	return filter, nil
 }
 
 func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
@@ -177,41 +280,127 @@ func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
 
 	id := chi.URLParam(r, "id")
 
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		log.Error("failed to check precondition", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
 	var req UpdateUserRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		log.Error("failed to decode request", slog.String("error", err.Error()))
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, ErrorResponse{Error: "invalid request body"})
+		problem.Write(w, r, userErr.Wrap(userErr.ErrInvalidRequestBody, err))
 		return
 	}
-
-	user := model.User{
-		ID:        id,
-		Username:  req.Username,
-		Email:     req.Email,
-		Avatar:    req.Avatar,
-		UpdatedAt: time.Now(),
+	if err := validation.Struct(req); err != nil {
+		log.Error("invalid request", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
 	}
 
-	err := h.svc.Update(r.Context(), user)
+	user, err := h.svc.Update(r.Context(), model.User{
+		ID:       id,
+		Username: req.Username,
+		Email:    req.Email,
+		Avatar:   req.Avatar,
+		Version:  version,
+	})
 	if err != nil {
 		log.Error("failed to update user", slog.String("error", err.Error()))
-		if errors.Is(err, userErr.ErrInvalidID) {
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, ErrorResponse{Error: err.Error()})
-			return
+		problem.Write(w, r, err)
+		return
+	}
+
+	response := toUserResponse(user)
+
+	w.Header().Set("ETag", etag(user.Version))
+	render.JSON(w, r, SuccessResponse{Data: response, Message: "user updated successfully"})
+}
+
+const mergePatchContentType = "application/merge-patch+json"
+
+// Patch serves PATCH /users/{id} with a JSON Merge Patch (RFC 7396) body:
+// only the members present are changed, and "avatar": null clears the avatar.
+func (h *UserHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	const op = "UserHandler.Patch"
+	log := h.log.With(slog.String("op", op))
+
+	id := chi.URLParam(r, "id")
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		log.Error("failed to check precondition", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != mergePatchContentType {
+		w.Header().Set("Accept-Patch", mergePatchContentType)
+		problem.Write(w, r, userErr.ErrUnsupportedMediaType)
+		return
+	}
+
+	patch, err := decodeUserPatch(r)
+	if err != nil {
+		log.Error("failed to decode patch", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	user, err := h.svc.Patch(r.Context(), id, version, patch)
+	if err != nil {
+		log.Error("failed to patch user", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	response := toUserResponse(user)
+
+	w.Header().Set("ETag", etag(user.Version))
+	render.JSON(w, r, SuccessResponse{Data: response, Message: "user updated successfully"})
+}
+
+// decodeUserPatch reads a merge patch object, keeping absent members apart
+// from explicit nulls, and validates the members that were sent. A null
+// avatar clears it; a null username or email fails the required rule.
+func decodeUserPatch(r *http.Request) (model.UserPatch, error) {
+	var patch model.UserPatch
+
+	var doc map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&doc); err != nil || doc == nil {
+		return patch, userErr.ErrInvalidPatch
+	}
+
+	var (
+		req     UpdateUserRequest
+		present []string
+	)
+	for name, raw := range doc {
+		var value *string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return patch, userErr.ErrInvalidPatch
 		}
-		if errors.Is(err, userErr.ErrUserNotFound) {
-			render.Status(r, http.StatusNotFound)
-			render.JSON(w, r, ErrorResponse{Error: err.Error()})
-			return
+		if value == nil {
+			value = new(string)
+		}
+
+		switch name {
+		case "username":
+			req.Username, patch.Username = *value, value
+			present = append(present, "Username")
+		case "email":
+			req.Email, patch.Email = *value, value
+			present = append(present, "Email")
+		case "avatar":
+			req.Avatar, patch.Avatar = *value, value
+			present = append(present, "Avatar")
+		default:
+			return patch, userErr.ErrInvalidPatch
 		}
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, ErrorResponse{Error: "failed to update user"})
-		return
 	}
 
-	render.JSON(w, r, SuccessResponse{Message: "user updated successfully"})
+	return patch, validation.Partial(req, present...)
 }
 
 func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
@@ -220,23 +409,65 @@ func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	id := chi.URLParam(r, "id")
 
-	err := h.svc.Delete(r.Context(), id)
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		log.Error("failed to check precondition", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	err = h.svc.Delete(r.Context(), id, version)
 	if err != nil {
 		log.Error("failed to delete user", slog.String("error", err.Error()))
-		if errors.Is(err, userErr.ErrInvalidID) {
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, ErrorResponse{Error: err.Error()})
-			return
-		}
-		if errors.Is(err, userErr.ErrUserNotFound) {
-			render.Status(r, http.StatusNotFound)
-			render.JSON(w, r, ErrorResponse{Error: err.Error()})
-			return
-		}
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, ErrorResponse{Error: "failed to delete user"})
+		problem.Write(w, r, err)
 		return
 	}
 
 	render.JSON(w, r, SuccessResponse{Message: "user deleted successfully"})
 }
+
+// Restore serves POST /users/{id}/restore and undoes a soft delete.
+func (h *UserHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	const op = "UserHandler.Restore"
+	log := h.log.With(slog.String("op", op))
+
+	id := chi.URLParam(r, "id")
+
+	user, err := h.svc.Restore(r.Context(), id)
+	if err != nil {
+		log.Error("failed to restore user", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	response := toUserResponse(user)
+
+	w.Header().Set("ETag", etag(user.Version))
+	render.JSON(w, r, SuccessResponse{Data: response, Message: "user restored successfully"})
+}
+
+// Erase serves POST /users/{id}/erase, the GDPR right-to-erasure request.
+// Personal data is anonymized irreversibly; the id stays valid.
+func (h *UserHandler) Erase(w http.ResponseWriter, r *http.Request) {
+	const op = "UserHandler.Erase"
+	log := h.log.With(slog.String("op", op))
+
+	id := chi.URLParam(r, "id")
+
+	err := h.svc.Erase(r.Context(), id)
+	if err != nil {
+		log.Error("failed to erase user", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	// Erasing an erased user is a no-op, so a client retrying after this
+	// fails gets the images deleted too.
+	if err := h.avatars.Erase(r.Context(), id); err != nil {
+		log.Error("failed to erase avatars", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, SuccessResponse{Message: "user erased successfully"})
+}
@@ -0,0 +1,120 @@
+package http
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	userErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/pkg/validation"
+)
+
+func TestDecodeUserPatch(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name         string
+		body         string
+		wantUsername *string
+		wantEmail    *string
+		wantAvatar   *string
+		wantErr      error
+		wantFields   []string
+	}{
+		{
+			name:         "absent members are left alone",
+			body:         `{"username":"alice"}`,
+			wantUsername: str("alice"),
+		},
+		{
+			name:       "null avatar clears it",
+			body:       `{"avatar":null}`,
+			wantAvatar: str(""),
+		},
+		{
+			name:         "every member",
+			body:         `{"username":"alice","email":"alice@example.com","avatar":"https://cdn.example.com/a.png"}`,
+			wantUsername: str("alice"),
+			wantEmail:    str("alice@example.com"),
+			wantAvatar:   str("https://cdn.example.com/a.png"),
+		},
+		{
+			name: "empty object is a valid no-op",
+			body: `{}`,
+		},
+		{
+			name:       "null username fails the required rule",
+			body:       `{"username":null}`,
+			wantFields: []string{"username"},
+		},
+		{
+			name:       "only sent members are validated",
+			body:       `{"email":"not-an-email","avatar":"ftp://example.com/a.png"}`,
+			wantFields: []string{"email", "avatar"},
+		},
+		{name: "unknown member", body: `{"role":"admin"}`, wantErr: userErr.ErrInvalidPatch},
+		{name: "non-string member", body: `{"username":42}`, wantErr: userErr.ErrInvalidPatch},
+		{name: "not an object", body: `["username"]`, wantErr: userErr.ErrInvalidPatch},
+		{name: "null document", body: `null`, wantErr: userErr.ErrInvalidPatch},
+		{name: "malformed", body: `{"username":`, wantErr: userErr.ErrInvalidPatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("PATCH", "/users/1", strings.NewReader(tt.body))
+
+			patch, err := decodeUserPatch(r)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("decodeUserPatch() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantFields != nil {
+				var verrs validation.Errors
+				if !errors.As(err, &verrs) {
+					t.Fatalf("decodeUserPatch() error = %v, want validation errors", err)
+				}
+				if len(verrs) != len(tt.wantFields) {
+					t.Fatalf("validation errors = %v, want fields %v", verrs, tt.wantFields)
+				}
+				for _, field := range tt.wantFields {
+					if !hasField(verrs, field) {
+						t.Errorf("validation errors = %v, missing %s", verrs, field)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeUserPatch() error = %v", err)
+			}
+
+			assertMember(t, "username", patch.Username, tt.wantUsername)
+			assertMember(t, "email", patch.Email, tt.wantEmail)
+			assertMember(t, "avatar", patch.Avatar, tt.wantAvatar)
+		})
+	}
+}
+
+func hasField(errs validation.Errors, field string) bool {
+	for _, fe := range errs {
+		if fe.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+func assertMember(t *testing.T, name string, got, want *string) {
+	t.Helper()
+
+	switch {
+	case got == nil && want == nil:
+	case got == nil || want == nil:
+		t.Errorf("%s = %v, want %v", name, got, want)
+	case *got != *want:
+		t.Errorf("%s = %q, want %q", name, *got, *want)
+	}
+}
@@ -2,21 +2,29 @@ package http
 
 import (
 	"github.com/go-chi/chi/v5"
-	"github.com/go-market/services/user/internal/derivery/http/middleware"
+	"github.com/go-market/pkg/authz"
 )
 
-func RegisterUserRoutes(r chi.Router, h *UserHandler, secret string) {
+func RegisterUserRoutes(r chi.Router, h *UserHandler, verifier *authz.Verifier, denied authz.DenyList) {
 	r.Route("/users", func(r chi.Router) {
-		r.Use(middleware.AuthMiddleware(secret))
+		r.Get("/verify", h.VerifyEmail)
 
-		r.Get("/me", h.GetMe)
-		r.Get("/{id}", h.GetByID)
-		r.Get("/", h.GetByEmail)
-		r.Put("/{id}", h.Update)
-		
 		r.Group(func(r chi.Router) {
-			r.Use(middleware.RequireRole("admin"))
-			r.Delete("/{id}", h.Delete)
+			r.Use(authz.Authenticate(verifier, authz.WithDenyList(denied)))
+
+			r.Post("/", h.Create)
+			r.Get("/me", h.GetMe)
+			r.Post("/me/verification", h.ResendVerification)
+
+			r.With(authz.RequirePermission(authz.PermUsersRead)).Get("/", h.List)
+			r.With(authz.RequirePermission(authz.PermUsersRead)).Get("/email/{email}", h.GetByEmail)
+			r.With(authz.RequireOwnerOr("id", authz.PermUsersRead)).Get("/{id}", h.GetByID)
+			r.With(authz.RequireOwnerOr("id", authz.PermUsersWrite), authz.RequireMFAUnlessOwner("id")).Put("/{id}", h.Update)
+			r.With(authz.RequireOwnerOr("id", authz.PermUsersWrite), authz.RequireMFAUnlessOwner("id")).Patch("/{id}", h.Patch)
+			r.With(authz.RequireOwnerOr("id", authz.PermUsersWrite), authz.RequireMFAUnlessOwner("id")).Post("/{id}/avatar", h.UploadAvatar)
+			r.With(authz.RequirePermission(authz.PermUsersDelete), authz.RequireMFA()).Delete("/{id}", h.Delete)
+			r.With(authz.RequirePermission(authz.PermUsersDelete), authz.RequireMFA()).Post("/{id}/restore", h.Restore)
+			r.With(authz.RequirePermission(authz.PermUsersDelete), authz.RequireMFA()).Post("/{id}/erase", h.Erase)
 		})
 	})
 }
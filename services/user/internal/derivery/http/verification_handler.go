@@ -0,0 +1,58 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/go-market/pkg/authz"
+	userErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/pkg/problem"
+)
+
+// VerifyEmail serves GET /users/verify?token=, the link sent by email. It
+// is public: the token alone identifies the user.
+func (h *UserHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	const op = "UserHandler.VerifyEmail"
+	log := h.log.With(slog.String("op", op))
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		problem.Write(w, r, userErr.ErrInvalidVerificationToken)
+		return
+	}
+
+	user, err := h.verification.Verify(r.Context(), token)
+	if err != nil {
+		log.Error("failed to verify email", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	response := toUserResponse(user)
+
+	w.Header().Set("ETag", etag(user.Version))
+	render.JSON(w, r, SuccessResponse{Data: response, Message: "email verified"})
+}
+
+// ResendVerification serves POST /users/me/verification.
+func (h *UserHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	const op = "UserHandler.ResendVerification"
+	log := h.log.With(slog.String("op", op))
+
+	principal, ok := authz.FromContext(r.Context())
+	if !ok {
+		log.Error("failed to extract user id from context")
+		problem.Write(w, r, userErr.ErrUnauthenticated)
+		return
+	}
+
+	if err := h.verification.Resend(r.Context(), principal.UserID); err != nil {
+		log.Error("failed to resend verification email", slog.String("error", err.Error()))
+		problem.Write(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusAccepted)
+	render.JSON(w, r, SuccessResponse{Message: "verification email sent"})
+}
@@ -0,0 +1,32 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	domain "github.com/go-market/pkg/domain/model"
+	userErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/pkg/kafka"
+	"github.com/go-market/services/user/internal/service"
+)
+
+// UserRegisteredHandler mails the verification link to newly registered
+// users. A redelivered event sends a new link, which revokes the old one.
+func UserRegisteredHandler(log *slog.Logger, verification *service.Verification) kafka.Handler[domain.UserRegisteredEvent] {
+	return func(ctx context.Context, _ kafka.Message, event domain.UserRegisteredEvent) error {
+		const op = "kafka.UserRegisteredHandler"
+		log := log.With(slog.String("op", op), slog.String("user_id", event.UserID))
+
+		if err := verification.Send(ctx, event.UserID); err != nil {
+			// The user was deleted before the event arrived.
+			if errors.Is(err, userErr.ErrUserNotFound) {
+				return kafka.Permanent(err)
+			}
+			return err
+		}
+
+		log.Info("verification email sent")
+		return nil
+	}
+}
@@ -0,0 +1,14 @@
+package model
+
+// Thumbnail is one stored rendition of an uploaded avatar.
+type Thumbnail struct {
+	Size int
+	URL  string
+}
+
+// AvatarUpload is the outcome of an avatar upload. User.Avatar points at the
+// largest thumbnail.
+type AvatarUpload struct {
+	User       *User
+	Thumbnails []Thumbnail
+}
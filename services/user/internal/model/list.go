@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// ListFilter describes a page request for Repository.List. Results are always
+// ordered by (created_at, id) so the cursor stays stable between pages.
+type ListFilter struct {
+	UsernamePrefix string
+	EmailDomain    string
+	CreatedAfter   *time.Time
+	CreatedBefore  *time.Time
+	SortDesc       bool
+	Limit          int
+	Cursor         *Cursor
+}
+
+type Cursor struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id"`
+}
+
+type UserPage struct {
+	Users      []User
+	Limit      int
+	NextCursor string
+	HasMore    bool
+}
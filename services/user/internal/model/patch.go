@@ -0,0 +1,13 @@
+package model
+
+// UserPatch is a JSON Merge Patch (RFC 7396) against a user. A nil field was
+// absent from the patch and is left untouched; a null avatar clears it.
+type UserPatch struct {
+	Username *string
+	Email    *string
+	Avatar   *string
+}
+
+func (p UserPatch) Empty() bool {
+	return p.Username == nil && p.Email == nil && p.Avatar == nil
+}
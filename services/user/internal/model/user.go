@@ -3,10 +3,12 @@ package model
 import "time"
 
 type User struct {
-	ID        string    `json:"id"`
-	Username  string    `json:"name" validate:"required"`
-	Email     string    `json:"email" validate:"required,email"`
-	Avatar    string    `json:"avatar" validate:"omitempty"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID            string    `json:"id"`
+	Username      string    `json:"name" validate:"required,username"`
+	Email         string    `json:"email" validate:"required,email,max=255"`
+	Avatar        string    `json:"avatar" validate:"omitempty,http_url,max=2048"`
+	EmailVerified bool      `json:"email_verified"`
+	Version       int64     `json:"version"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
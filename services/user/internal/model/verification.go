@@ -0,0 +1,8 @@
+package model
+
+// VerificationToken is what a verification link resolves to. The email is
+// kept so a link stops working once the user changes address.
+type VerificationToken struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+}
@@ -0,0 +1,229 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/go-market/pkg/logger/sl"
+	user "github.com/go-market/services/user/internal/model"
+	userRepo "github.com/go-market/services/user/internal/repository"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	idKeyPrefix    = "user:id:"
+	emailKeyPrefix = "user:email:"
+
+	// loadTimeout bounds a database load shared by concurrent callers,
+	// which runs detached from the request that started it.
+	loadTimeout = 5 * time.Second
+)
+
+// CachedRepo is a read-through Redis cache in front of another Repository.
+// Redis failures are logged and never surface to callers: lookups fall back
+// to the wrapped repository.
+type CachedRepo struct {
+	next  userRepo.Repository
+	rdb   *redis.Client
+	ttl   time.Duration
+	log   *slog.Logger
+	group singleflight.Group
+}
+
+func New(next userRepo.Repository, rdb *redis.Client, ttl time.Duration, log *slog.Logger) *CachedRepo {
+	return &CachedRepo{
+		next: next,
+		rdb:  rdb,
+		ttl:  ttl,
+		log:  log.With(slog.String("component", "cache.CachedRepo")),
+	}
+}
+
+func (c *CachedRepo) Create(ctx context.Context, u user.User, roles []string) (*user.User, error) {
+	return c.next.Create(ctx, u, roles)
+}
+
+func (c *CachedRepo) GetMe(ctx context.Context) (*user.User, error) {
+	return c.next.GetMe(ctx)
+}
+
+func (c *CachedRepo) GetByID(ctx context.Context, id string) (*user.User, error) {
+	return c.get(ctx, idKeyPrefix+id, func(ctx context.Context) (*user.User, error) {
+		return c.next.GetByID(ctx, id)
+	})
+}
+
+func (c *CachedRepo) GetByEmail(ctx context.Context, email string) (*user.User, error) {
+	return c.get(ctx, emailKey(email), func(ctx context.Context) (*user.User, error) {
+		return c.next.GetByEmail(ctx, email)
+	})
+}
+
+func (c *CachedRepo) List(ctx context.Context, filter user.ListFilter) ([]user.User, error) {
+	return c.next.List(ctx, filter)
+}
+
+func (c *CachedRepo) Update(ctx context.Context, u user.User) (*user.User, error) {
+	old, err := c.next.GetByID(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := c.next.Update(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, idKeyPrefix+u.ID, emailKey(old.Email), emailKey(updated.Email))
+	return updated, nil
+}
+
+func (c *CachedRepo) Patch(ctx context.Context, id string, version int64, patch user.UserPatch) (*user.User, error) {
+	old, err := c.next.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := c.next.Patch(ctx, id, version, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, idKeyPrefix+id, emailKey(old.Email), emailKey(u.Email))
+	return u, nil
+}
+
+func (c *CachedRepo) Delete(ctx context.Context, id string, version int64) error {
+	old, err := c.next.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := c.next.Delete(ctx, id, version); err != nil {
+		return err
+	}
+
+	c.invalidate(ctx, idKeyPrefix+id, emailKey(old.Email))
+	return nil
+}
+
+func (c *CachedRepo) Restore(ctx context.Context, id string) (*user.User, error) {
+	u, err := c.next.Restore(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, idKeyPrefix+id, emailKey(u.Email))
+	return u, nil
+}
+
+func (c *CachedRepo) Erase(ctx context.Context, id string) error {
+	// A soft-deleted user is already out of the cache, so a miss here only
+	// means there is no email key left to drop.
+	keys := []string{idKeyPrefix + id}
+	if old, err := c.next.GetByID(ctx, id); err == nil {
+		keys = append(keys, emailKey(old.Email))
+	}
+
+	if err := c.next.Erase(ctx, id); err != nil {
+		return err
+	}
+
+	c.invalidate(ctx, keys...)
+	return nil
+}
+
+func (c *CachedRepo) MarkEmailVerified(ctx context.Context, id, email string) (*user.User, error) {
+	u, err := c.next.MarkEmailVerified(ctx, id, email)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, idKeyPrefix+id, emailKey(u.Email))
+	return u, nil
+}
+
+// get serves key from Redis, otherwise loads it once per key across
+// concurrent callers and populates the cache. The load does not use the
+// context of the caller that started it, so one client going away does not
+// fail the others; each caller still stops waiting when its own context
+// ends.
+func (c *CachedRepo) get(ctx context.Context, key string, load func(context.Context) (*user.User, error)) (*user.User, error) {
+	if u, ok := c.lookup(ctx, key); ok {
+		return u, nil
+	}
+
+	ch := c.group.DoChan(key, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), loadTimeout)
+		defer cancel()
+
+		u, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.store(ctx, u)
+		return u, nil
+	})
+
+	var res singleflight.Result
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res = <-ch:
+	}
+	if res.Err != nil {
+		return nil, res.Err
+	}
+
+	// Hand out a copy so callers sharing a flight cannot affect each other.
+	u := *res.Val.(*user.User)
+	return &u, nil
+}
+
+func (c *CachedRepo) lookup(ctx context.Context, key string) (*user.User, bool) {
+	data, err := c.rdb.Get(ctx, key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			c.log.Warn("cache lookup failed, falling back to database", slog.String("key", key), sl.Err(err))
+		}
+		return nil, false
+	}
+
+	var u user.User
+	if err := json.Unmarshal(data, &u); err != nil {
+		c.log.Warn("failed to decode cached user", slog.String("key", key), sl.Err(err))
+		return nil, false
+	}
+
+	return &u, true
+}
+
+func (c *CachedRepo) store(ctx context.Context, u *user.User) {
+	data, err := json.Marshal(u)
+	if err != nil {
+		c.log.Warn("failed to encode user for cache", sl.Err(err))
+		return
+	}
+
+	pipe := c.rdb.Pipeline()
+	pipe.Set(ctx, idKeyPrefix+u.ID, data, c.ttl)
+	pipe.Set(ctx, emailKey(u.Email), data, c.ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		c.log.Warn("failed to populate cache", slog.String("id", u.ID), sl.Err(err))
+	}
+}
+
+func (c *CachedRepo) invalidate(ctx context.Context, keys ...string) {
+	if err := c.rdb.Del(ctx, keys...).Err(); err != nil {
+		c.log.Warn("failed to invalidate cache", slog.Any("keys", keys), sl.Err(err))
+	}
+}
+
+// emailKey matches addresses exactly, as the users table does.
+func emailKey(email string) string {
+	return emailKeyPrefix + email
+}
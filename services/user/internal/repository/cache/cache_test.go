@@ -0,0 +1,317 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	userErr "github.com/go-market/pkg/errs"
+	user "github.com/go-market/services/user/internal/model"
+	userRepo "github.com/go-market/services/user/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+// memRedis answers GET, SET and DEL from memory instead of a server. It is
+// installed as a hook, so the client never dials.
+type memRedis struct {
+	mu   sync.Mutex
+	data map[string]string
+}
+
+func newMemRedis() *redis.Client {
+	rdb := redis.NewClient(&redis.Options{Addr: "mem"})
+	rdb.AddHook(&memRedis{data: make(map[string]string)})
+	return rdb
+}
+
+func (m *memRedis) DialHook(redis.DialHook) redis.DialHook {
+	return func(context.Context, string, string) (net.Conn, error) {
+		return nil, errors.New("memRedis does not dial")
+	}
+}
+
+func (m *memRedis) ProcessHook(redis.ProcessHook) redis.ProcessHook {
+	return func(_ context.Context, cmd redis.Cmder) error {
+		m.process(cmd)
+		return cmd.Err()
+	}
+}
+
+func (m *memRedis) ProcessPipelineHook(redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(_ context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			m.process(cmd)
+		}
+		return nil
+	}
+}
+
+func (m *memRedis) process(cmd redis.Cmder) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	args := cmd.Args()
+	switch cmd := cmd.(type) {
+	case *redis.StringCmd:
+		if v, ok := m.data[fmt.Sprint(args[1])]; ok {
+			cmd.SetVal(v)
+		} else {
+			cmd.SetErr(redis.Nil)
+		}
+	case *redis.StatusCmd:
+		v := args[2]
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+		m.data[fmt.Sprint(args[1])] = fmt.Sprint(v)
+		cmd.SetVal("OK")
+	case *redis.IntCmd:
+		var n int64
+		for _, key := range args[1:] {
+			if _, ok := m.data[fmt.Sprint(key)]; ok {
+				delete(m.data, fmt.Sprint(key))
+				n++
+			}
+		}
+		cmd.SetVal(n)
+	default:
+		cmd.SetErr(fmt.Errorf("memRedis: unsupported command %v", args))
+	}
+}
+
+// fakeRepo serves users from memory and counts loads. While block is set,
+// GetByID waits for it to close.
+type fakeRepo struct {
+	userRepo.Repository
+
+	mu      sync.Mutex
+	users   map[string]user.User
+	loads   atomic.Int32
+	block   chan struct{}
+	loadErr error
+}
+
+func newFakeRepo(users ...user.User) *fakeRepo {
+	f := &fakeRepo{users: make(map[string]user.User)}
+	for _, u := range users {
+		f.users[u.ID] = u
+	}
+	return f
+}
+
+func (f *fakeRepo) GetByID(ctx context.Context, id string) (*user.User, error) {
+	f.loads.Add(1)
+	if f.block != nil {
+		<-f.block
+		// A load that outlives a cancelled caller must still be usable.
+		if f.loadErr = ctx.Err(); f.loadErr != nil {
+			return nil, f.loadErr
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	u, ok := f.users[id]
+	if !ok {
+		return nil, userErr.ErrUserNotFound
+	}
+	return &u, nil
+}
+
+func (f *fakeRepo) GetByEmail(_ context.Context, email string) (*user.User, error) {
+	f.loads.Add(1)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, u := range f.users {
+		if u.Email == email {
+			return &u, nil
+		}
+	}
+	return nil, userErr.ErrUserNotFound
+}
+
+func (f *fakeRepo) Update(_ context.Context, u user.User) (*user.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	u.Version = f.users[u.ID].Version + 1
+	f.users[u.ID] = u
+	return &u, nil
+}
+
+func (f *fakeRepo) Delete(_ context.Context, id string, _ int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.users, id)
+	return nil
+}
+
+func newCachedRepo(next userRepo.Repository, rdb *redis.Client) *CachedRepo {
+	return New(next, rdb, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+var alice = user.User{ID: "u1", Username: "alice", Email: "alice@example.com", Version: 1}
+
+func TestInvalidation(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepo(alice)
+	c := newCachedRepo(repo, newMemRedis())
+
+	for range 2 {
+		if _, err := c.GetByID(ctx, alice.ID); err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+	}
+	if _, err := c.GetByEmail(ctx, alice.Email); err != nil {
+		t.Fatalf("GetByEmail() error = %v", err)
+	}
+	if got := repo.loads.Load(); got != 1 {
+		t.Fatalf("loads = %d, want the cache to serve repeat lookups", got)
+	}
+
+	changed := alice
+	changed.Email = "alice@example.org"
+	if _, err := c.Update(ctx, changed); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	got, err := c.GetByID(ctx, alice.ID)
+	if err != nil || got.Email != changed.Email {
+		t.Fatalf("GetByID() after Update = %+v, %v, want the new email", got, err)
+	}
+	if _, err := c.GetByEmail(ctx, alice.Email); !errors.Is(err, userErr.ErrUserNotFound) {
+		t.Errorf("GetByEmail(old address) error = %v, want %v", err, userErr.ErrUserNotFound)
+	}
+
+	if err := c.Delete(ctx, alice.ID, got.Version); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := c.GetByID(ctx, alice.ID); !errors.Is(err, userErr.ErrUserNotFound) {
+		t.Errorf("GetByID() after Delete error = %v, want %v", err, userErr.ErrUserNotFound)
+	}
+	if _, err := c.GetByEmail(ctx, changed.Email); !errors.Is(err, userErr.ErrUserNotFound) {
+		t.Errorf("GetByEmail() after Delete error = %v, want %v", err, userErr.ErrUserNotFound)
+	}
+}
+
+func TestEmailKeyMatchesDatabase(t *testing.T) {
+	ctx := context.Background()
+	c := newCachedRepo(newFakeRepo(alice), newMemRedis())
+
+	if _, err := c.GetByEmail(ctx, alice.Email); err != nil {
+		t.Fatalf("GetByEmail() error = %v", err)
+	}
+	if _, err := c.GetByEmail(ctx, "Alice@Example.com"); !errors.Is(err, userErr.ErrUserNotFound) {
+		t.Errorf("GetByEmail(other case) error = %v, want the same answer as the database", err)
+	}
+}
+
+func TestStampede(t *testing.T) {
+	repo := newFakeRepo(alice)
+	repo.block = make(chan struct{})
+	c := newCachedRepo(repo, newMemRedis())
+
+	const callers = 20
+	errs := make(chan error, callers)
+	for range callers {
+		go func() {
+			_, err := c.GetByID(context.Background(), alice.ID)
+			errs <- err
+		}()
+	}
+
+	waitForLoad(t, repo)
+	time.Sleep(20 * time.Millisecond)
+	close(repo.block)
+
+	for range callers {
+		if err := <-errs; err != nil {
+			t.Errorf("GetByID() error = %v", err)
+		}
+	}
+	if got := repo.loads.Load(); got != 1 {
+		t.Errorf("loads = %d, want one load shared by every caller", got)
+	}
+}
+
+func TestLoadOutlivesCancelledCaller(t *testing.T) {
+	repo := newFakeRepo(alice)
+	repo.block = make(chan struct{})
+	c := newCachedRepo(repo, newMemRedis())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := c.GetByID(ctx, alice.ID)
+		first <- err
+	}()
+	waitForLoad(t, repo)
+
+	second := make(chan error, 1)
+	go func() {
+		_, err := c.GetByID(context.Background(), alice.ID)
+		second <- err
+	}()
+
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled caller error = %v, want %v", err, context.Canceled)
+	}
+
+	close(repo.block)
+	if err := <-second; err != nil {
+		t.Errorf("other caller error = %v, want the shared load to succeed", err)
+	}
+	if repo.loadErr != nil {
+		t.Errorf("load context ended with the caller: %v", repo.loadErr)
+	}
+}
+
+func TestRedisDown(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepo(alice)
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 100 * time.Millisecond, MaxRetries: -1})
+	t.Cleanup(func() { rdb.Close() })
+	c := newCachedRepo(repo, rdb)
+
+	for range 2 {
+		got, err := c.GetByID(ctx, alice.ID)
+		if err != nil || got.ID != alice.ID {
+			t.Fatalf("GetByID() = %+v, %v, want the user from the database", got, err)
+		}
+	}
+	if _, err := c.GetByEmail(ctx, alice.Email); err != nil {
+		t.Fatalf("GetByEmail() error = %v", err)
+	}
+	if got := repo.loads.Load(); got != 3 {
+		t.Errorf("loads = %d, want every lookup served by the database", got)
+	}
+
+	changed := alice
+	changed.Username = "alice2"
+	if _, err := c.Update(ctx, changed); err != nil {
+		t.Errorf("Update() error = %v, want cache failures to stay internal", err)
+	}
+	if err := c.Delete(ctx, alice.ID, 2); err != nil {
+		t.Errorf("Delete() error = %v, want cache failures to stay internal", err)
+	}
+}
+
+func waitForLoad(t *testing.T, repo *fakeRepo) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for repo.loads.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the load")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -5,14 +5,30 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
+	"time"
 
+	"github.com/go-market/pkg/authz"
+	domain "github.com/go-market/pkg/domain/model"
 	userErr "github.com/go-market/pkg/errs"
-	"github.com/go-market/services/user/internal/derivery/http/middleware"
+	"github.com/go-market/pkg/migrate"
+	"github.com/go-market/pkg/outbox"
 	user "github.com/go-market/services/user/internal/model"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+const (
+	uniqueViolation = "23505"
+)
+
+// schema lists every column the queries below rely on.
+var schema = map[string][]string{
+	"users":  {"id", "username", "email", "avatar", "email_verified", "version", "created_at", "updated_at", "deleted_at", "erased_at"},
+	"outbox": {"id", "topic", "message_key", "payload", "attempts", "last_error", "next_attempt_at", "published_at", "locked_until"},
+}
+
 type PostgresRepo struct {
 	db *pgxpool.Pool
 }
@@ -27,16 +43,64 @@ func New(db_url string) (*PostgresRepo, error) {
 	return &PostgresRepo{db: db}, nil
 }
 
+// Pool exposes the connection pool for components that share the database,
+// such as the outbox relay.
+func (r *PostgresRepo) Pool() *pgxpool.Pool {
+	return r.db
+}
+
+// Create inserts the user and its UserRegisteredEvent in one transaction.
+func (r *PostgresRepo) Create(ctx context.Context, usr user.User, roles []string) (*user.User, error) {
+	const op = "repo.Create"
+	slog.With("op", op)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	u := &user.User{}
+	query := `INSERT INTO users (id, username, email, avatar) VALUES ($1, $2, $3, $4)
+		RETURNING id, username, email, avatar, email_verified, version, created_at, updated_at`
+	err = tx.QueryRow(ctx, query, usr.ID, usr.Username, usr.Email, usr.Avatar).
+		Scan(&u.ID, &u.Username, &u.Email, &u.Avatar, &u.EmailVerified, &u.Version, &u.CreatedAt, &u.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return nil, userErr.ErrUserExists
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	event := domain.UserRegisteredEvent{
+		UserID:       u.ID,
+		Name:         u.Username,
+		Email:        u.Email,
+		Roles:        roles,
+		RegisteredAt: u.CreatedAt,
+	}
+	if err := outbox.Enqueue(ctx, tx, domain.TopicUserRegistered, u.ID, event); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return u, nil
+}
+
 func (r *PostgresRepo) GetMe(ctx context.Context) (*user.User, error) {
 	const op = "repo.GetMe"
 	slog.With("op", op)
 
-	userID, ok := ctx.Value(middleware.UserIDKey).(string)
-	if !ok || userID == "" {
+	principal, ok := authz.FromContext(ctx)
+	if !ok {
 		return nil, userErr.ErrInvalidID
 	}
 
-	return r.GetByID(ctx, userID)
+	return r.GetByID(ctx, principal.UserID)
 }
 
 func (r *PostgresRepo) GetByID(ctx context.Context, id string) (*user.User, error) {
@@ -44,8 +108,8 @@ func (r *PostgresRepo) GetByID(ctx context.Context, id string) (*user.User, erro
 	slog.With("op", op)
 
 	u := &user.User{}
-	query := `SELECT id, name, email, avatar, created_at, updated_at FROM users WHERE id = $1`
-	err := r.db.QueryRow(ctx, query, id).Scan(&u.ID, &u.Username, &u.Email, &u.Avatar, &u.CreatedAt, &u.UpdatedAt)
+	query := `SELECT id, username, email, avatar, email_verified, version, created_at, updated_at FROM users WHERE id = $1 AND deleted_at IS NULL`
+	err := r.db.QueryRow(ctx, query, id).Scan(&u.ID, &u.Username, &u.Email, &u.Avatar, &u.EmailVerified, &u.Version, &u.CreatedAt, &u.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, userErr.ErrUserNotFound
@@ -61,49 +125,315 @@ func (r *PostgresRepo) GetByEmail(ctx context.Context, email string) (*user.User
 	slog.With("op", op)
 
 	u := &user.User{}
-	query := `SELECT id, name, email, avatar, created_at, updated_at FROM users WHERE email = $1`
-	err := r.db.QueryRow(ctx, query, email).Scan(&u.ID, &u.Username, &u.Email, &u.Avatar, &u.CreatedAt, &u.UpdatedAt)
+	query := `SELECT id, username, email, avatar, email_verified, version, created_at, updated_at FROM users WHERE email = $1 AND deleted_at IS NULL`
+	err := r.db.QueryRow(ctx, query, email).Scan(&u.ID, &u.Username, &u.Email, &u.Avatar, &u.EmailVerified, &u.Version, &u.CreatedAt, &u.UpdatedAt)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, userErr.ErrUserNotFound
+		}
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
 	return u, nil
 }
 
-func (r *PostgresRepo) Update(ctx context.Context, user user.User) error {
+// List returns at most filter.Limit users using keyset pagination on
+// (created_at, id).
+func (r *PostgresRepo) List(ctx context.Context, filter user.ListFilter) ([]user.User, error) {
+	const op = "repo.List"
+	slog.With("op", op)
+
+	var (
+		conds = []string{"deleted_at IS NULL"}
+		args  []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.UsernamePrefix != "" {
+		conds = append(conds, "username LIKE "+arg(escapeLike(filter.UsernamePrefix)+"%"))
+	}
+	if filter.EmailDomain != "" {
+		conds = append(conds, "lower(email) LIKE "+arg("%@"+escapeLike(strings.ToLower(filter.EmailDomain))))
+	}
+	if filter.CreatedAfter != nil {
+		conds = append(conds, "created_at >= "+arg(*filter.CreatedAfter))
+	}
+	if filter.CreatedBefore != nil {
+		conds = append(conds, "created_at < "+arg(*filter.CreatedBefore))
+	}
+
+	order, cmp := "ASC", ">"
+	if filter.SortDesc {
+		order, cmp = "DESC", "<"
+	}
+	if filter.Cursor != nil {
+		conds = append(conds, fmt.Sprintf("(created_at, id) %s (%s, %s)",
+			cmp, arg(filter.Cursor.CreatedAt), arg(filter.Cursor.ID)))
+	}
+
+	query := `SELECT id, username, email, avatar, email_verified, version, created_at, updated_at FROM users WHERE ` + strings.Join(conds, " AND ")
+	query += fmt.Sprintf(" ORDER BY created_at %s, id %s LIMIT %s", order, order, arg(filter.Limit))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	users := make([]user.User, 0, filter.Limit)
+	for rows.Next() {
+		var u user.User
+		if err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.Avatar, &u.EmailVerified, &u.Version, &u.CreatedAt, &u.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return users, nil
+}
+
+// Update replaces the user's profile when it is still at u.Version, which
+// callers take from the If-Match header. A zero version skips the check.
+func (r *PostgresRepo) Update(ctx context.Context, usr user.User) (*user.User, error) {
 	const op = "repo.Update"
 	slog.With("op", op)
 
-	query := `UPDATE users SET name = $1, email = $2, avatar = $3 WHERE id = $4`
-	result, err := r.db.Exec(ctx, query, user.Username, user.Email, user.Avatar, user.ID)
+	u := &user.User{}
+	query := `UPDATE users
+		SET username = $1, email = $2, avatar = $3,
+		    email_verified = email_verified AND email = $2,
+		    version = version + 1, updated_at = NOW()
+		WHERE id = $4 AND deleted_at IS NULL AND ($5::bigint = 0 OR version = $5)
+		RETURNING id, username, email, avatar, email_verified, version, created_at, updated_at`
+	err := r.db.QueryRow(ctx, query, usr.Username, usr.Email, usr.Avatar, usr.ID, usr.Version).
+		Scan(&u.ID, &u.Username, &u.Email, &u.Avatar, &u.EmailVerified, &u.Version, &u.CreatedAt, &u.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, r.missOrConflict(ctx, op, usr.ID)
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return nil, userErr.ErrUserExists
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return u, nil
+}
+
+// Patch updates only the columns present in patch. An empty patch just
+// returns the current user.
+func (r *PostgresRepo) Patch(ctx context.Context, id string, version int64, patch user.UserPatch) (*user.User, error) {
+	const op = "repo.Patch"
+	slog.With("op", op)
+
+	if patch.Empty() {
+		u, err := r.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if version != 0 && u.Version != version {
+			return nil, userErr.ErrVersionConflict
+		}
+		return u, nil
+	}
+
+	var (
+		sets []string
+		args []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if patch.Username != nil {
+		sets = append(sets, "username = "+arg(*patch.Username))
+	}
+	if patch.Email != nil {
+		// A new address has to be verified again.
+		p := arg(*patch.Email)
+		sets = append(sets, "email = "+p, "email_verified = email_verified AND email = "+p)
+	}
+	if patch.Avatar != nil {
+		sets = append(sets, "avatar = "+arg(*patch.Avatar))
+	}
+	sets = append(sets, "version = version + 1", "updated_at = NOW()")
+
+	query := `UPDATE users SET ` + strings.Join(sets, ", ") +
+		` WHERE id = ` + arg(id) + ` AND deleted_at IS NULL`
+	if version != 0 {
+		query += ` AND version = ` + arg(version)
+	}
+	query += ` RETURNING id, username, email, avatar, email_verified, version, created_at, updated_at`
+
+	u := &user.User{}
+	err := r.db.QueryRow(ctx, query, args...).Scan(&u.ID, &u.Username, &u.Email, &u.Avatar, &u.EmailVerified, &u.Version, &u.CreatedAt, &u.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, r.missOrConflict(ctx, op, id)
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return nil, userErr.ErrUserExists
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return u, nil
+}
+
+// MarkEmailVerified flags the user's email as verified, provided it is
+// still the address the verification token was issued for.
+func (r *PostgresRepo) MarkEmailVerified(ctx context.Context, id, email string) (*user.User, error) {
+	const op = "repo.MarkEmailVerified"
+	slog.With("op", op)
+
+	u := &user.User{}
+	query := `UPDATE users
+		SET email_verified = TRUE,
+		    version = CASE WHEN email_verified THEN version ELSE version + 1 END,
+		    updated_at = CASE WHEN email_verified THEN updated_at ELSE NOW() END
+		WHERE id = $1 AND email = $2 AND deleted_at IS NULL
+		RETURNING id, username, email, avatar, email_verified, version, created_at, updated_at`
+	err := r.db.QueryRow(ctx, query, id, email).
+		Scan(&u.ID, &u.Username, &u.Email, &u.Avatar, &u.EmailVerified, &u.Version, &u.CreatedAt, &u.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, userErr.ErrInvalidVerificationToken
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return u, nil
+}
+
+// Delete soft-deletes the user: the row stays so an admin can restore it,
+// but every read above skips it. A non-zero version must match.
+func (r *PostgresRepo) Delete(ctx context.Context, id string, version int64) error {
+	const op = "repo.Delete"
+	slog.With("op", op)
+
+	query := `UPDATE users SET deleted_at = NOW(), version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL AND ($2::bigint = 0 OR version = $2)`
+	result, err := r.db.Exec(ctx, query, id, version)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("%s: user not found", op)
+		return r.missOrConflict(ctx, op, id)
 	}
 
 	return nil
 }
 
-func (r *PostgresRepo) Delete(ctx context.Context, id string) error {
-	const op = "repo.Delete"
+// missOrConflict explains why a versioned write matched no row: either the
+// user is gone or someone else changed it first.
+func (r *PostgresRepo) missOrConflict(ctx context.Context, op, id string) error {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)`
+	if err := r.db.QueryRow(ctx, query, id).Scan(&exists); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !exists {
+		return userErr.ErrUserNotFound
+	}
+	return userErr.ErrVersionConflict
+}
+
+// Restore brings back a soft-deleted user. Restoring an active user is a
+// no-op; erased users are gone for good and report ErrUserNotFound.
+func (r *PostgresRepo) Restore(ctx context.Context, id string) (*user.User, error) {
+	const op = "repo.Restore"
+	slog.With("op", op)
+
+	u := &user.User{}
+	query := `UPDATE users
+		SET deleted_at = NULL,
+		    version = CASE WHEN deleted_at IS NULL THEN version ELSE version + 1 END,
+		    updated_at = CASE WHEN deleted_at IS NULL THEN updated_at ELSE NOW() END
+		WHERE id = $1 AND erased_at IS NULL
+		RETURNING id, username, email, avatar, email_verified, version, created_at, updated_at`
+	err := r.db.QueryRow(ctx, query, id).Scan(&u.ID, &u.Username, &u.Email, &u.Avatar, &u.EmailVerified, &u.Version, &u.CreatedAt, &u.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, userErr.ErrUserNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return u, nil
+}
+
+// Erase anonymizes the user's personal data for a GDPR erasure request and
+// enqueues a UserErasedEvent in the same transaction. The row and its id
+// are kept so references from other services stay valid. Erasing an
+// already erased user is a no-op.
+func (r *PostgresRepo) Erase(ctx context.Context, id string) error {
+	const op = "repo.Erase"
 	slog.With("op", op)
 
-	query := `DELETE FROM users WHERE id = $1`
-	result, err := r.db.Exec(ctx, query, id)
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
+	defer tx.Rollback(ctx)
 
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("%s: user not found", op)
+	var erasedAt *time.Time
+	err = tx.QueryRow(ctx, `SELECT erased_at FROM users WHERE id = $1 FOR UPDATE`, id).Scan(&erasedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return userErr.ErrUserNotFound
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if erasedAt != nil {
+		return nil
+	}
+
+	var event domain.UserErasedEvent
+	query := `UPDATE users
+		SET username = 'erased-' || id::text,
+		    email = 'erased+' || id::text || '@invalid',
+		    avatar = '',
+		    deleted_at = COALESCE(deleted_at, NOW()),
+		    erased_at = NOW(),
+		    version = version + 1,
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING id, erased_at`
+	if err := tx.QueryRow(ctx, query, id).Scan(&event.UserID, &event.ErasedAt); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := outbox.Enqueue(ctx, tx, domain.TopicUserErased, event.UserID, event); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
 
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// CheckSchema fails when the database is missing a column used by the
+// repository queries.
+func (r *PostgresRepo) CheckSchema(ctx context.Context) error {
+	return migrate.VerifyColumns(ctx, r.db, schema)
+}
+
 func (r *PostgresRepo) Close() {
 	r.db.Close()
 }
@@ -0,0 +1,122 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	userErr "github.com/go-market/pkg/errs"
+	user "github.com/go-market/services/user/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	tokenKeyPrefix  = "email_verify:token:"
+	latestKeyPrefix = "email_verify:user:"
+	resendKeyPrefix = "email_verify:resend:"
+
+	// maxSaveAttempts bounds how often SaveToken retries when concurrent
+	// sends for the same user keep changing the latest token.
+	maxSaveAttempts = 10
+)
+
+// VerificationStore keeps verification tokens as JSON strings that expire on
+// their own. A per-user pointer to the latest token lets a new token revoke
+// the previous one.
+type VerificationStore struct {
+	rdb *redis.Client
+}
+
+func NewVerificationStore(rdb *redis.Client) *VerificationStore {
+	return &VerificationStore{rdb: rdb}
+}
+
+func (s *VerificationStore) SaveToken(ctx context.Context, nonce string, t user.VerificationToken, ttl time.Duration) error {
+	const op = "redis.SaveToken"
+
+	data, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	latestKey := latestKeyPrefix + t.UserID
+
+	// WATCH makes the read of the previous nonce and the swap one step:
+	// when two sends race, the loser retries and revokes the winner's
+	// token instead of leaving both links valid.
+	txf := func(tx *redis.Tx) error {
+		previous, err := tx.Get(ctx, latestKey).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if previous != "" {
+				pipe.Del(ctx, tokenKeyPrefix+previous)
+			}
+			pipe.Set(ctx, tokenKeyPrefix+nonce, data, ttl)
+			pipe.Set(ctx, latestKey, nonce, ttl)
+			return nil
+		})
+		return err
+	}
+
+	for range maxSaveAttempts {
+		err = s.rdb.Watch(ctx, txf, latestKey)
+		if !errors.Is(err, redis.TxFailedErr) {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *VerificationStore) ConsumeToken(ctx context.Context, nonce string) (*user.VerificationToken, error) {
+	const op = "redis.ConsumeToken"
+
+	data, err := s.rdb.GetDel(ctx, tokenKeyPrefix+nonce).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, userErr.ErrInvalidVerificationToken
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var t user.VerificationToken
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &t, nil
+}
+
+// AllowResend is a fixed-window counter: the first resend in a window starts
+// its expiry.
+func (s *VerificationStore) AllowResend(ctx context.Context, userID string, limit int, window time.Duration) (bool, time.Duration, error) {
+	const op = "redis.AllowResend"
+
+	key := resendKeyPrefix + userID
+	var (
+		count *redis.IntCmd
+		ttl   *redis.DurationCmd
+	)
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		count = pipe.Incr(ctx, key)
+		pipe.ExpireNX(ctx, key, window)
+		ttl = pipe.PTTL(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return false, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if count.Val() > int64(limit) {
+		return false, ttl.Val(), nil
+	}
+	return true, 0, nil
+}
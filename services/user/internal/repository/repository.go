@@ -2,14 +2,35 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	user "github.com/go-market/services/user/internal/model"
 )
 
 type Repository interface {
+	// Create stores the profile. roles are the account's roles, which are
+	// announced with the registration event.
+	Create(ctx context.Context, user user.User, roles []string) (*user.User, error)
 	GetMe(ctx context.Context) (*user.User, error)
 	GetByID(ctx context.Context, id string) (*user.User, error)
 	GetByEmail(ctx context.Context, email string) (*user.User, error)
-	Update(ctx context.Context, user user.User) error
-	Delete(ctx context.Context, id string) error
+	List(ctx context.Context, filter user.ListFilter) ([]user.User, error)
+	Update(ctx context.Context, user user.User) (*user.User, error)
+	Patch(ctx context.Context, id string, version int64, patch user.UserPatch) (*user.User, error)
+	Delete(ctx context.Context, id string, version int64) error
+	Restore(ctx context.Context, id string) (*user.User, error)
+	Erase(ctx context.Context, id string) error
+	MarkEmailVerified(ctx context.Context, id, email string) (*user.User, error)
+}
+
+// VerificationStore keeps short-lived email verification state.
+type VerificationStore interface {
+	// SaveToken stores t under nonce and revokes the user's previous token.
+	SaveToken(ctx context.Context, nonce string, t user.VerificationToken, ttl time.Duration) error
+	// ConsumeToken returns and deletes the token, so each link works once.
+	ConsumeToken(ctx context.Context, nonce string) (*user.VerificationToken, error)
+	// AllowResend counts a resend for userID. When the limit for the
+	// current window is used up it returns false and the time until the
+	// window ends.
+	AllowResend(ctx context.Context, userID string, limit int, window time.Duration) (bool, time.Duration, error)
 }
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	userErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/services/user/internal/blob"
+	user "github.com/go-market/services/user/internal/model"
+	userRepo "github.com/go-market/services/user/internal/repository"
+	"github.com/google/uuid"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+type AvatarOptions struct {
+	MaxBytes     int64
+	MinDimension int
+	MaxDimension int
+	// Sizes are the square thumbnail edges in pixels. The largest becomes
+	// the user's avatar URL.
+	Sizes []int
+}
+
+// Avatars turns uploaded images into square thumbnails, stores them and
+// points the user's avatar at the largest one.
+type Avatars struct {
+	repo  userRepo.Repository
+	store blob.Store
+	opts  AvatarOptions
+}
+
+func NewAvatars(repo userRepo.Repository, store blob.Store, opts AvatarOptions) *Avatars {
+	opts.Sizes = slices.Clone(opts.Sizes)
+	if len(opts.Sizes) == 0 {
+		opts.Sizes = []int{defaultAvatarSize}
+	}
+	slices.SortFunc(opts.Sizes, func(a, b int) int { return b - a })
+
+	return &Avatars{
+		repo:  repo,
+		store: store,
+		opts:  opts,
+	}
+}
+
+// MaxBytes is the largest image Upload accepts.
+func (a *Avatars) MaxBytes() int64 {
+	return a.opts.MaxBytes
+}
+
+const defaultAvatarSize = 256
+
+var avatarFormats = map[string]string{
+	"image/jpeg": "jpeg",
+	"image/png":  "png",
+	"image/webp": "webp",
+}
+
+// Upload replaces the user's avatar. The change is conditional on version,
+// or on the version read before processing when version is 0, so a
+// concurrent write to the user is never overwritten.
+func (a *Avatars) Upload(ctx context.Context, userID string, version int64, r io.Reader) (*user.AvatarUpload, error) {
+	if userID == "" {
+		return nil, userErr.ErrInvalidID
+	}
+	current, err := a.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		version = current.Version
+	} else if version != current.Version {
+		return nil, userErr.ErrVersionConflict
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, a.opts.MaxBytes+1))
+	if err != nil {
+		return nil, userErr.Wrap(userErr.ErrInvalidRequestBody, err)
+	}
+	if int64(len(data)) > a.opts.MaxBytes {
+		return nil, userErr.ErrAvatarTooLarge
+	}
+
+	// Trust the bytes, not the client's Content-Type.
+	format, ok := avatarFormats[http.DetectContentType(data)]
+	if !ok {
+		return nil, userErr.ErrUnsupportedImage
+	}
+
+	// Check dimensions from the header before decoding, so a small file
+	// cannot expand into a huge bitmap.
+	cfg, decoded, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || decoded != format {
+		return nil, userErr.ErrInvalidImage
+	}
+	if cfg.Width < a.opts.MinDimension || cfg.Height < a.opts.MinDimension ||
+		cfg.Width > a.opts.MaxDimension || cfg.Height > a.opts.MaxDimension {
+		return nil, userErr.ErrInvalidImageDimensions
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, userErr.Wrap(userErr.ErrInvalidImage, err)
+	}
+
+	// Every upload gets fresh keys, so cached copies of the previous avatar
+	// can never be served under the new URL.
+	uploadID := uuid.NewString()
+	var (
+		keys       []string
+		thumbnails = make([]user.Thumbnail, 0, len(a.opts.Sizes))
+	)
+	for _, size := range a.opts.Sizes {
+		body, contentType, ext, err := thumbnail(img, size, format)
+		if err != nil {
+			a.discard(keys)
+			return nil, fmt.Errorf("Avatars.Upload: %w", err)
+		}
+
+		key := fmt.Sprintf("%s/%s/%d.%s", userID, uploadID, size, ext)
+		url, err := a.store.Put(ctx, key, bytes.NewReader(body), int64(len(body)), contentType)
+		if err != nil {
+			a.discard(keys)
+			return nil, fmt.Errorf("Avatars.Upload: %w", err)
+		}
+		keys = append(keys, key)
+		thumbnails = append(thumbnails, user.Thumbnail{Size: size, URL: url})
+	}
+
+	u, err := a.repo.Patch(ctx, userID, version, user.UserPatch{Avatar: &thumbnails[0].URL})
+	if err != nil {
+		a.discard(keys)
+		return nil, err
+	}
+
+	// The previous upload is no longer referenced by anyone.
+	if prefix, ok := uploadPrefix(userID, current.Avatar); ok {
+		a.discardPrefix(prefix)
+	}
+
+	return &user.AvatarUpload{User: u, Thumbnails: thumbnails}, nil
+}
+
+// discard deletes what a failed upload already stored. It runs detached
+// from the request, which may be the reason the upload failed.
+func (a *Avatars) discard(keys []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, key := range keys {
+		_ = a.store.Delete(ctx, key)
+	}
+}
+
+// Erase deletes every avatar the user ever uploaded.
+func (a *Avatars) Erase(ctx context.Context, userID string) error {
+	if userID == "" {
+		return userErr.ErrInvalidID
+	}
+	if err := a.store.DeletePrefix(ctx, userID+"/"); err != nil {
+		return fmt.Errorf("Avatars.Erase: %w", err)
+	}
+	return nil
+}
+
+// discardPrefix is discard for a whole upload.
+func (a *Avatars) discardPrefix(prefix string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_ = a.store.DeletePrefix(ctx, prefix)
+}
+
+// uploadPrefix finds the "<user id>/<upload id>/" key prefix in an avatar
+// URL issued by Upload. Avatars set to URLs elsewhere do not match.
+func uploadPrefix(userID, avatarURL string) (string, bool) {
+	_, rest, ok := strings.Cut(avatarURL, "/"+userID+"/")
+	if !ok {
+		return "", false
+	}
+	uploadID, _, ok := strings.Cut(rest, "/")
+	if !ok || uuid.Validate(uploadID) != nil {
+		return "", false
+	}
+	return userID + "/" + uploadID + "/", true
+}
+
+// thumbnail center-crops img to a square and scales it to size, never
+// upscaling. Formats that may carry transparency are kept as PNG.
+func thumbnail(img image.Image, size int, format string) ([]byte, string, string, error) {
+	b := img.Bounds()
+	edge := min(b.Dx(), b.Dy())
+	crop := image.Rect(0, 0, edge, edge).Add(image.Pt(
+		b.Min.X+(b.Dx()-edge)/2,
+		b.Min.Y+(b.Dy()-edge)/2,
+	))
+
+	size = min(size, edge)
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, crop, draw.Src, nil)
+
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, "", "", err
+		}
+		return buf.Bytes(), "image/jpeg", "jpg", nil
+	}
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, "", "", err
+	}
+	return buf.Bytes(), "image/png", "png", nil
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	userErr "github.com/go-market/pkg/errs"
+	user "github.com/go-market/services/user/internal/model"
+)
+
+func encodeCursor(u user.User) (string, error) {
+	b, err := json.Marshal(user.Cursor{CreatedAt: u.CreatedAt, ID: u.ID})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func DecodeCursor(s string) (*user.Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, userErr.ErrInvalidCursor
+	}
+
+	var c user.Cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == "" || c.CreatedAt.IsZero() {
+		return nil, userErr.ErrInvalidCursor
+	}
+
+	return &c, nil
+}
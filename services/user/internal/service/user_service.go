@@ -3,8 +3,10 @@ package service
 import (
 	"context"
 	"errors"
+	"strings"
 
 	userErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/pkg/validation"
 	user "github.com/go-market/services/user/internal/model"
 	userRepo "github.com/go-market/services/user/internal/repository"
 )
@@ -19,13 +21,65 @@ func New(repo userRepo.Repository) *Service {
 	}
 }
 
+// Create stores the profile of the account u.ID, whose roles come from its
+// credentials in the auth service.
+func (s *Service) Create(ctx context.Context, u user.User, roles []string) (*user.User, error) {
+	if u.ID == "" {
+		return nil, userErr.ErrInvalidID
+	}
+	if err := normalize(&u); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Create(ctx, u, roles)
+}
+
+// normalize trims and validates the fields every stored user must have.
+func normalize(u *user.User) error {
+	var err error
+	if u.Username, err = normalizeUsername(u.Username); err != nil {
+		return err
+	}
+	if u.Email, err = normalizeEmail(u.Email); err != nil {
+		return err
+	}
+	if u.Avatar, err = normalizeAvatar(u.Avatar); err != nil {
+		return err
+	}
+	return nil
+}
+
+func normalizeUsername(username string) (string, error) {
+	username = strings.TrimSpace(username)
+	if !validation.Var(username, validation.UsernameRule) {
+		return "", userErr.ErrInvalidUsername
+	}
+	return username, nil
+}
+
+func normalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if !validation.Var(email, validation.EmailRule) {
+		return "", userErr.ErrInvalidEmail
+	}
+	return email, nil
+}
+
+func normalizeAvatar(avatar string) (string, error) {
+	avatar = strings.TrimSpace(avatar)
+	if !validation.Var(avatar, validation.AvatarURLRule) {
+		return "", userErr.ErrInvalidAvatar
+	}
+	return avatar, nil
+}
+
 func (s *Service) GetMe(ctx context.Context) (*user.User, error) {
 	user, err := s.repo.GetMe(ctx)
 	if err != nil {
 		if errors.Is(err, userErr.ErrUserNotFound) {
 			return nil, err
 		}
-		return nil, userErr.ErrFailedToGetUser
+		return nil, userErr.Wrap(userErr.ErrFailedToGetUser, err)
 	}
 
 	return user, err
@@ -40,7 +94,7 @@ func (s *Service) GetByID(ctx context.Context, id string) (*user.User, error) {
 		if errors.Is(err, userErr.ErrUserNotFound) {
 			return nil, err
 		}
-		return nil, userErr.ErrFailedToGetUser
+		return nil, userErr.Wrap(userErr.ErrFailedToGetUser, err)
 	}
 
 	return user, err
@@ -55,44 +109,116 @@ func (s *Service) GetByEmail(ctx context.Context, email string) (*user.User, err
 		if errors.Is(err, userErr.ErrUserNotFound) {
 			return nil, err
 		}
-		return nil, userErr.ErrFailedToGetUser
+		return nil, userErr.Wrap(userErr.ErrFailedToGetUser, err)
 	}
 
 	return user, err
 }
 
-func (s *Service) Update(ctx context.Context, user user.User) error {
-	if user.ID == "" {
-		return userErr.ErrInvalidID
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+func (s *Service) List(ctx context.Context, filter user.ListFilter) (*user.UserPage, error) {
+	if filter.Limit == 0 {
+		filter.Limit = defaultPageSize
 	}
-	existingUser, err := s.repo.GetByID(ctx, user.ID)
+	if filter.Limit < 0 || filter.Limit > maxPageSize {
+		return nil, userErr.ErrInvalidFilter
+	}
+	if filter.CreatedAfter != nil && filter.CreatedBefore != nil && !filter.CreatedAfter.Before(*filter.CreatedBefore) {
+		return nil, userErr.ErrInvalidFilter
+	}
+
+	// Fetch one extra row to find out whether another page exists.
+	pageSize := filter.Limit
+	filter.Limit++
+
+	users, err := s.repo.List(ctx, filter)
 	if err != nil {
-		if errors.Is(err, userErr.ErrUserNotFound) {
-			return err
+		return nil, err
+	}
+
+	page := &user.UserPage{Users: users, Limit: pageSize}
+	if len(users) > pageSize {
+		page.Users = users[:pageSize]
+		page.HasMore = true
+
+		page.NextCursor, err = encodeCursor(page.Users[pageSize-1])
+		if err != nil {
+			return nil, err
 		}
-		return err
 	}
-	if existingUser == nil {
-		return userErr.ErrUserNotFound
+
+	return page, nil
+}
+
+// Update replaces the user's profile as a whole, so username and email are
+// required and an empty avatar clears it. Use Patch for partial changes.
+// user.Version is the version the caller last saw; a stale one fails with
+// ErrVersionConflict.
+func (s *Service) Update(ctx context.Context, user user.User) (*user.User, error) {
+	if user.ID == "" {
+		return nil, userErr.ErrInvalidID
+	}
+	if err := normalize(&user); err != nil {
+		return nil, err
 	}
 
 	return s.repo.Update(ctx, user)
 }
 
-func (s *Service) Delete(ctx context.Context, id string) error {
+// Patch applies only the fields present in patch and returns the result.
+func (s *Service) Patch(ctx context.Context, id string, version int64, patch user.UserPatch) (*user.User, error) {
+	if id == "" {
+		return nil, userErr.ErrInvalidID
+	}
+	if patch.Username != nil {
+		username, err := normalizeUsername(*patch.Username)
+		if err != nil {
+			return nil, err
+		}
+		patch.Username = &username
+	}
+	if patch.Email != nil {
+		email, err := normalizeEmail(*patch.Email)
+		if err != nil {
+			return nil, err
+		}
+		patch.Email = &email
+	}
+	if patch.Avatar != nil {
+		avatar, err := normalizeAvatar(*patch.Avatar)
+		if err != nil {
+			return nil, err
+		}
+		patch.Avatar = &avatar
+	}
+
+	return s.repo.Patch(ctx, id, version, patch)
+}
+
+func (s *Service) Delete(ctx context.Context, id string, version int64) error {
 	if id == "" {
 		return userErr.ErrInvalidID
 	}
 
-	existingUser, err := s.repo.GetByID(ctx, id)
-	if err != nil {
-		if errors.Is(err, userErr.ErrUserNotFound) {
-			return err
-		}
+	return s.repo.Delete(ctx, id, version)
+}
+
+func (s *Service) Restore(ctx context.Context, id string) (*user.User, error) {
+	if id == "" {
+		return nil, userErr.ErrInvalidID
 	}
-	if existingUser == nil {
-		return userErr.ErrUserNotFound
+
+	return s.repo.Restore(ctx, id)
+}
+
+func (s *Service) Erase(ctx context.Context, id string) error {
+	if id == "" {
+		return userErr.ErrInvalidID
 	}
 
-	return s.repo.Delete(ctx, id)
+	return s.repo.Erase(ctx, id)
 }
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	userErr "github.com/go-market/pkg/errs"
+	"github.com/go-market/pkg/mailer"
+	user "github.com/go-market/services/user/internal/model"
+	userRepo "github.com/go-market/services/user/internal/repository"
+)
+
+type VerificationOptions struct {
+	// Secret signs tokens, so guessed or altered tokens are rejected before
+	// Redis is consulted.
+	Secret   string
+	TokenTTL time.Duration
+	// LinkURL is the page the email links to; the token is appended as the
+	// "token" query parameter.
+	LinkURL      string
+	ResendLimit  int
+	ResendWindow time.Duration
+}
+
+// Verification confirms that users own the email address they registered
+// with. Tokens are single use, expire after TokenTTL and are tied to the
+// address they were sent to.
+type Verification struct {
+	repo   userRepo.Repository
+	tokens userRepo.VerificationStore
+	mailer mailer.Mailer
+	opts   VerificationOptions
+}
+
+func NewVerification(repo userRepo.Repository, tokens userRepo.VerificationStore, m mailer.Mailer, opts VerificationOptions) *Verification {
+	return &Verification{
+		repo:   repo,
+		tokens: tokens,
+		mailer: m,
+		opts:   opts,
+	}
+}
+
+// Send mails a fresh verification link to the user's current address. Any
+// link sent before stops working. Already verified users get nothing.
+func (v *Verification) Send(ctx context.Context, userID string) error {
+	u, err := v.repo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u.EmailVerified {
+		return nil
+	}
+
+	return v.send(ctx, u)
+}
+
+// Resend is Send on behalf of the user, limited to ResendLimit links per
+// ResendWindow.
+func (v *Verification) Resend(ctx context.Context, userID string) error {
+	u, err := v.repo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u.EmailVerified {
+		return userErr.ErrEmailAlreadyVerified
+	}
+
+	ok, retryAfter, err := v.tokens.AllowResend(ctx, u.ID, v.opts.ResendLimit, v.opts.ResendWindow)
+	if err != nil {
+		return fmt.Errorf("Verification.Resend: %w", err)
+	}
+	if !ok {
+		return &userErr.Throttled{RetryAfter: retryAfter}
+	}
+
+	return v.send(ctx, u)
+}
+
+func (v *Verification) send(ctx context.Context, u *user.User) error {
+	const op = "Verification.send"
+
+	nonce, token, err := v.newToken()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	t := user.VerificationToken{UserID: u.ID, Email: u.Email}
+	if err := v.tokens.SaveToken(ctx, nonce, t, v.opts.TokenTTL); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	link, err := url.Parse(v.opts.LinkURL)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	q := link.Query()
+	q.Set("token", token)
+	link.RawQuery = q.Encode()
+
+	err = v.mailer.Send(ctx, mailer.Message{
+		To:      u.Email,
+		Subject: "Confirm your email address",
+		Text: fmt.Sprintf("Hi %s,\n\nConfirm your email address by opening this link:\n\n%s\n\n"+
+			"The link expires in %s. If you did not sign up, ignore this email.\n",
+			u.Username, link, v.opts.TokenTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Verify consumes token and marks the address it was issued for as
+// verified. A token for an address the user has since changed is rejected.
+func (v *Verification) Verify(ctx context.Context, token string) (*user.User, error) {
+	nonce, ok := v.checkToken(token)
+	if !ok {
+		return nil, userErr.ErrInvalidVerificationToken
+	}
+
+	t, err := v.tokens.ConsumeToken(ctx, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.repo.MarkEmailVerified(ctx, t.UserID, t.Email)
+}
+
+// newToken returns a random nonce and the token sent to the user, which is
+// the nonce followed by its signature.
+func (v *Verification) newToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	nonce := base64.RawURLEncoding.EncodeToString(b)
+	return nonce, nonce + "." + v.sign(nonce), nil
+}
+
+func (v *Verification) checkToken(token string) (string, bool) {
+	nonce, sig, ok := strings.Cut(token, ".")
+	if !ok || nonce == "" {
+		return "", false
+	}
+	return nonce, hmac.Equal([]byte(sig), []byte(v.sign(nonce)))
+}
+
+func (v *Verification) sign(nonce string) string {
+	mac := hmac.New(sha256.New, []byte(v.opts.Secret))
+	mac.Write([]byte(nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
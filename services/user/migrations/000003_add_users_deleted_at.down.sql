@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS users_active_created_at_id_idx;
+
+ALTER TABLE users DROP COLUMN IF EXISTS erased_at;
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS erased_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS users_active_created_at_id_idx ON users (created_at, id) WHERE deleted_at IS NULL;